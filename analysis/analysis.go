@@ -0,0 +1,178 @@
+// Package analysis finds mistakes in games with a UCI engine and turns
+// them into puzzles.
+package analysis
+
+import (
+	"fmt"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+	"github.com/Yoshi-Exeler/chesslib/uci"
+)
+
+// mateScore is the centipawn value of a mate in zero moves.  Shorter
+// mates score higher so losing a faster mate isn't a blunder.
+const mateScore = 100000
+
+// Engine is a UCI engine such as a *uci.Engine.  The engine should have
+// been sent CmdUCI and CmdIsReady before it is used.
+type Engine interface {
+	Run(cmds ...uci.Cmd) error
+	SearchResults() uci.SearchResults
+}
+
+// A Theme describes the tactic solving a puzzle.
+type Theme string
+
+const (
+	// Fork is a solution that starts by attacking two or more pieces
+	// other than pawns with the moved piece.
+	Fork Theme = "fork"
+	// Pin is a solution that starts by pinning a piece to its king.
+	Pin Theme = "pin"
+)
+
+// MateIn returns the theme of a solution mating in n moves.
+func MateIn(n int) Theme {
+	return Theme(fmt.Sprintf("mateIn%d", n))
+}
+
+// Puzzle is a position following a blunder along with the engine's
+// line punishing it.
+type Puzzle struct {
+	// Ply is the index of the blunder in the game's moves.
+	Ply int
+	// Position is the position after the blunder with the solving side
+	// to move.
+	Position *chess.Position
+	// Blunder is the move that allowed the puzzle.
+	Blunder *chess.Move
+	// Solution is the engine's principal variation from the position.
+	Solution []*chess.Move
+	// Loss is the number of centipawns the blunder lost.
+	Loss   int
+	Themes []Theme
+}
+
+// Analyzer searches a game's positions with an engine.
+type Analyzer struct {
+	engine    Engine
+	threshold int
+	search    uci.CmdGo
+}
+
+// New returns an analyzer using the engine.  By default moves losing
+// 200 centipawns or more are blunders and each position is searched to
+// a depth of 12.
+func New(eng Engine, options ...func(*Analyzer)) *Analyzer {
+	a := &Analyzer{engine: eng, threshold: 200, search: uci.CmdGo{Depth: 12}}
+	for _, f := range options {
+		f(a)
+	}
+	return a
+}
+
+// Threshold is an option for New setting the number of centipawns a move
+// has to lose to be a blunder.
+func Threshold(cp int) func(*Analyzer) {
+	return func(a *Analyzer) {
+		a.threshold = cp
+	}
+}
+
+// Search is an option for New setting the search run for each position.
+// The search must end by itself so it can't be infinite or pondering.
+func Search(cmd uci.CmdGo) func(*Analyzer) {
+	return func(a *Analyzer) {
+		a.search = cmd
+	}
+}
+
+// evaluation is the engine's score of a position for the side to move.
+type evaluation struct {
+	score int
+	mate  int
+	pv    []*chess.Move
+}
+
+// Puzzles searches every position of the game's main line and returns a
+// puzzle for each move losing at least the threshold.  The loss of a
+// move is the score of the position before it minus the score after it,
+// both from the moving side's perspective.
+func (a *Analyzer) Puzzles(g *chess.Game) ([]Puzzle, error) {
+	positions := g.Positions()
+	moves := g.Moves()
+	evals := make([]evaluation, len(positions))
+	for i, pos := range positions {
+		e, err := a.evaluate(positions[0], moves[:i], pos)
+		if err != nil {
+			return nil, err
+		}
+		evals[i] = e
+	}
+	puzzles := []Puzzle{}
+	for i, m := range moves {
+		loss := evals[i].score + evals[i+1].score
+		if loss < a.threshold || len(evals[i+1].pv) == 0 {
+			continue
+		}
+		pos := positions[i+1]
+		puzzles = append(puzzles, Puzzle{
+			Ply:      i,
+			Position: pos,
+			Blunder:  m,
+			Solution: evals[i+1].pv,
+			Loss:     loss,
+			Themes:   themes(pos, evals[i+1]),
+		})
+	}
+	return puzzles, nil
+}
+
+// evaluate searches the position reached by playing the moves from the
+// starting position.  Positions that ended the game aren't searched.
+func (a *Analyzer) evaluate(start *chess.Position, moves []*chess.Move, pos *chess.Position) (evaluation, error) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		return evaluation{score: -mateScore}, nil
+	case chess.Stalemate:
+		return evaluation{}, nil
+	}
+	if err := a.engine.Run(uci.CmdPosition{Position: start, Moves: moves}, a.search); err != nil {
+		return evaluation{}, err
+	}
+	results := a.engine.SearchResults()
+	s := results.Info.Score
+	e := evaluation{score: s.CP, mate: s.Mate, pv: results.Info.PV}
+	switch {
+	case s.Mate > 0:
+		e.score = mateScore - s.Mate
+	case s.Mate < 0:
+		e.score = -mateScore - s.Mate
+	}
+	return e, nil
+}
+
+// themes returns the themes of the solution starting from the position.
+func themes(pos *chess.Position, e evaluation) []Theme {
+	themes := []Theme{}
+	if e.mate > 0 {
+		themes = append(themes, MateIn(e.mate))
+	}
+	m := e.pv[0]
+	mover := pos.Turn()
+	after := pos.Update(m)
+	attacked := 0
+	after.Board().Occupancy(mover.Other()).Each(func(sq chess.Square) bool {
+		if after.Board().Piece(sq).Type() != chess.Pawn && after.Board().AttackersOf(sq, mover).Occupied(m.S2) {
+			attacked++
+		}
+		return true
+	})
+	if attacked >= 2 {
+		themes = append(themes, Fork)
+	}
+	if after.PinnedPieces(mover.Other()).PopCount() > pos.PinnedPieces(mover.Other()).PopCount() {
+		themes = append(themes, Pin)
+	}
+	return themes
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+	"github.com/Yoshi-Exeler/chesslib/uci"
+)
+
+// fakeEngine reports the scripted info for the position after the given
+// number of moves and a score of zero for the others.
+type fakeEngine struct {
+	infos   map[int]uci.Info
+	ply     int
+	results uci.SearchResults
+	err     error
+}
+
+func (e *fakeEngine) Run(cmds ...uci.Cmd) error {
+	if e.err != nil {
+		return e.err
+	}
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case uci.CmdPosition:
+			e.ply = len(c.Moves)
+		case uci.CmdGo:
+			e.results = uci.SearchResults{Info: e.infos[e.ply]}
+		}
+	}
+	return nil
+}
+
+func (e *fakeEngine) SearchResults() uci.SearchResults {
+	return e.results
+}
+
+func newTestGame(t *testing.T, fen string, moves ...string) *chess.Game {
+	opts := []func(*chess.Game){}
+	if fen != "" {
+		f, err := chess.FEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, f)
+	}
+	g := chess.NewGame(opts...)
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func uciMoves(t *testing.T, pos *chess.Position, strs ...string) []*chess.Move {
+	moves := []*chess.Move{}
+	for _, s := range strs {
+		m, err := chess.UCINotation{}.Decode(pos, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves = append(moves, m)
+		pos = pos.Update(m)
+	}
+	return moves
+}
+
+func TestPuzzlesFork(t *testing.T) {
+	g := newTestGame(t, "4k3/8/8/1N6/8/8/q7/4K3 b - - 0 1", "Qa8", "Nc7+")
+	solution := uciMoves(t, g.Positions()[1], "b5c7", "e8d7", "c7a8")
+	eng := &fakeEngine{infos: map[int]uci.Info{
+		1: {Score: uci.Score{CP: 880}, PV: solution},
+		2: {Score: uci.Score{CP: -880}, PV: solution[1:]},
+	}}
+	puzzles, err := New(eng).Puzzles(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 {
+		t.Fatalf("expected 1 puzzle but got %d", len(puzzles))
+	}
+	p := puzzles[0]
+	if p.Ply != 0 || p.Blunder.String() != "a2a8" || p.Loss != 880 {
+		t.Fatalf("expected a2a8 at ply 0 to lose 880 but got %s at ply %d losing %d", p.Blunder, p.Ply, p.Loss)
+	}
+	if !reflect.DeepEqual(p.Themes, []Theme{Fork}) {
+		t.Fatalf("expected themes %v but got %v", []Theme{Fork}, p.Themes)
+	}
+}
+
+func TestPuzzlesMate(t *testing.T) {
+	g := newTestGame(t, "", "f3", "e5", "g4", "Qh4#")
+	solution := uciMoves(t, g.Positions()[3], "d8h4")
+	eng := &fakeEngine{infos: map[int]uci.Info{
+		3: {Score: uci.Score{Mate: 1}, PV: solution},
+	}}
+	puzzles, err := New(eng).Puzzles(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 || puzzles[0].Blunder.String() != "g2g4" {
+		t.Fatalf("expected g2g4 to be the only blunder but got %v", puzzles)
+	}
+	if expected := []Theme{MateIn(1)}; !reflect.DeepEqual(puzzles[0].Themes, expected) {
+		t.Fatalf("expected themes %v but got %v", expected, puzzles[0].Themes)
+	}
+}
+
+func TestPuzzlesThreshold(t *testing.T) {
+	g := newTestGame(t, "", "e4", "f6")
+	solution := uciMoves(t, g.Positions()[2], "d2d4")
+	eng := &fakeEngine{infos: map[int]uci.Info{
+		2: {Score: uci.Score{CP: 150}, PV: solution},
+	}}
+	for _, test := range []struct {
+		threshold int
+		count     int
+	}{{200, 0}, {100, 1}} {
+		puzzles, err := New(eng, Threshold(test.threshold)).Puzzles(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(puzzles) != test.count {
+			t.Fatalf("expected %d puzzles with threshold %d but got %d", test.count, test.threshold, len(puzzles))
+		}
+	}
+}
+
+func TestPuzzlesEngineError(t *testing.T) {
+	g := newTestGame(t, "", "e4")
+	expected := errors.New("engine crashed")
+	if _, err := New(&fakeEngine{err: expected}).Puzzles(g); err != expected {
+		t.Fatalf("expected error %v but got %v", expected, err)
+	}
+}
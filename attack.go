@@ -0,0 +1,176 @@
+package chess
+
+// IsPinned returns true if the piece on the given square is absolutely
+// pinned to its king, along with the square of the pinning piece.  If
+// the piece isn't pinned then false and NoSquare are returned.
+func (pos *Position) IsPinned(sq Square) (bool, Square) {
+	p := pos.board.Piece(sq)
+	if p == NoPiece || p.Type() == King {
+		return false, NoSquare
+	}
+	kingSq := pos.board.kingSquare(p.Color())
+	if kingSq == NoSquare {
+		return false, NoSquare
+	}
+	df, dr, ok := rayDirection(kingSq, sq)
+	if !ok {
+		return false, NoSquare
+	}
+	// every square between the king and the piece must be empty
+	f, r := int(kingSq.File())+df, int(kingSq.Rank())+dr
+	for getSquare(File(f), Rank(r)) != sq {
+		if pos.board.isOccupied(getSquare(File(f), Rank(r))) {
+			return false, NoSquare
+		}
+		f, r = f+df, r+dr
+	}
+	// the first piece behind must be an enemy slider moving along the ray
+	for f, r = f+df, r+dr; onBoard(f, r); f, r = f+df, r+dr {
+		behindSq := getSquare(File(f), Rank(r))
+		behind := pos.board.Piece(behindSq)
+		if behind == NoPiece {
+			continue
+		}
+		if behind.Color() != p.Color() && slidesAlong(behind.Type(), df, dr) {
+			return true, behindSq
+		}
+		return false, NoSquare
+	}
+	return false, NoSquare
+}
+
+// Attackers returns the squares of the pieces of the given color that
+// attack the square in A1 to H8 order.  Pins aren't considered and
+// pieces attacking through other pieces aren't included.
+func (pos *Position) Attackers(sq Square, c Color) []Square {
+	return pos.board.attackersOf(sq, c, ^pos.board.emptySqs).squares()
+}
+
+// AttackersOf returns the pieces of the given color that attack the
+// square as a Bitboard, like Position's Attackers method.
+func (b *Board) AttackersOf(sq Square, c Color) Bitboard {
+	return Bitboard(b.attackersOf(sq, c, ^b.emptySqs))
+}
+
+// IsAttacked returns true if a piece of the given color attacks the
+// square.
+func (pos *Position) IsAttacked(sq Square, c Color) bool {
+	return pos.board.attackersOf(sq, c, ^pos.board.emptySqs) != 0
+}
+
+// PinnedPieces returns the pieces of the given color that are absolutely
+// pinned to their king.
+func (pos *Position) PinnedPieces(c Color) Bitboard {
+	var bb bitboard
+	for _, p := range allPieces {
+		if p.Color() != c || p.Type() == King {
+			continue
+		}
+		for _, sq := range pos.board.bbForPiece(p).squares() {
+			if pinned, _ := pos.IsPinned(sq); pinned {
+				bb |= bbForSquare(sq)
+			}
+		}
+	}
+	return Bitboard(bb)
+}
+
+// Checkers returns the pieces giving check to the side to move.
+func (pos *Position) Checkers() Bitboard {
+	kingSq := pos.board.kingSquare(pos.turn)
+	if kingSq == NoSquare {
+		return 0
+	}
+	return Bitboard(pos.board.attackersOf(kingSq, pos.turn.Other(), ^pos.board.emptySqs))
+}
+
+// ViolatesPin returns true if the move takes an absolutely pinned piece
+// off the line between its king and the pinning piece.  Moves along the
+// pin, including capturing the pinning piece, don't violate the pin.
+func (pos *Position) ViolatesPin(m *Move) bool {
+	pinned, pinner := pos.IsPinned(m.S1)
+	if !pinned {
+		return false
+	}
+	kingSq := pos.board.kingSquare(pos.board.Piece(m.S1).Color())
+	line := bbBetween(kingSq, pinner) | bbForSquare(pinner)
+	return line&bbForSquare(m.S2) == 0
+}
+
+// GivesCheck returns true if the move puts the opponent's king in check
+// directly, by discovery, by promotion or by the rook when castling.
+// Only the resulting board is examined so the opponent's moves aren't
+// generated.  The move itself isn't validated.
+func (pos *Position) GivesCheck(m *Move) bool {
+	return pos.checkersAfter(m) != 0
+}
+
+// CheckingPieces returns the squares of the pieces giving check to the
+// side to move.  Two squares are returned for a double check and none
+// if the side to move isn't in check.
+func (pos *Position) CheckingPieces() []Square {
+	return pos.Checkers().Squares()
+}
+
+// checkersAfter returns the pieces attacking the opponent's king once
+// the move has been played on the board.
+func (pos *Position) checkersAfter(m *Move) bitboard {
+	p := pos.board.Piece(m.S1)
+	if m.drop != NoPieceType {
+		p = getPiece(m.drop, pos.turn)
+	}
+	if p == NoPiece {
+		return 0
+	}
+	kingSq := pos.board.kingSquare(p.Color().Other())
+	if kingSq == NoSquare {
+		return 0
+	}
+	// tag the move so the board updates en passant captures and castles
+	mv := &Move{S1: m.S1, S2: m.S2, promo: m.promo, drop: m.drop, tags: m.tags}
+	pos.addMoveTags(mv)
+	b := pos.board.copy()
+	pos.updateBoard(b, mv)
+	return b.attackersOf(kingSq, p.Color(), ^b.emptySqs)
+}
+
+// rayDirection returns the unit file and rank steps from s1 towards s2
+// and true if the two squares share a rank, file or diagonal.
+func rayDirection(s1, s2 Square) (int, int, bool) {
+	fd := int(s2.File()) - int(s1.File())
+	rd := int(s2.Rank()) - int(s1.Rank())
+	if s1 == s2 || (fd != 0 && rd != 0 && abs(fd) != abs(rd)) {
+		return 0, 0, false
+	}
+	return sign(fd), sign(rd), true
+}
+
+// slidesAlong returns true if the piece type attacks along
+// rays with the given file and rank steps.
+func slidesAlong(pt PieceType, df, dr int) bool {
+	if df == 0 || dr == 0 {
+		return pt == Rook || pt == Queen
+	}
+	return pt == Bishop || pt == Queen
+}
+
+func onBoard(f, r int) bool {
+	return f >= 0 && f < numOfSquaresInRow && r >= 0 && r < numOfSquaresInRow
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
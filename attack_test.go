@@ -0,0 +1,170 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsPinned(t *testing.T) {
+	tables := []struct {
+		fen    string
+		sq     Square
+		pinned bool
+		pinner Square
+	}{
+		// knight pinned by bishop on the diagonal
+		{"4k3/8/8/8/1b6/8/3N4/4K3 w - - 0 1", D2, true, B4},
+		// rook pinned by queen on the file
+		{"4q1k1/8/8/8/8/8/4R3/4K3 w - - 0 1", E2, true, E8},
+		// bishop can't pin along a file
+		{"4b1k1/8/8/8/8/8/4R3/4K3 w - - 0 1", E2, false, NoSquare},
+		// second piece on the ray blocks the pin
+		{"4k3/8/8/8/1b6/2P5/3N4/4K3 w - - 0 1", D2, false, NoSquare},
+		// friendly slider behind isn't a pin
+		{"4k3/8/8/8/1B6/8/3N4/4K3 w - - 0 1", D2, false, NoSquare},
+		// black piece pinned by a white rook on the rank
+		{"R2nk3/8/8/8/8/8/8/4K3 b - - 0 1", D8, true, A8},
+		// empty square
+		{"4k3/8/8/8/1b6/8/3N4/4K3 w - - 0 1", D3, false, NoSquare},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		pinned, pinner := pos.IsPinned(table.sq)
+		if pinned != table.pinned || pinner != table.pinner {
+			t.Fatalf("%s expected pin on %s to be (%t, %s) but got (%t, %s)",
+				table.fen, table.sq, table.pinned, table.pinner, pinned, pinner)
+		}
+	}
+}
+
+func TestGivesCheck(t *testing.T) {
+	tables := []struct {
+		fen   string
+		move  string
+		check bool
+	}{
+		// direct check
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", true},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a7", false},
+		// discovered check by moving the bishop off the file
+		{"4k3/8/8/8/8/4B3/8/4RK2 w - - 0 1", "e3c5", true},
+		// promotion to a knight checks while a queen wouldn't
+		{"8/1P1k4/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", true},
+		{"8/1P1k4/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", false},
+		// en passant removes the blocking pawn from the diagonal
+		{"8/k7/8/2pP4/8/8/8/4K1B1 w - c6 0 1", "d5c6", true},
+		// the castling rook checks the king
+		{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", true},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m := &Move{S1: strToSquareMap[table.move[0:2]], S2: strToSquareMap[table.move[2:4]]}
+		if len(table.move) == 5 {
+			m.promo = pieceTypeFromChar(table.move[4:5])
+		}
+		if check := pos.GivesCheck(m); check != table.check {
+			t.Fatalf("%s expected %s gives check to be %t", table.fen, table.move, table.check)
+		}
+	}
+}
+
+func TestDoubleCheck(t *testing.T) {
+	// the e4 knight blocks the e1 rook from the black king
+	pos := unsafeFEN("4k3/8/8/8/4N3/8/8/4RK2 w - - 0 1")
+	tables := []struct {
+		move     string
+		double   bool
+		checkers []Square
+	}{
+		{"e4d6", true, []Square{E1, D6}},
+		{"e4f6", true, []Square{E1, F6}},
+		{"e4c5", false, []Square{E1}},
+		{"f1f2", false, []Square{}},
+	}
+	for _, table := range tables {
+		m := &Move{S1: strToSquareMap[table.move[0:2]], S2: strToSquareMap[table.move[2:4]]}
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			t.Fatalf("expected %s to be valid", table.move)
+		}
+		if valid.HasTag(DoubleCheck) != table.double {
+			t.Fatalf("expected %s double check tag to be %t", table.move, table.double)
+		}
+		pos.NormalizeTags(m)
+		if m.HasTag(DoubleCheck) != table.double {
+			t.Fatalf("expected normalized %s double check tag to be %t", table.move, table.double)
+		}
+		checkers := pos.Update(valid).CheckingPieces()
+		if !reflect.DeepEqual(checkers, table.checkers) {
+			t.Fatalf("expected %s checking pieces %v but got %v", table.move, table.checkers, checkers)
+		}
+	}
+}
+
+func TestAttackers(t *testing.T) {
+	pos := unsafeFEN("4k3/8/2n5/3p4/4P3/8/8/3RK3 w - - 0 1")
+	tables := []struct {
+		sq        Square
+		c         Color
+		attackers []Square
+	}{
+		{D5, White, []Square{D1, E4}},
+		{D5, Black, []Square{}},
+		{E4, Black, []Square{D5}},
+		{D4, Black, []Square{C6}},
+		{H8, White, []Square{}},
+	}
+	for _, table := range tables {
+		if actual := pos.Attackers(table.sq, table.c); !reflect.DeepEqual(actual, table.attackers) {
+			t.Fatalf("expected %s attackers of %s to be %v but got %v", table.c, table.sq, table.attackers, actual)
+		}
+		if actual := pos.Board().AttackersOf(table.sq, table.c).Squares(); !reflect.DeepEqual(actual, table.attackers) {
+			t.Fatalf("expected %s attacker bitboard of %s to be %v but got %v", table.c, table.sq, table.attackers, actual)
+		}
+		if attacked := pos.IsAttacked(table.sq, table.c); attacked != (len(table.attackers) > 0) {
+			t.Fatalf("expected %s attacked by %s to be %t", table.sq, table.c, !attacked)
+		}
+	}
+}
+
+func TestPinnedPieces(t *testing.T) {
+	// the d2 knight is pinned by the a5 bishop, the e2 rook by the e8 rook
+	// and the black b7 pawn by the d5 bishop
+	pos := unsafeFEN("k3r3/1p6/8/b2B4/8/8/3NR3/4K3 w - - 0 1")
+	if pinned := pos.PinnedPieces(White).Squares(); !reflect.DeepEqual(pinned, []Square{D2, E2}) {
+		t.Fatalf("expected the white pinned pieces to be [d2 e2] but got %v", pinned)
+	}
+	if pinned := pos.PinnedPieces(Black).Squares(); !reflect.DeepEqual(pinned, []Square{B7}) {
+		t.Fatalf("expected the black pinned pieces to be [b7] but got %v", pinned)
+	}
+	if checkers := pos.Checkers(); checkers != 0 {
+		t.Fatalf("expected no checkers but got %v", checkers.Squares())
+	}
+}
+
+func TestViolatesPin(t *testing.T) {
+	// the d2 knight is pinned by the a5 bishop and the e2 rook by the e8 rook
+	pos := unsafeFEN("k3r3/8/8/b7/8/8/3NR3/4K3 w - - 0 1")
+	tables := []struct {
+		move      string
+		violation bool
+	}{
+		{"d2b3", true},
+		{"d2f3", true},
+		{"e2e5", false},
+		{"e2e8", false},
+		{"e2f2", true},
+		{"e1f1", false},
+	}
+	for _, table := range tables {
+		m := &Move{S1: strToSquareMap[table.move[0:2]], S2: strToSquareMap[table.move[2:4]]}
+		if v := pos.ViolatesPin(m); v != table.violation {
+			t.Fatalf("expected %s violating a pin to be %t", table.move, table.violation)
+		}
+	}
+	// a free knight next to the pinned piece
+	pos = unsafeFEN("k3r3/8/8/8/8/8/3NR3/4K3 w - - 0 1")
+	if pos.ViolatesPin(&Move{S1: D2, S2: B3}) {
+		t.Fatal("expected unpinned knight move not to violate a pin")
+	}
+}
@@ -59,3 +59,108 @@ func (b bitboard) Draw() string {
 	}
 	return s
 }
+
+// squares returns the occupied squares of the bitboard in A1 to H8 order.
+func (b bitboard) squares() []Square {
+	sqs := []Square{}
+	for sq := 0; sq < numOfSquaresInBoard && b != 0; sq++ {
+		if b.Occupied(Square(sq)) {
+			sqs = append(sqs, Square(sq))
+			b &= ^bbForSquare(Square(sq))
+		}
+	}
+	return sqs
+}
+
+// Bitboard is a set of squares encoded in an unsigned 64-bit integer
+// with A1 as the most significant bit and H8 as the least, the same
+// layout the board uses internally.
+type Bitboard uint64
+
+// NewBitboard returns a bitboard of the given squares.
+func NewBitboard(sqs ...Square) Bitboard {
+	var bb bitboard
+	for _, sq := range sqs {
+		bb |= bbForSquare(sq)
+	}
+	return Bitboard(bb)
+}
+
+// And returns the squares in both bitboards.
+func (b Bitboard) And(o Bitboard) Bitboard {
+	return b & o
+}
+
+// Or returns the squares in either bitboard.
+func (b Bitboard) Or(o Bitboard) Bitboard {
+	return b | o
+}
+
+// Xor returns the squares in exactly one of the bitboards.
+func (b Bitboard) Xor(o Bitboard) Bitboard {
+	return b ^ o
+}
+
+// Not returns the squares that aren't in the bitboard.
+func (b Bitboard) Not() Bitboard {
+	return ^b
+}
+
+// PopCount returns the number of squares in the bitboard.
+func (b Bitboard) PopCount() int {
+	return bitboard(b).count()
+}
+
+// MSB returns the square of the most significant bit, which is the
+// lowest square of the bitboard in A1 to H8 order.  NoSquare is
+// returned for an empty bitboard.
+func (b Bitboard) MSB() Square {
+	if b == 0 {
+		return NoSquare
+	}
+	return bitboard(b).first()
+}
+
+// LSB returns the square of the least significant bit, which is the
+// highest square of the bitboard in A1 to H8 order.  NoSquare is
+// returned for an empty bitboard.
+func (b Bitboard) LSB() Square {
+	if b == 0 {
+		return NoSquare
+	}
+	return bitboard(b).last()
+}
+
+// Occupied returns true if the square is in the bitboard.
+func (b Bitboard) Occupied(sq Square) bool {
+	return bitboard(b).Occupied(sq)
+}
+
+// Squares returns the squares of the bitboard in A1 to H8 order.
+func (b Bitboard) Squares() []Square {
+	return bitboard(b).squares()
+}
+
+// Each calls f for every square of the bitboard in order from A1 to H8.
+// Iteration stops early if f returns false.
+func (b Bitboard) Each(f func(Square) bool) {
+	for bb := bitboard(b); bb != 0; {
+		sq := bb.first()
+		if !f(sq) {
+			return
+		}
+		bb &^= bbForSquare(sq)
+	}
+}
+
+// String returns a 64 character string of 1s and 0s starting with the
+// most significant bit.
+func (b Bitboard) String() string {
+	return bitboard(b).String()
+}
+
+// Draw returns a visual representation of the bitboard with rank 8 at
+// the top.
+func (b Bitboard) Draw() string {
+	return bitboard(b).Draw()
+}
@@ -13,3 +13,18 @@ func (b bitboard) Reverse() bitboard {
 func (b bitboard) Occupied(sq Square) bool {
 	return (bits.RotateLeft64(uint64(b), int(sq)+1) & 1) == 1
 }
+
+// count returns the number of occupied squares in the bitboard.
+func (b bitboard) count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// first returns the lowest occupied square of a non empty bitboard.
+func (b bitboard) first() Square {
+	return Square(bits.LeadingZeros64(uint64(b)))
+}
+
+// last returns the highest occupied square of a non empty bitboard.
+func (b bitboard) last() Square {
+	return Square(63 - bits.TrailingZeros64(uint64(b)))
+}
@@ -40,3 +40,30 @@ func (b bitboard) Occupied(sq Square) bool {
 }
 
 //
+
+// count returns the number of occupied squares in the bitboard.
+func (b bitboard) count() int {
+	c := 0
+	for ; b != 0; c++ {
+		b &= b - 1
+	}
+	return c
+}
+
+// first returns the lowest occupied square of a non empty bitboard.
+func (b bitboard) first() Square {
+	sq := Square(0)
+	for ; b&(1<<63) == 0; b <<= 1 {
+		sq++
+	}
+	return sq
+}
+
+// last returns the highest occupied square of a non empty bitboard.
+func (b bitboard) last() Square {
+	sq := Square(63)
+	for ; b&1 == 0; b >>= 1 {
+		sq--
+	}
+	return sq
+}
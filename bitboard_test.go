@@ -0,0 +1,56 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBitboard(t *testing.T) {
+	a := NewBitboard(A1, E4, H8)
+	b := NewBitboard(E4, D5)
+	tables := []struct {
+		bb      Bitboard
+		squares []Square
+	}{
+		{a.And(b), []Square{E4}},
+		{a.Or(b), []Square{A1, E4, D5, H8}},
+		{a.Xor(b), []Square{A1, D5, H8}},
+		{a.Not().And(b), []Square{D5}},
+		{0, []Square{}},
+	}
+	for _, table := range tables {
+		if sqs := table.bb.Squares(); !reflect.DeepEqual(sqs, table.squares) {
+			t.Fatalf("expected squares %v but got %v", table.squares, sqs)
+		}
+		if n := table.bb.PopCount(); n != len(table.squares) {
+			t.Fatalf("expected %v to have %d squares but got %d", table.squares, len(table.squares), n)
+		}
+		sqs := []Square{}
+		table.bb.Each(func(sq Square) bool {
+			sqs = append(sqs, sq)
+			return true
+		})
+		if !reflect.DeepEqual(sqs, table.squares) {
+			t.Fatalf("expected to iterate %v but got %v", table.squares, sqs)
+		}
+	}
+	if a.MSB() != A1 || a.LSB() != H8 || b.MSB() != E4 || b.LSB() != D5 {
+		t.Fatalf("expected the bits of %v and %v to be a1, h8, e4 and d5", a.Squares(), b.Squares())
+	}
+	if Bitboard(0).MSB() != NoSquare || Bitboard(0).LSB() != NoSquare {
+		t.Fatal("expected an empty bitboard to have no bits")
+	}
+}
+
+func TestBoardBitboards(t *testing.T) {
+	b := StartingPosition().Board()
+	if sqs := b.Bitboard(WhiteKnight).Squares(); !reflect.DeepEqual(sqs, []Square{B1, G1}) {
+		t.Fatalf("expected the white knights on b1 and g1 but got %v", sqs)
+	}
+	if n := b.Occupancy(Black).PopCount(); n != 16 {
+		t.Fatalf("expected 16 black pieces but got %d", n)
+	}
+	if b.Occupancy(NoColor) != b.Occupancy(White).Or(b.Occupancy(Black)) {
+		t.Fatal("expected the occupancy of all pieces to be the union of both colors")
+	}
+}
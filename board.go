@@ -50,13 +50,38 @@ func NewBoard(m map[Square]Piece) *Board {
 // SquareMap returns a mapping of squares to pieces.  A square is only added to the map if it is occupied.
 func (b *Board) SquareMap() map[Square]Piece {
 	m := map[Square]Piece{}
-	for sq := 0; sq < numOfSquaresInBoard; sq++ {
-		p := b.Piece(Square(sq))
-		if p != NoPiece {
-			m[Square(sq)] = p
+	b.Each(func(sq Square, p Piece) bool {
+		m[sq] = p
+		return true
+	})
+	return m
+}
+
+// Bitboard returns the squares occupied by the piece.
+func (b *Board) Bitboard(p Piece) Bitboard {
+	return Bitboard(b.bbForPiece(p))
+}
+
+// Occupancy returns the squares occupied by the pieces of the color or
+// by all pieces for NoColor.
+func (b *Board) Occupancy(c Color) Bitboard {
+	switch c {
+	case White:
+		return Bitboard(b.whiteSqs)
+	case Black:
+		return Bitboard(b.blackSqs)
+	}
+	return Bitboard(^b.emptySqs)
+}
+
+// Each calls f for every occupied square in order from A1 to H8.
+// Iteration stops early if f returns false.
+func (b *Board) Each(f func(Square, Piece) bool) {
+	for _, sq := range (^b.emptySqs).squares() {
+		if !f(sq, b.Piece(sq)) {
+			return
 		}
 	}
-	return m
 }
 
 var whitePawnPositionalValues = [64]float32{
@@ -466,7 +491,7 @@ func (b *Board) MarshalText() (text []byte, err error) {
 	return []byte(b.String()), nil
 }
 
-// UnmarshalText implements the encoding.TextUnarshaler interface and takes
+// UnmarshalText implements the encoding.TextUnmarshaler interface and takes
 // a string in the FEN board format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
 func (b *Board) UnmarshalText(text []byte) error {
 	cp, err := fenBoard(string(text))
@@ -561,19 +586,27 @@ func (b *Board) update(m *Move) {
 			b.bbWhitePawn = ^(bbForSquare(m.S2) >> 8) & b.bbWhitePawn
 		}
 	}
-	// move rook for castle
-	if p1.Color() == White && m.HasTag(KingSideCastle) {
-		b.bbWhiteRook = (b.bbWhiteRook & ^bbForSquare(H1) | bbForSquare(F1))
-	} else if p1.Color() == White && m.HasTag(QueenSideCastle) {
-		b.bbWhiteRook = (b.bbWhiteRook & ^bbForSquare(A1)) | bbForSquare(D1)
-	} else if p1.Color() == Black && m.HasTag(KingSideCastle) {
-		b.bbBlackRook = (b.bbBlackRook & ^bbForSquare(H8) | bbForSquare(F8))
-	} else if p1.Color() == Black && m.HasTag(QueenSideCastle) {
-		b.bbBlackRook = (b.bbBlackRook & ^bbForSquare(A8)) | bbForSquare(D8)
-	}
 	b.calcConvienceBBs(m)
 }
 
+// castle moves the king and the rook from their home squares to their
+// destinations.  As in Chess960 either may land on the other's home
+// square or stay where it is.
+func (b *Board) castle(king, kingTo, rook, rookTo Square) {
+	k, r := b.Piece(king), b.Piece(rook)
+	b.setBBForPiece(k, b.bbForPiece(k)&^bbForSquare(king)|bbForSquare(kingTo))
+	if r.Type() == Rook {
+		b.setBBForPiece(r, b.bbForPiece(r)&^bbForSquare(rook)|bbForSquare(rookTo))
+	}
+	b.calcConvienceBBs(&Move{S1: king, S2: kingTo})
+}
+
+// drop puts the piece on the empty square.
+func (b *Board) drop(p Piece, sq Square) {
+	b.setBBForPiece(p, b.bbForPiece(p)|bbForSquare(sq))
+	b.calcConvienceBBs(&Move{S1: sq, S2: sq})
+}
+
 func (b *Board) calcConvienceBBs(m *Move) {
 	whiteSqs := b.bbWhiteKing | b.bbWhiteQueen | b.bbWhiteRook | b.bbWhiteBishop | b.bbWhiteKnight | b.bbWhitePawn
 	blackSqs := b.bbBlackKing | b.bbBlackQueen | b.bbBlackRook | b.bbBlackBishop | b.bbBlackKnight | b.bbBlackPawn
@@ -622,6 +655,13 @@ func (b *Board) copy() *Board {
 	}
 }
 
+func (b *Board) kingSquare(c Color) Square {
+	if c == White {
+		return b.whiteKingSq
+	}
+	return b.blackKingSq
+}
+
 func (b *Board) isOccupied(sq Square) bool {
 	return !b.emptySqs.Occupied(sq)
 }
@@ -674,6 +714,18 @@ func (b *Board) hasSufficientMaterial() bool {
 	return true
 }
 
+// isKnightsVersusKing returns true if one side has a king and two
+// knights and the other side only a king.  Checkmate is possible in
+// this ending but can't be forced, so it isn't insufficient material.
+func (b *Board) isKnightsVersusKing() bool {
+	if (b.bbWhiteQueen | b.bbWhiteRook | b.bbWhiteBishop | b.bbWhitePawn |
+		b.bbBlackQueen | b.bbBlackRook | b.bbBlackBishop | b.bbBlackPawn) != 0 {
+		return false
+	}
+	white, black := b.bbWhiteKnight.count(), b.bbBlackKnight.count()
+	return (white == 2 && black == 0) || (white == 0 && black == 2)
+}
+
 func (b *Board) bbForPiece(p Piece) bitboard {
 	switch p {
 	case WhiteKing:
@@ -1,6 +1,7 @@
 package chess
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -88,3 +89,57 @@ func TestBoardTranspose(t *testing.T) {
 		t.Fatalf("expected board string %s but got %s", b, board.String())
 	}
 }
+
+func TestBoardEach(t *testing.T) {
+	board := unsafeFEN("4k3/8/8/8/8/8/3P4/R3K3 w - - 0 1").Board()
+	sqs := []Square{}
+	pieces := []Piece{}
+	board.Each(func(sq Square, p Piece) bool {
+		sqs = append(sqs, sq)
+		pieces = append(pieces, p)
+		return true
+	})
+	expectedSqs := []Square{A1, E1, D2, E8}
+	expectedPieces := []Piece{WhiteRook, WhiteKing, WhitePawn, BlackKing}
+	if !reflect.DeepEqual(sqs, expectedSqs) || !reflect.DeepEqual(pieces, expectedPieces) {
+		t.Fatalf("expected to visit %v %v but got %v %v", expectedSqs, expectedPieces, sqs, pieces)
+	}
+	count := 0
+	board.Each(func(sq Square, p Piece) bool {
+		count++
+		return sq != E1
+	})
+	if count != 2 {
+		t.Fatalf("expected iteration to stop after 2 squares but visited %d", count)
+	}
+	if m := board.SquareMap(); len(m) != 4 || m[D2] != WhitePawn {
+		t.Fatalf("expected square map of the occupied squares but got %v", m)
+	}
+}
+
+func TestBoardSufficientMaterial(t *testing.T) {
+	tables := []struct {
+		fen        string
+		sufficient bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/3BK3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/3NK3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", true},
+		// all bishops on dark squares regardless of their number
+		{"4kb2/8/8/8/8/8/1B6/B3K3 w - - 0 1", false},
+		{"2b1kb2/8/8/8/8/8/8/3BK3 w - - 0 1", true},
+		{"4k3/8/4b3/8/8/8/8/3BK3 w - - 0 1", false},
+		// two knights can't force mate but mate is still possible
+		{"4k3/8/8/8/8/8/8/1N2K1N1 w - - 0 1", true},
+		// a helpmate is possible
+		{"4k3/8/8/8/8/8/4n3/3BK3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/7P/4K3 w - - 0 1", true},
+	}
+	for _, table := range tables {
+		board := unsafeFEN(table.fen).Board()
+		if board.hasSufficientMaterial() != table.sufficient {
+			t.Fatalf("%s expected sufficient material to be %t", table.fen, table.sufficient)
+		}
+	}
+}
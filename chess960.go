@@ -0,0 +1,261 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Chess960 is standard chess from one of 960 starting positions with
+// the pieces of the back rank shuffled, also known as Fischer Random.
+// The king and rook castle to the same squares as in standard chess
+// from wherever they start: the squares between them and their
+// destinations must be empty apart from the two of them, and the king
+// may not castle out of, through or into check.  FEN castling rights
+// are written in X-FEN, where K and Q name the outermost rook on their
+// side and a file letter any other rook, and Shredder-FEN is accepted.
+// UCI notation writes castling as the king capturing its own rook such
+// as e1h1, while SAN writes O-O and O-O-O as usual.
+type Chess960 struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Chess960) Name() string {
+	return "Chess960"
+}
+
+// StartingFEN implements the Variant interface and returns the standard
+// starting position, which is number 518.  The other starting positions
+// are returned by Chess960Position.
+func (Chess960) StartingFEN() string {
+	return startFEN
+}
+
+// isChess960 returns true if the position is played by the rules of
+// Chess960.
+func (pos *Position) isChess960() bool {
+	_, ok := pos.variant.(Chess960)
+	return ok
+}
+
+// castleTarget returns the square of the rook the move castles with in
+// Chess960, where castles are written as the king capturing its own
+// rook, and otherwise the move's destination.
+func (pos *Position) castleTarget(m *Move) Square {
+	if side := m.castleSide(); side != 0 && pos.isChess960() {
+		_, rook := pos.castleSquares(pos.turn, side)
+		return rook
+	}
+	return m.S2
+}
+
+// kingTakesRook returns the castle written as the king on s1 capturing
+// its own rook on s2 or nil if the squares aren't a Chess960 castle.
+func (pos *Position) kingTakesRook(s1, s2 Square) *Move {
+	p := pos.board.Piece(s1)
+	if p.Type() != King || !pos.isChess960() {
+		return nil
+	}
+	for _, side := range castleSides {
+		king, rook := pos.castleSquares(p.Color(), side)
+		if s1 != king || s2 != rook || pos.board.Piece(rook) != getPiece(Rook, p.Color()) {
+			continue
+		}
+		m := &Move{S1: s1}
+		m.S2, _ = castleDestinations(p.Color(), side)
+		if side == KingSide {
+			m.addTag(KingSideCastle)
+		} else {
+			m.addTag(QueenSideCastle)
+		}
+		return m
+	}
+	return nil
+}
+
+// chess960Knights lists the files of the two knights among the five
+// squares left after placing the bishops and queen of a Chess960
+// starting position.
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960Position returns the Chess960 starting position with the
+// given Scharnagl number from 0 to 959.  The number 518 is the standard
+// starting position.  Both sides can castle with either rook and games
+// are started from the position with VariantFEN(Chess960{}, pos.String()).
+// An error is returned if the number is out of range.
+func Chess960Position(id int) (*Position, error) {
+	if id < 0 || id > 959 {
+		return nil, fmt.Errorf("chess: invalid chess960 position %d", id)
+	}
+	rank := [8]PieceType{}
+	n := id
+	rank[(n%4)*2+1] = Bishop
+	n /= 4
+	rank[(n%4)*2] = Bishop
+	n /= 4
+	// the remaining pieces fill the empty files from left to right
+	place := func(i int, pt PieceType) {
+		for f := range rank {
+			if rank[f] != NoPieceType {
+				continue
+			}
+			if i == 0 {
+				rank[f] = pt
+				return
+			}
+			i--
+		}
+	}
+	place(n%6, Queen)
+	n /= 6
+	knights := chess960Knights[n]
+	place(knights[1], Knight)
+	place(knights[0], Knight)
+	place(0, Rook)
+	place(0, King)
+	place(0, Rook)
+	m := map[Square]Piece{}
+	cs := &castling{}
+	rooks := []File{}
+	for f, pt := range rank {
+		m[getSquare(File(f), Rank1)] = getPiece(pt, White)
+		m[getSquare(File(f), Rank2)] = WhitePawn
+		m[getSquare(File(f), Rank7)] = BlackPawn
+		m[getSquare(File(f), Rank8)] = getPiece(pt, Black)
+		switch pt {
+		case King:
+			cs.king[White], cs.king[Black] = File(f), File(f)
+		case Rook:
+			rooks = append(rooks, File(f))
+		}
+	}
+	for _, c := range []Color{White, Black} {
+		cs.rooks[c][QueenSide], cs.rooks[c][KingSide] = rooks[0], rooks[1]
+	}
+	return &Position{
+		board:           NewBoard(m),
+		turn:            White,
+		castleRights:    "KQkq",
+		enPassantSquare: NoSquare,
+		moveCount:       1,
+		castling:        cs,
+		variant:         Chess960{},
+	}, nil
+}
+
+// chess960Castling decodes castling rights written in X-FEN or
+// Shredder-FEN for the board.  K and Q name the outermost rook on the
+// side of the king and a file letter the rook on that file, in upper
+// case for white and lower case for black.
+func chess960Castling(b *Board, s string) (CastleRights, *castling, error) {
+	cs := standardCastling
+	if s == "-" {
+		return "-", &cs, nil
+	}
+	err := fmt.Errorf("chess: fen invalid castle rights %s", s)
+	cr := ""
+	for _, r := range s {
+		c := White
+		if unicode.IsLower(r) {
+			c = Black
+		}
+		king := b.kingSquare(c)
+		if king == NoSquare || king.Rank() != backRank(c) {
+			return "-", nil, err
+		}
+		rook := getPiece(Rook, c)
+		side := KingSide
+		file := File(-1)
+		switch u := unicode.ToUpper(r); {
+		case u == 'K' || u == 'Q':
+			dir := 1
+			if u == 'Q' {
+				side, dir = QueenSide, -1
+			}
+			for f := int(king.File()) + dir; f >= 0 && f < numOfSquaresInRow; f += dir {
+				if b.Piece(getSquare(File(f), king.Rank())) == rook {
+					file = File(f)
+				}
+			}
+		case u >= 'A' && u <= 'H':
+			file = File(u - 'A')
+			if file < king.File() {
+				side = QueenSide
+			}
+			if file == king.File() || b.Piece(getSquare(file, king.Rank())) != rook {
+				return "-", nil, err
+			}
+		default:
+			return "-", nil, err
+		}
+		if file < 0 {
+			return "-", nil, err
+		}
+		cs.king[c] = king.File()
+		cs.rooks[c][side] = file
+		cr += castleChar(c, side)
+	}
+	rights := ""
+	for _, ch := range []string{"K", "Q", "k", "q"} {
+		switch strings.Count(cr, ch) {
+		case 0:
+		case 1:
+			rights += ch
+		default:
+			return "-", nil, err
+		}
+	}
+	return CastleRights(rights), &cs, nil
+}
+
+// fenCastleRights returns the castling rights for the FEN.  Shredder-FEN
+// writes the file of every castling rook and X-FEN only the files of
+// rooks that aren't the outermost on their side.  Positions without
+// their own castling, which castle as in standard chess, write the
+// rights as they are unless Shredder-FEN is asked for.
+func (pos *Position) fenCastleRights(shredder bool) string {
+	if pos.castling == nil && !shredder {
+		return pos.castleRights.String()
+	}
+	s := ""
+	for _, c := range []Color{White, Black} {
+		for _, side := range castleSides {
+			if !pos.castleRights.CanCastle(c, side) {
+				continue
+			}
+			king, rook := pos.castleSquares(c, side)
+			if !shredder && pos.outermostRook(c, side, king, rook) {
+				s += castleChar(c, side)
+				continue
+			}
+			f := rook.File().String()
+			if c == White {
+				f = strings.ToUpper(f)
+			}
+			s += f
+		}
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// outermostRook returns true if no other rook of the color is further
+// from the king than the rook on the side.
+func (pos *Position) outermostRook(c Color, side Side, king, rook Square) bool {
+	dir := 1
+	if side == QueenSide {
+		dir = -1
+	}
+	for f := int(rook.File()) + dir; f >= 0 && f < numOfSquaresInRow; f += dir {
+		if pos.board.Piece(getSquare(File(f), king.Rank())) == getPiece(Rook, c) {
+			return false
+		}
+	}
+	return true
+}
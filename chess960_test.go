@@ -0,0 +1,314 @@
+package chess
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestChess960Position(t *testing.T) {
+	tables := []struct {
+		id  int
+		fen string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1"},
+		{518, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1"},
+	}
+	for _, table := range tables {
+		pos, err := Chess960Position(table.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.String() != table.fen {
+			t.Fatalf("expected chess960 position %d to be %s but got %s", table.id, table.fen, pos)
+		}
+		if n := len(pos.ValidMoves()); n != 20 {
+			t.Fatalf("expected 20 moves in chess960 position %d but got %d", table.id, n)
+		}
+		if _, err := FromVariantFEN(Chess960{}, pos.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []int{-1, 960} {
+		if _, err := Chess960Position(id); err == nil {
+			t.Fatalf("expected error for chess960 position %d", id)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tables := []struct {
+		fen  string
+		uci  string
+		san  string
+		next string
+	}{
+		// position 0 castles long with the king passing its rook
+		{"bb3rkr/pppppppp/8/8/8/8/PPPPPPPP/BB3RKR w KQkq - 0 1", "g1f1", "O-O-O", "bb3rkr/pppppppp/8/8/8/8/PPPPPPPP/BBKR3R b kq - 1 1"},
+		// position 959 castles short with the king jumping over its rook
+		{"rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKR4B w KQkq - 0 1", "b1c1", "O-O", "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/R4RKB b kq - 1 1"},
+		// position 518 castles like standard chess
+		{"r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1", "e1h1", "O-O", "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R4RK1 b kq - 1 1"},
+		{"r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1", "e8a8", "O-O-O", "2kr3r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQ - 1 2"},
+		// the king stays on its square and only the rook moves
+		{"4k3/8/8/8/8/8/8/6KR w K - 0 1", "g1h1", "O-O", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		// a castle and a king move to the same square
+		{"4k3/8/8/8/8/8/8/5K1R w K - 0 1", "f1h1", "O-O", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		{"4k3/8/8/8/8/8/8/5K1R w K - 0 1", "f1g1", "Kg1", "4k3/8/8/8/8/8/8/6KR b - - 1 1"},
+	}
+	for _, table := range tables {
+		pos, err := FromVariantFEN(Chess960{}, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := UCINotation{}.Decode(pos, table.uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			t.Fatalf("%s expected %s to be valid", table.fen, table.uci)
+		}
+		if s := (UCINotation{}).Encode(pos, valid); s != table.uci {
+			t.Fatalf("%s expected the move to encode as %s but got %s", table.fen, table.uci, s)
+		}
+		if s := (AlgebraicNotation{}).Encode(pos, valid); s != table.san {
+			t.Fatalf("%s expected the move to encode as %s but got %s", table.fen, table.san, s)
+		}
+		if san, err := (AlgebraicNotation{}).Decode(pos, table.san); err != nil || san.castleSide() != valid.castleSide() || san.String() != valid.String() {
+			t.Fatalf("%s expected %s to decode into %s but got %v %v", table.fen, table.san, valid, san, err)
+		}
+		if san, err := (RelaxedAlgebraicNotation{}).Decode(pos, strings.ToLower(table.san)); err != nil || san.castleSide() != valid.castleSide() || san.String() != valid.String() {
+			t.Fatalf("%s expected %s to decode into %s but got %v %v", table.fen, strings.ToLower(table.san), valid, san, err)
+		}
+		next := pos.Update(valid)
+		if next.String() != table.next {
+			t.Fatalf("%s expected %s to lead to %s but got %s", table.fen, table.uci, table.next, next)
+		}
+		if next.hash != generateZobristHash(next) {
+			t.Fatalf("%s expected incremental hash after %s to match the full hash", table.fen, table.uci)
+		}
+	}
+}
+
+func TestChess960CastlingBlocked(t *testing.T) {
+	fens := []string{
+		// the other rook stands on the king's destination
+		"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+		// the rook's destination is occupied
+		"rk2r3/8/8/8/8/8/8/RK1B2R1 w KQkq - 0 1",
+		// the king passes an attacked square
+		"1k2r3/8/8/8/8/8/8/RK5R w K - 0 1",
+		// the rook on a1 shields the king on b1 from the queen on h1
+		"1k6/8/8/8/8/8/8/RK5q w Q - 0 1",
+	}
+	for _, fen := range fens {
+		pos, err := FromVariantFEN(Chess960{}, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range pos.ValidMoves() {
+			if m.castleSide() != 0 {
+				t.Fatalf("%s expected no castle but got %s", fen, m)
+			}
+		}
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	tables := []struct {
+		fen   string
+		nodes []uint64
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002, 667366}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471, 273318}},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		for i, expected := range table.nodes {
+			depth := i + 1
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := Perft(pos, depth); nodes != expected {
+				t.Fatalf("%s expected perft %d to be %d but got %d", table.fen, depth, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestChess960FEN(t *testing.T) {
+	tables := []struct {
+		fen      string
+		xfen     string
+		shredder string
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", "KQkq", "HFhf"},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", "KQ", "GE"},
+		{startFEN, "KQkq", "HAha"},
+		// only rooks behind another rook need their file
+		{"4k3/8/8/8/8/8/8/RR2K3 w B - 0 1", "B", "B"},
+		{"4k3/8/8/8/8/8/8/RR2K3 w A - 0 1", "Q", "A"},
+		{"4k3/8/8/8/8/8/8/RR2K3 w Q - 0 1", "Q", "A"},
+		{"r1r1k3/8/8/8/8/8/8/4K3 w c - 0 1", "c", "c"},
+	}
+	for _, table := range tables {
+		pos, err := FromVariantFEN(Chess960{}, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.Split(pos.String(), " ")
+		if parts[2] != table.xfen {
+			t.Fatalf("%s expected the X-FEN castle rights %s but got %s", table.fen, table.xfen, parts[2])
+		}
+		parts = strings.Split(pos.ShredderFEN(), " ")
+		if parts[2] != table.shredder {
+			t.Fatalf("%s expected the Shredder-FEN castle rights %s but got %s", table.fen, table.shredder, parts[2])
+		}
+		// Shredder-FEN decodes into the same Chess960 position
+		cp, err := FromFEN(pos.ShredderFEN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Variant().Name() != "Chess960" || cp.String() != pos.String() {
+			t.Fatalf("%s expected %s to decode into the position but got %s in %s", table.fen, pos.ShredderFEN(), cp, cp.Variant().Name())
+		}
+	}
+	invalid := []string{
+		"4k3/8/8/8/8/8/8/4K3 w H - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w HH - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w HK - 0 1",
+		"4k3/8/8/8/8/8/8/R3K3 w K - 0 1",
+		"4k3/8/8/8/8/8/4K3/7R w H - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w X - 0 1",
+	}
+	for _, fen := range invalid {
+		if _, err := FromVariantFEN(Chess960{}, fen); err == nil {
+			t.Fatalf("expected an error for %s", fen)
+		}
+	}
+}
+
+func TestChess960Game(t *testing.T) {
+	pos, err := Chess960Position(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := VariantFEN(Chess960{}, pos.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt, UseNotation(UCINotation{}))
+	for _, s := range []string{"c2c3", "c7c6", "d1e3", "d8e6", "e1d3", "e8d6", "c1c2", "c8c7", "g1f1"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "bb3rkr/ppqppppp/2pnn3/8/8/2PNN3/PPQPPPPP/BBKR3R b kq - 7 5"
+	if g.Position().String() != expected {
+		t.Fatalf("expected %s but got %s", expected, g.Position())
+	}
+	pgn := g.String()
+	if !strings.Contains(pgn, `[Variant "Chess960"]`) || !strings.Contains(pgn, "5.g1f1") {
+		t.Fatalf("expected the PGN of the Chess960 game but got %s", pgn)
+	}
+	opt, err = PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2 := NewGame(opt); g2.Position().String() != expected {
+		t.Fatalf("expected the PGN to replay to %s but got %s", expected, g2.Position())
+	}
+}
+
+func TestChess960GameBinary(t *testing.T) {
+	tables := []struct {
+		fen   string
+		moves []string
+		next  string
+	}{
+		// the king stays on g1 and steps from f8 to g8
+		{"1r3k1r/pppppppp/8/8/8/8/PPPPPPPP/1R4KR w KQkq - 0 1", []string{"O-O", "O-O"}, "1r3rk1/pppppppp/8/8/8/8/PPPPPPPP/1R3RK1 w - - 2 2"},
+		{"1r3k1r/pppppppp/8/8/8/8/PPPPPPPP/1R3K1R w KQkq - 0 1", []string{"O-O", "O-O-O"}, "2kr3r/pppppppp/8/8/8/8/PPPPPPPP/1R3RK1 w - - 2 2"},
+		{"1r3k1r/pppppppp/8/8/8/8/PPPPPPPP/1R3K1R w KQkq - 0 1", []string{"Kg1", "Kg8"}, "1r4kr/pppppppp/8/8/8/8/PPPPPPPP/1R4KR w - - 2 2"},
+	}
+	for _, table := range tables {
+		opt, err := VariantFEN(Chess960{}, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(opt)
+		for _, s := range table.moves {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g.FEN() != table.next {
+			t.Fatalf("%s expected %v to lead to %s but got %s", table.fen, table.moves, table.next, g.FEN())
+		}
+		b, err := g.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp := NewGame()
+		if err := cp.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if cp.String() != g.String() || cp.FEN() != g.FEN() {
+			t.Fatalf("expected game %s to round trip but got %s", g, cp)
+		}
+	}
+}
+
+func TestChess960Encoding(t *testing.T) {
+	fens := []string{}
+	for _, id := range []int{0, 959} {
+		pos, err := Chess960Position(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fens = append(fens, pos.String())
+	}
+	// the rook on b1 is the outermost and the one on d1 may castle
+	fens = append(fens, "1r1rk3/pppppppp/8/8/8/8/PPPPPPPP/1R1RK3 w Dq - 0 1")
+	for _, fen := range fens {
+		pos, err := FromVariantFEN(Chess960{}, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := []*Position{}
+		cp, err := FromFEN(pos.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, cp)
+		text, err := pos.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp = &Position{}
+		if err := cp.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, cp)
+		data, err := json.Marshal(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp = &Position{}
+		if err := json.Unmarshal(data, cp); err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, cp)
+		for _, cp := range decoded {
+			if cp.String() != fen || cp.Variant().Name() != "Chess960" || cp.ShredderFEN() != pos.ShredderFEN() {
+				t.Fatalf("expected %s to decode into the Chess960 position but got %s in %s", fen, cp.ShredderFEN(), cp.Variant().Name())
+			}
+			if len(cp.ValidMoves()) != len(pos.ValidMoves()) {
+				t.Fatalf("expected %s to decode with the same moves", fen)
+			}
+		}
+	}
+}
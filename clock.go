@@ -0,0 +1,298 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A TimeControlStage is a period of a time control.  Time is added to
+// both clocks when the stage starts and Moves is the number of moves to
+// play in the stage, zero for the rest of the game.  Increment is added
+// after each move (Fischer).  Delay is the time at the start of each
+// move that isn't deducted from the clock, which covers both Bronstein
+// and simple (US) delay as they leave the same time after a move.
+type TimeControlStage struct {
+	Moves     int
+	Time      time.Duration
+	Increment time.Duration
+	Delay     time.Duration
+}
+
+// A TimeControl is the sequence of stages of a game's time control.  If
+// the last stage has a number of moves it is repeated.
+type TimeControl []TimeControlStage
+
+// ParseTimeControl parses the value of a PGN TimeControl tag such as
+// 300+2 or 40/7200:3600.  Stages are separated by colons and given in
+// seconds, optionally preceded by the number of moves and a slash and
+// followed by a plus and the increment.  An error is returned if the
+// value can't be parsed or the game doesn't have a time control.
+func ParseTimeControl(s string) (TimeControl, error) {
+	err := fmt.Errorf("chess: invalid time control %q", s)
+	if s == "" || s == "-" || s == "?" {
+		return nil, err
+	}
+	tc := TimeControl{}
+	for _, field := range strings.Split(s, ":") {
+		stage := TimeControlStage{}
+		if i := strings.Index(field, "/"); i != -1 {
+			moves, convErr := strconv.Atoi(field[:i])
+			if convErr != nil || moves <= 0 {
+				return nil, err
+			}
+			stage.Moves = moves
+			field = field[i+1:]
+		}
+		if i := strings.Index(field, "+"); i != -1 {
+			inc, convErr := strconv.ParseFloat(field[i+1:], 64)
+			if convErr != nil || inc < 0 {
+				return nil, err
+			}
+			stage.Increment = time.Duration(inc * float64(time.Second))
+			field = field[:i]
+		}
+		secs, convErr := strconv.ParseFloat(field, 64)
+		if convErr != nil || secs < 0 {
+			return nil, err
+		}
+		stage.Time = time.Duration(secs * float64(time.Second))
+		tc = append(tc, stage)
+	}
+	return tc, nil
+}
+
+// String returns the time control in the format of the PGN
+// TimeControl tag.  Delays can't be represented and are left out.
+func (tc TimeControl) String() string {
+	fields := []string{}
+	for _, stage := range tc {
+		s := strconv.FormatFloat(stage.Time.Seconds(), 'f', -1, 64)
+		if stage.Moves > 0 {
+			s = strconv.Itoa(stage.Moves) + "/" + s
+		}
+		if stage.Increment > 0 {
+			s += "+" + strconv.FormatFloat(stage.Increment.Seconds(), 'f', -1, 64)
+		}
+		fields = append(fields, s)
+	}
+	return strings.Join(fields, ":")
+}
+
+// A Clock is a chess clock following a time control.  Only the clock of
+// the side to move runs and pressing it after a move switches sides.
+type Clock struct {
+	tc        TimeControl
+	remaining map[Color]time.Duration
+	moves     map[Color]int
+	stage     map[Color]int
+	turn      Color
+	// spent is the time of the current move used before the clock
+	// was last stopped
+	spent     time.Duration
+	turnStart time.Time
+	running   bool
+	flagged   Color
+	now       func() time.Time
+}
+
+// NewClock returns a stopped clock for the time control with white to
+// move.  An empty time control gives a clock without time.
+func NewClock(tc TimeControl) *Clock {
+	c := &Clock{
+		tc:        append(TimeControl(nil), tc...),
+		remaining: map[Color]time.Duration{},
+		moves:     map[Color]int{},
+		stage:     map[Color]int{},
+		turn:      White,
+		flagged:   NoColor,
+		now:       time.Now,
+	}
+	if len(tc) > 0 {
+		c.remaining[White] = tc[0].Time
+		c.remaining[Black] = tc[0].Time
+	}
+	return c
+}
+
+// TimeControl returns the clock's time control.
+func (c *Clock) TimeControl() TimeControl {
+	return append(TimeControl(nil), c.tc...)
+}
+
+// Turn returns the color whose clock runs when the clock is started.
+func (c *Clock) Turn() Color {
+	return c.turn
+}
+
+// Running returns true if the clock has been started and not stopped.
+func (c *Clock) Running() bool {
+	return c.running
+}
+
+// Start starts the clock of the side to move.  Starting a running
+// clock or one that has flagged has no effect.
+func (c *Clock) Start() {
+	if c.running || c.flagged != NoColor {
+		return
+	}
+	c.running = true
+	c.turnStart = c.now()
+}
+
+// Stop stops the clock.  The time used in the current move is kept
+// when it's started again.
+func (c *Clock) Stop() {
+	if !c.running {
+		return
+	}
+	c.spent += c.now().Sub(c.turnStart)
+	c.running = false
+}
+
+// SetRemaining sets the color's remaining time, for example to adjust
+// the clock after an arbiter's decision or to resume an adjourned game.
+func (c *Clock) SetRemaining(color Color, d time.Duration) {
+	c.remaining[color] = d
+	if color == c.turn {
+		// the move starts over so that its delay isn't lost
+		c.spent = 0
+		c.turnStart = c.now()
+	}
+}
+
+// Remaining returns the color's remaining time.
+func (c *Clock) Remaining(color Color) time.Duration {
+	r := c.remaining[color]
+	if color == c.turn {
+		r -= c.used()
+	}
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+// Flagged returns the color that ran out of time and true, or false if
+// neither has.  The clock stops when a color flags.
+func (c *Clock) Flagged() (Color, bool) {
+	if c.flagged == NoColor && len(c.tc) > 0 && c.remaining[c.turn]-c.used() <= 0 {
+		c.Stop()
+		c.flagged = c.turn
+	}
+	return c.flagged, c.flagged != NoColor
+}
+
+// Press ends the move of the side to move and starts the opponent's
+// clock.  The move's increment is added and the next stage's time is
+// added once the stage's moves have been played.  An error is returned
+// if the clock isn't running or the side to move ran out of time.
+func (c *Clock) Press() error {
+	if color, flagged := c.Flagged(); flagged {
+		return fmt.Errorf("chess: %s ran out of time", color.Name())
+	}
+	if !c.running {
+		return errors.New("chess: clock isn't running")
+	}
+	color := c.turn
+	c.remaining[color] -= c.used()
+	if stage, ok := c.currentStage(color); ok {
+		c.remaining[color] += stage.Increment
+	}
+	if next, ok := c.countMove(color); ok {
+		c.remaining[color] += next.Time
+	}
+	c.turn = color.Other()
+	c.spent = 0
+	c.turnStart = c.now()
+	return nil
+}
+
+// used returns the time of the current move deducted from the clock.
+func (c *Clock) used() time.Duration {
+	used := c.spent
+	if c.running {
+		used += c.now().Sub(c.turnStart)
+	}
+	if stage, ok := c.currentStage(c.turn); ok {
+		used -= stage.Delay
+	}
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// countMove counts a move of the color and returns the stage it enters
+// and true if the move ends its current stage.
+func (c *Clock) countMove(color Color) (TimeControlStage, bool) {
+	c.moves[color]++
+	stage, ok := c.currentStage(color)
+	if !ok || stage.Moves == 0 {
+		return TimeControlStage{}, false
+	}
+	end := 0
+	for i := 0; i <= c.stage[color]; i++ {
+		end += c.stageAt(i).Moves
+	}
+	if c.moves[color] != end {
+		return TimeControlStage{}, false
+	}
+	c.stage[color]++
+	return c.currentStage(color)
+}
+
+// currentStage returns the color's current stage or false if the clock
+// has no time control.
+func (c *Clock) currentStage(color Color) (TimeControlStage, bool) {
+	if len(c.tc) == 0 {
+		return TimeControlStage{}, false
+	}
+	return c.stageAt(c.stage[color]), true
+}
+
+// stageAt returns the stage at the index repeating the last stage.
+func (c *Clock) stageAt(i int) TimeControlStage {
+	if i >= len(c.tc) {
+		i = len(c.tc) - 1
+	}
+	return c.tc[i]
+}
+
+func (c *Clock) copy() *Clock {
+	cp := *c
+	cp.tc = c.TimeControl()
+	cp.remaining = map[Color]time.Duration{White: c.remaining[White], Black: c.remaining[Black]}
+	cp.moves = map[Color]int{White: c.moves[White], Black: c.moves[Black]}
+	cp.stage = map[Color]int{White: c.stage[White], Black: c.stage[Black]}
+	return &cp
+}
+
+// canCheckmate returns true unless the color has a lone king or a king
+// and a single minor piece against a lone king.  It is used to decide
+// whether running out of time loses.
+func canCheckmate(b *Board, c Color) bool {
+	minors, others := 0, 0
+	opponent := 0
+	for sq := A1; sq <= H8; sq++ {
+		p := b.Piece(sq)
+		switch {
+		case p == NoPiece || p.Type() == King:
+		case p.Color() != c:
+			opponent++
+		case p.Type() == Knight || p.Type() == Bishop:
+			minors++
+		default:
+			others++
+		}
+	}
+	switch {
+	case others > 0 || minors > 1:
+		return true
+	case minors == 1:
+		return opponent > 0
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package chess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTime is a settable time source for clocks under test.
+type fakeTime struct {
+	t time.Time
+}
+
+func (f *fakeTime) now() time.Time {
+	return f.t
+}
+
+func (f *fakeTime) advance(d time.Duration) {
+	f.t = f.t.Add(d)
+}
+
+func newTestClock(tc TimeControl) (*Clock, *fakeTime) {
+	ft := &fakeTime{t: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewClock(tc)
+	c.now = ft.now
+	return c, ft
+}
+
+func TestParseTimeControl(t *testing.T) {
+	tables := []struct {
+		s        string
+		expected TimeControl
+	}{
+		{"300+2", TimeControl{{Time: 5 * time.Minute, Increment: 2 * time.Second}}},
+		{"5400", TimeControl{{Time: 90 * time.Minute}}},
+		{"40/7200:3600", TimeControl{{Moves: 40, Time: 2 * time.Hour}, {Time: time.Hour}}},
+		{"40/5400+30:1800+30", TimeControl{
+			{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second},
+			{Time: 30 * time.Minute, Increment: 30 * time.Second},
+		}},
+	}
+	for _, table := range tables {
+		tc, err := ParseTimeControl(table.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc, table.expected) {
+			t.Fatalf("expected %s to parse to %v but got %v", table.s, table.expected, tc)
+		}
+		if s := tc.String(); s != table.s {
+			t.Fatalf("expected time control string %s but got %s", table.s, s)
+		}
+	}
+	for _, s := range []string{"", "-", "?", "40/", "x+2", "0/300", "300+"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Fatalf("expected an error parsing time control %q", s)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	tables := []struct {
+		name     string
+		tc       TimeControl
+		moves    []time.Duration
+		expected time.Duration
+	}{
+		{"classical", TimeControl{{Time: time.Minute}}, []time.Duration{10 * time.Second, 5 * time.Second}, 45 * time.Second},
+		{"increment", TimeControl{{Time: time.Minute, Increment: 2 * time.Second}}, []time.Duration{10 * time.Second, 5 * time.Second}, 49 * time.Second},
+		{"delay", TimeControl{{Time: time.Minute, Delay: 5 * time.Second}}, []time.Duration{10 * time.Second, 3 * time.Second}, 55 * time.Second},
+		{"stages", TimeControl{{Moves: 2, Time: time.Minute}, {Time: time.Minute}}, []time.Duration{10 * time.Second, 5 * time.Second, time.Second}, 104 * time.Second},
+		{"repeated stage", TimeControl{{Moves: 1, Time: time.Minute}}, []time.Duration{10 * time.Second, 10 * time.Second}, 160 * time.Second},
+	}
+	for _, table := range tables {
+		c, ft := newTestClock(table.tc)
+		c.Start()
+		for _, d := range table.moves {
+			ft.advance(d)
+			if err := c.Press(); err != nil {
+				t.Fatal(err)
+			}
+			// black moves instantly
+			if err := c.Press(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if r := c.Remaining(White); r != table.expected {
+			t.Fatalf("%s: expected white to have %s left but got %s", table.name, table.expected, r)
+		}
+	}
+}
+
+func TestClockStopAndFlag(t *testing.T) {
+	c, ft := newTestClock(TimeControl{{Time: time.Minute, Delay: 5 * time.Second}})
+	c.Start()
+	ft.advance(3 * time.Second)
+	c.Stop()
+	ft.advance(time.Hour)
+	c.Start()
+	// the delay isn't given again after restarting
+	ft.advance(4 * time.Second)
+	if r := c.Remaining(White); r != 58*time.Second {
+		t.Fatalf("expected white to have 58s left but got %s", r)
+	}
+	ft.advance(time.Minute)
+	if color, flagged := c.Flagged(); !flagged || color != White {
+		t.Fatalf("expected white to flag but got %s %t", color, flagged)
+	}
+	if err := c.Press(); err == nil {
+		t.Fatal("expected an error pressing a flagged clock")
+	}
+	if c.Running() || c.Remaining(White) != 0 {
+		t.Fatalf("expected a stopped clock without time but got %t and %s", c.Running(), c.Remaining(White))
+	}
+}
+
+func TestGameClock(t *testing.T) {
+	c, ft := newTestClock(TimeControl{{Time: time.Minute, Increment: time.Second}})
+	g := NewGame(UseClock(c))
+	ft.advance(1500 * time.Millisecond)
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	ft.advance(20 * time.Second)
+	if err := g.MoveStr("e5"); err != nil {
+		t.Fatal(err)
+	}
+	nodes := g.mainLine()
+	if d, _ := nodes[0].Clock(); d != 59500*time.Millisecond {
+		t.Fatalf("expected white's clock to be 59.5s but got %s", d)
+	}
+	expected := "1.e4 {[%clk 0:00:59.5]} 1...e5 {[%clk 0:00:41]}  *"
+	if s := strings.TrimSpace(g.String()); s != expected {
+		t.Fatalf("expected pgn %s but got %s", expected, s)
+	}
+	ft.advance(time.Minute)
+	if g.Outcome() != BlackWon || g.Method() != Timeout {
+		t.Fatalf("expected black to win on time but got %s by %s", g.Outcome(), g.Method())
+	}
+	if err := g.MoveStr("Nf3"); err == nil {
+		t.Fatal("expected an error moving after running out of time")
+	}
+}
+
+func TestGameClockTimeoutDraw(t *testing.T) {
+	fen, _ := FEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	c, ft := newTestClock(TimeControl{{Time: time.Minute}})
+	g := NewGame(fen, UseClock(c))
+	ft.advance(2 * time.Minute)
+	if err := g.MoveStr("Ra8"); err == nil {
+		t.Fatal("expected an error moving after running out of time")
+	}
+	if g.Outcome() != Draw || g.Method() != Timeout {
+		t.Fatalf("expected a draw on time but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestGameClockFlagOnMove(t *testing.T) {
+	c, ft := newTestClock(TimeControl{{Time: time.Minute}})
+	g := NewGame(UseClock(c))
+	ft.advance(2 * time.Minute)
+	if err := g.MoveStr("e4"); err == nil {
+		t.Fatal("expected an error moving after running out of time")
+	}
+	if g.Outcome() != BlackWon || g.Method() != Timeout || len(g.Moves()) != 0 {
+		t.Fatalf("expected black to win on time without the move but got %s by %s after %d moves", g.Outcome(), g.Method(), len(g.Moves()))
+	}
+}
+
+func TestGameClockStopped(t *testing.T) {
+	c, _ := newTestClock(TimeControl{{Time: time.Minute}})
+	g := NewGame(UseClock(c))
+	c.Stop()
+	if err := g.MoveStr("e4"); err == nil {
+		t.Fatal("expected an error moving with a stopped clock")
+	}
+	if g.Outcome() != NoOutcome || g.Method() != NoMethod {
+		t.Fatalf("expected the game to continue but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestGameClockResume(t *testing.T) {
+	pgn := "1. e4 {[%clk 0:04:58]} e5 {[%clk 0:04:50]} 2. Nf3 {[%clk 0:04:40]} *"
+	gameFunc, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClock(TimeControl{{Time: 5 * time.Minute}})
+	g := NewGame(gameFunc, UseClock(c))
+	if g.Clock().Turn() != Black || !g.Clock().Running() {
+		t.Fatalf("expected black's running clock but got %s %t", g.Clock().Turn(), g.Clock().Running())
+	}
+	if c.Remaining(White) != 4*time.Minute+40*time.Second || c.Remaining(Black) != 4*time.Minute+50*time.Second {
+		t.Fatalf("expected 4m40s and 4m50s but got %s and %s", c.Remaining(White), c.Remaining(Black))
+	}
+}
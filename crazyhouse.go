@@ -0,0 +1,232 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const crazyhouseStartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+
+// dropPieceTypes are the piece types that can be held in a pocket in
+// the order they are written in FEN.
+var dropPieceTypes = []PieceType{Queen, Rook, Bishop, Knight, Pawn}
+
+// pockets holds the pieces captured by each side in Crazyhouse.  A
+// captured piece changes color and joins the capturer's pocket, from
+// where it can be dropped instead of moving.  Pieces that were
+// promoted are marked since they return to the pocket as pawns.
+type pockets struct {
+	counts   [3][7]int
+	promoted bitboard
+}
+
+// CrazyhousePosition returns the Crazyhouse starting position which is
+// the standard starting position with empty pockets.
+func CrazyhousePosition() *Position {
+	pos, _ := decodeFEN(crazyhouseStartFEN)
+	return pos
+}
+
+// HasPockets returns true if the position has pockets to hold captured
+// pieces as in Crazyhouse.  Only positions with pockets have drops.
+func (pos *Position) HasPockets() bool {
+	return pos.pockets != nil
+}
+
+// Pocket returns the number of pieces of each type in the color's
+// pocket.  Piece types the pocket doesn't hold are omitted and an empty
+// map is returned if the position has no pockets.
+func (pos *Position) Pocket(c Color) map[PieceType]int {
+	m := map[PieceType]int{}
+	for _, pt := range dropPieceTypes {
+		if n := pos.pockets.count(c, pt); n > 0 {
+			m[pt] = n
+		}
+	}
+	return m
+}
+
+// IsPromoted returns true if the piece on the square was promoted from
+// a pawn.  Promotions are only tracked in positions with pockets.
+func (pos *Position) IsPromoted(sq Square) bool {
+	return pos.pockets != nil && pos.pockets.promoted&bbForSquare(sq) != 0
+}
+
+func (p *pockets) count(c Color, pt PieceType) int {
+	if p == nil || c == NoColor {
+		return 0
+	}
+	return p.counts[c][pt]
+}
+
+func (p *pockets) equal(o *pockets) bool {
+	if p == nil || o == nil {
+		return p == o
+	}
+	return p.counts == o.counts
+}
+
+// capturedType returns the type the piece captured on the square has in
+// the capturer's pocket.
+func (p *pockets) capturedType(b *Board, sq Square) PieceType {
+	if p.promoted&bbForSquare(sq) != 0 {
+		return Pawn
+	}
+	return b.Piece(sq).Type()
+}
+
+// updatePockets returns the pockets after the move.  The pockets of a
+// position are never modified so they can be shared.
+func (pos *Position) updatePockets(m *Move) *pockets {
+	if pos.pockets == nil {
+		return nil
+	}
+	next := *pos.pockets
+	switch {
+	case m.drop != NoPieceType:
+		next.counts[pos.turn][m.drop]--
+	case m.HasTag(EnPassant):
+		next.counts[pos.turn][Pawn]++
+	case m.HasTag(Capture):
+		next.counts[pos.turn][pos.pockets.capturedType(pos.board, m.S2)]++
+	}
+	// promoted pieces keep their mark as they move
+	s1, s2 := bbForSquare(m.S1), bbForSquare(m.S2)
+	promoted := next.promoted &^ s2
+	if m.drop == NoPieceType && next.promoted&s1 != 0 {
+		promoted = promoted&^s1 | s2
+	}
+	if m.promo != NoPieceType {
+		promoted |= s2
+	}
+	next.promoted = promoted
+	return &next
+}
+
+// updateBoard plays the move on the board for the side to move.  A drop
+// puts the piece on its square.
+func (pos *Position) updateBoard(b *Board, m *Move) {
+	if m.drop != NoPieceType {
+		b.drop(getPiece(m.drop, pos.turn), m.S2)
+		return
+	}
+	if side := m.castleSide(); side != 0 {
+		c := b.Piece(m.S1).Color()
+		_, rook := pos.castleSquares(c, side)
+		_, rookTo := castleDestinations(c, side)
+		b.castle(m.S1, m.S2, rook, rookTo)
+		return
+	}
+	b.update(m)
+}
+
+// dropMoves returns the valid drops of the side to move on the target
+// squares.  Pawns can't be dropped on the first or last rank.
+func dropMoves(pos *Position, first bool, targets bitboard) []*Move {
+	moves := []*Move{}
+	if pos.pockets == nil {
+		return moves
+	}
+	for _, pt := range dropPieceTypes {
+		if pos.pockets.count(pos.turn, pt) == 0 {
+			continue
+		}
+		bb := pos.board.emptySqs & targets
+		if pt == Pawn {
+			bb &^= bbRank1 | bbRank8
+		}
+		for sq := 0; sq < numOfSquaresInBoard; sq++ {
+			if bb&bbForSquare(Square(sq)) == 0 {
+				continue
+			}
+			m := DropMove(pt, Square(sq))
+			addTags(m, pos)
+			// filter out drops that leave the king in check
+			if !m.HasTag(inCheck) {
+				moves = append(moves, m)
+				if first {
+					return moves
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// parseFENPockets splits the pockets from the board field of a
+// Crazyhouse FEN.  The pockets follow the board either in brackets,
+// rnbqkbnr/.../RNBQKBNR[Qn], or as a ninth rank, .../RNBQKBNR/Qn, and a
+// promoted piece is followed by a tilde.  Nil pockets are returned if
+// the field has no pockets.
+func parseFENPockets(boardStr string) (string, *pockets, error) {
+	pocketStr, found := "", false
+	if i := strings.Index(boardStr, "["); i != -1 {
+		if !strings.HasSuffix(boardStr, "]") {
+			return "", nil, fmt.Errorf("chess: fen invalid pockets %s", boardStr[i:])
+		}
+		pocketStr, boardStr, found = boardStr[i+1:len(boardStr)-1], boardStr[:i], true
+	} else if ranks := strings.Split(boardStr, "/"); len(ranks) == 9 {
+		pocketStr, boardStr, found = ranks[8], strings.Join(ranks[:8], "/"), true
+	}
+	if !found && !strings.Contains(boardStr, "~") {
+		return boardStr, nil, nil
+	}
+	p := &pockets{}
+	for _, r := range pocketStr {
+		piece := fenPieceMap[string(r)]
+		if piece == NoPiece || piece.Type() == King {
+			return "", nil, fmt.Errorf("chess: fen invalid pocket piece %c", r)
+		}
+		p.counts[piece.Color()][piece.Type()]++
+	}
+	// the tildes are removed leaving a standard board
+	board := ""
+	sq := int(A8)
+	for _, r := range boardStr {
+		switch {
+		case r == '~':
+			if sq == int(A8) || !strings.ContainsRune("QRBNqrbn", rune(board[len(board)-1])) {
+				return "", nil, errors.New("chess: fen invalid promoted marker")
+			}
+			p.promoted |= bbForSquare(Square(sq - 1))
+			continue
+		case r == '/':
+			sq -= 16
+		case r >= '1' && r <= '8':
+			sq += int(r - '0')
+		default:
+			sq++
+		}
+		board += string(r)
+	}
+	return board, p, nil
+}
+
+// fen returns the board field of the FEN with the promoted pieces
+// marked and the pockets in brackets.
+func (p *pockets) fen(boardStr string) string {
+	board := ""
+	sq := int(A8)
+	for _, r := range boardStr {
+		board += string(r)
+		switch {
+		case r == '/':
+			sq -= 16
+		case r >= '1' && r <= '8':
+			sq += int(r - '0')
+		default:
+			if p.promoted&bbForSquare(Square(sq)) != 0 {
+				board += "~"
+			}
+			sq++
+		}
+	}
+	s := ""
+	for _, c := range []Color{White, Black} {
+		for _, pt := range dropPieceTypes {
+			s += strings.Repeat(getPiece(pt, c).getFENChar(), p.count(c, pt))
+		}
+	}
+	return board + "[" + s + "]"
+}
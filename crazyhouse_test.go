@@ -0,0 +1,145 @@
+package chess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrazyhouseFEN(t *testing.T) {
+	tables := []struct {
+		fen      string
+		expected string
+	}{
+		{crazyhouseStartFEN, crazyhouseStartFEN},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[QPnn] w KQkq - 0 1", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[QPnn] w KQkq - 0 1"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R/nPn w KQkq - 0 1", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[Pnn] w KQkq - 0 1"},
+		{"2Q~1k3/8/8/8/8/8/8/4K3[] b - - 0 1", "2Q~1k3/8/8/8/8/8/8/4K3[] b - - 0 1"},
+	}
+	for _, table := range tables {
+		pos, err := FromFEN(table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := pos.String(); s != table.expected {
+			t.Fatalf("expected fen %s but got %s", table.expected, s)
+		}
+	}
+	pos := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[QPnn] w KQkq - 0 1")
+	if p := pos.Pocket(Black); !reflect.DeepEqual(p, map[PieceType]int{Knight: 2}) {
+		t.Fatalf("expected black to have two knights in the pocket but got %v", p)
+	}
+	if !pos.HasPockets() || StartingPosition().HasPockets() {
+		t.Fatal("expected only the crazyhouse position to have pockets")
+	}
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/4K3[K] w - - 0 1",
+		"4k3/8/8/8/8/8/8/4K3[P w - - 0 1",
+		"4k3/8/8/8/8/8/8/~4K3[] w - - 0 1",
+	} {
+		if _, err := FromFEN(fen); err == nil {
+			t.Fatalf("expected an error decoding fen %s", fen)
+		}
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	fen, err := FEN(CrazyhousePosition().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	for _, s := range []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qa5", "P@d5", "@d4"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos := g.Position()
+	if p := pos.Pocket(White); len(p) != 0 {
+		t.Fatalf("expected white's pocket to be empty but got %v", p)
+	}
+	if p := pos.Pocket(Black); len(p) != 0 {
+		t.Fatalf("expected black's pocket to be empty but got %v", p)
+	}
+	if pos.Board().Piece(D4) != BlackPawn || pos.Board().Piece(D5) != WhitePawn {
+		t.Fatalf("expected the dropped pieces on d4 and d5 but got %s", pos)
+	}
+	if pos.Hash() != generateZobristHash(pos) {
+		t.Fatal("expected the updated hash to match the hash of the position")
+	}
+	history := g.MoveHistory(UCINotation{})
+	if history[6] != "P@d5" || history[7] != "P@d4" {
+		t.Fatalf("expected drops P@d5 and P@d4 but got %v", history)
+	}
+	if m, err := (UCINotation{}).Decode(pos, "Q@e2"); err != nil || m.Drop() != Queen || m.S2 != E2 {
+		t.Fatalf("expected a queen drop on e2 but got %s %v", m, err)
+	}
+}
+
+func TestCrazyhouseCheckmate(t *testing.T) {
+	tables := []struct {
+		fen    string
+		method Method
+		drops  []string
+	}{
+		{"4k3/8/8/8/8/8/3PPP2/r3K3[] w - - 0 1", Checkmate, []string{}},
+		{"4k3/8/8/8/8/8/3PPP2/r3K3[N] w - - 0 1", NoMethod, []string{"N@b1", "N@c1", "N@d1"}},
+		// pawns can't be dropped on the first rank
+		{"4k3/8/8/8/8/8/3PPP2/r3K3[P] w - - 0 1", Checkmate, []string{}},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if method := pos.Status(); method != table.method {
+			t.Fatalf("expected %s to have status %s but got %s", table.fen, table.method, method)
+		}
+		drops := []string{}
+		for _, m := range pos.EvasionMoves() {
+			drops = append(drops, AlgebraicNotation{}.Encode(pos, m))
+		}
+		if !reflect.DeepEqual(drops, table.drops) {
+			t.Fatalf("expected %s to have evasions %v but got %v", table.fen, table.drops, drops)
+		}
+	}
+	// a drop can deliver mate
+	pos := unsafeFEN("6rk/6pp/8/8/8/8/8/K7[N] w - - 0 1")
+	m, err := AlgebraicNotation{}.Decode(pos, "N@f7#")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := (AlgebraicNotation{}).Encode(pos, m); s != "N@f7#" {
+		t.Fatalf("expected smothered mate N@f7# but got %s", s)
+	}
+}
+
+func TestCrazyhousePromotedCapture(t *testing.T) {
+	pos := unsafeFEN("4k3/8/8/8/8/8/r7/Q~5K1[] b - - 0 1")
+	m, err := AlgebraicNotation{}.Decode(pos, "Rxa1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := pos.Update(m)
+	if p := next.Pocket(Black); !reflect.DeepEqual(p, map[PieceType]int{Pawn: 1}) {
+		t.Fatalf("expected the promoted queen to return as a pawn but got %v", p)
+	}
+	if next.IsPromoted(A1) || next.Hash() != generateZobristHash(next) {
+		t.Fatalf("expected the capture to clear the promotion and keep the hash but got %s", next)
+	}
+}
+
+func TestCrazyhousePGN(t *testing.T) {
+	pgn := `[Variant "Crazyhouse"]
+
+1. e4 e5 2. Nf3 Nc6 3. Nxe5 Nxe5 4. P@d4 N@f3+ 5. gxf3 *`
+	for i := 0; i < 2; i++ {
+		read, err := PGN(strings.NewReader(pgn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(read)
+		if p := g.Position().Pocket(White); !reflect.DeepEqual(p, map[PieceType]int{Knight: 1}) {
+			t.Fatalf("expected white to have a knight in the pocket but got %v", p)
+		}
+		// the encoded game starts from the crazyhouse position's FEN
+		pgn = g.String()
+	}
+}
@@ -0,0 +1,153 @@
+package chess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DescriptiveNotation is the English descriptive notation found in
+// older books and game collections.  Squares are named by the file's
+// original piece and a rank counted from the moving side, so white's
+// e4 and black's e5 are both K4.  Examples: P-K4, N-KB3, NxB, PxP e.p.,
+// R/1-Q1 (the rook on the first rank), P-K8=Q (promotion), O-O (short
+// castling).  Kt is accepted for knights and check, mate and
+// annotation suffixes are ignored.  A promotion without a piece is
+// decoded as a queen.  DescriptiveNotation only implements the Decoder
+// interface.
+type DescriptiveNotation struct{}
+
+// String implements the fmt.Stringer interface and returns
+// the notation's name.
+func (DescriptiveNotation) String() string {
+	return "Descriptive Notation"
+}
+
+var descriptiveRegex = regexp.MustCompile(`^` +
+	`([KQ]?[RNB]?P|[KQ]?[RNB]|K|Q)` + // moving piece
+	`(?:/([KQ]?[RNB]?)([1-8]?))?` + // origin qualifier
+	`(?:-([KQ]?[RNB]?)([1-8])` + // destination square
+	`|x([KQ]?[RNB]?P|[KQ]?[RNB]|K|Q)(?:/([KQ]?[RNB]?)([1-8]))?)` + // captured piece
+	`(?:[=/(]?([QRBN])\)?)?$`) // promotion
+
+var descriptiveFiles = map[string]File{
+	"QR": FileA, "QN": FileB, "QB": FileC, "Q": FileD,
+	"K": FileE, "KB": FileF, "KN": FileG, "KR": FileH,
+}
+
+// Decode implements the Decoder interface.  An error is returned if
+// the text doesn't match exactly one valid move.
+func (DescriptiveNotation) Decode(pos *Position, s string) (*Move, error) {
+	err := fmt.Errorf("chess: could not decode descriptive notation %s for position %s", s, pos)
+	s = strings.Replace(s, "Kt", "N", -1)
+	s = removeSubstrings(s, " ", "e.p.", "ep", "dbl", "dis", "ch", "mate", "+", "#", "!", "?")
+	s = strings.Replace(s, "0", "O", -1)
+	match := descriptiveRegex.FindStringSubmatch(s)
+	var matches []*Move
+	for _, m := range pos.ValidMoves() {
+		switch {
+		case s == "O-O" && m.HasTag(KingSideCastle),
+			s == "O-O-O" && m.HasTag(QueenSideCastle),
+			match != nil && matchesDescriptive(pos, m, match):
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) != 1 {
+		return nil, err
+	}
+	return matches[0], nil
+}
+
+// matchesDescriptive returns true if the move fits the description
+// matched by descriptiveRegex.
+func matchesDescriptive(pos *Position, m *Move, match []string) bool {
+	if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+		return false
+	}
+	c := pos.Turn()
+	p := pos.Board().Piece(m.S1)
+	if !matchesDescriptivePiece(match[1], p.Type(), m.S1.File()) {
+		return false
+	}
+	if match[2] != "" || match[3] != "" {
+		if !matchesDescriptiveSquare(match[2], match[3], c, m.S1) {
+			return false
+		}
+	}
+	if match[5] != "" && !matchesDescriptiveSquare(match[4], match[5], c, m.S2) {
+		return false
+	}
+	if match[6] != "" {
+		captured := pos.Board().Piece(m.S2).Type()
+		if m.HasTag(EnPassant) {
+			captured = Pawn
+		}
+		if captured == NoPieceType || !matchesDescriptivePiece(match[6], captured, m.S2.File()) {
+			return false
+		}
+		if match[8] != "" && !matchesDescriptiveSquare(match[7], match[8], c, m.S2) {
+			return false
+		}
+	}
+	promo := NoPieceType
+	if match[9] != "" {
+		promo = pieceTypeFromChar(match[9])
+	} else if m.promo != NoPieceType {
+		promo = Queen
+	}
+	return m.promo == promo
+}
+
+// matchesDescriptivePiece returns true if the piece on the file fits
+// the description such as N, KR or QBP.
+func matchesDescriptivePiece(desc string, pt PieceType, f File) bool {
+	switch desc {
+	case "K":
+		return pt == King
+	case "Q":
+		return pt == Queen
+	}
+	if strings.HasSuffix(desc, "P") {
+		// a pawn is named by its file such as KBP or RP
+		return pt == Pawn && matchesDescriptiveFile(strings.TrimSuffix(desc, "P"), f)
+	}
+	side := ""
+	if desc[0] == 'K' || desc[0] == 'Q' {
+		side, desc = desc[:1], desc[1:]
+	}
+	if pieceTypeFromChar(desc) != pt {
+		return false
+	}
+	// a piece is named by the side of the board it stands on
+	return side == "" || (f >= FileE) == (side == "K")
+}
+
+// matchesDescriptiveFile returns true if the file fits the description
+// such as KB or B for either bishop file.  An empty description fits
+// every file.
+func matchesDescriptiveFile(desc string, f File) bool {
+	switch desc {
+	case "":
+		return true
+	case "R", "N", "B":
+		return descriptiveFiles["K"+desc] == f || descriptiveFiles["Q"+desc] == f
+	}
+	file, ok := descriptiveFiles[desc]
+	return ok && file == f
+}
+
+// matchesDescriptiveSquare returns true if the square fits the file and
+// the rank counted from the color's side.  Either may be left empty.
+func matchesDescriptiveSquare(file, rank string, c Color, sq Square) bool {
+	if !matchesDescriptiveFile(file, sq.File()) {
+		return false
+	}
+	if rank == "" {
+		return true
+	}
+	r := Rank(rank[0] - '1')
+	if c == Black {
+		r = Rank8 - r
+	}
+	return r == sq.Rank()
+}
@@ -0,0 +1,62 @@
+package chess
+
+import "testing"
+
+func TestDescriptiveNotation(t *testing.T) {
+	tables := []struct {
+		fen string
+		s   string
+		uci string
+	}{
+		{startFEN, "P-K4", "e2e4"},
+		{startFEN, "N-KB3", "g1f3"},
+		{startFEN, "Kt-QB3", "b1c3"},
+		{startFEN, "QP-Q4", "d2d4"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", "P-K4", "e7e5"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", "P-QB4", "c7c5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "PxP", "e4d5"},
+		{"rnbqkb1r/pppp1ppp/5n2/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "NxP", "f3e5"},
+		{"rnbqkb1r/pppp1ppp/5n2/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "B-N5ch", "f1b5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "PxP e.p.", "e5d6"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "QR-Q1", "a1d1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "KR-KB1", "h1f1"},
+		{"4k3/8/8/8/8/8/R7/R3K3 w - - 0 1", "R/1-Q1", "a1d1"},
+		{"4k3/8/1n3n2/8/8/8/8/4K3 b - - 0 1", "KN-Q4", "f6d5"},
+		{"4k3/8/1n3n2/3P4/8/8/8/4K3 b - - 0 1", "QNxP", "b6d5"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "P-N8=N", "b7b8n"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "P-N8(R)", "b7b8r"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "P-N8", "b7b8q"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := DescriptiveNotation{}.Decode(pos, table.s)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		if m.String() != table.uci {
+			t.Fatalf("expected %s to decode to %s but got %s", table.s, table.uci, m)
+		}
+	}
+}
+
+func TestDescriptiveNotationErrors(t *testing.T) {
+	tables := []struct {
+		fen string
+		s   string
+	}{
+		// both knights can reach Q4
+		{"4k3/8/1n3n2/3P4/8/8/8/4K3 b - - 0 1", "NxP"},
+		{"4k3/8/8/3p4/2P1P3/8/8/4K3 w - - 0 1", "PxP"},
+		{startFEN, "P-K5"},
+		{startFEN, "e4"},
+		{startFEN, "O-O"},
+		{startFEN, "NxP"},
+	}
+	for _, table := range tables {
+		if _, err := (DescriptiveNotation{}).Decode(unsafeFEN(table.fen), table.s); err == nil {
+			t.Fatalf("expected %s to fail decoding", table.s)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package chess
+
+import "math"
+
+// ExpectedScore returns the score player A is expected to achieve
+// against player B according to the Elo rating system.  The result
+// is in the range 0 to 1 where 0.5 means both players are equal.
+func ExpectedScore(ratingA, ratingB int) float64 {
+	return 1 / (1 + math.Pow(10, float64(ratingB-ratingA)/400))
+}
+
+// NewRating returns the rating after a game given the old rating,
+// the achieved score (1 for a win, 0.5 for a draw and 0 for a loss),
+// the expected score and the K-factor.  The result is rounded to the
+// nearest integer.
+func NewRating(old int, score, expected float64, k int) int {
+	return old + int(math.Round(float64(k)*(score-expected)))
+}
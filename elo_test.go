@@ -0,0 +1,43 @@
+package chess
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpectedScore(t *testing.T) {
+	tables := []struct {
+		a, b     int
+		expected float64
+	}{
+		{1500, 1500, 0.5},
+		{1800, 1400, 0.909},
+		{1400, 1800, 0.091},
+		{2000, 1900, 0.640},
+	}
+	for _, table := range tables {
+		if e := ExpectedScore(table.a, table.b); math.Abs(e-table.expected) > 0.001 {
+			t.Fatalf("expected score of %d against %d to be %.3f but got %.3f", table.a, table.b, table.expected, e)
+		}
+	}
+}
+
+func TestNewRating(t *testing.T) {
+	tables := []struct {
+		old      int
+		score    float64
+		expected float64
+		k        int
+		rating   int
+	}{
+		{1500, 1, 0.5, 32, 1516},
+		{1500, 0.5, 0.5, 32, 1500},
+		{1613, 0, 0.640, 20, 1600},
+		{1400, 1, ExpectedScore(1400, 1800), 32, 1429},
+	}
+	for _, table := range tables {
+		if r := NewRating(table.old, table.score, table.expected, table.k); r != table.rating {
+			t.Fatalf("expected new rating of %d to be %d but got %d", table.old, table.rating, r)
+		}
+	}
+}
@@ -4,22 +4,81 @@ type engine struct{}
 
 func (engine) CalcMoves(pos *Position, first bool) []*Move {
 	// generate possible moves
-	moves := standardMoves(pos, first)
-	// return moves including castles
-	return append(moves, castleMoves(pos)...)
+	moves := standardMoves(pos, first, allTargets)
+	if first && len(moves) > 0 {
+		return moves
+	}
+	// return moves including castles and drops
+	moves = append(moves, castleMoves(pos)...)
+	return append(moves, dropMoves(pos, first && len(moves) == 0, ^bitboard(0))...)
 }
 
-func (engine) Status(pos *Position) Method {
-	hasMove := false
-	if pos.validMoves != nil {
-		hasMove = len(pos.validMoves) > 0
-	} else {
-		hasMove = len(engine{}.CalcMoves(pos, true)) > 0
+func (engine) CalcCaptures(pos *Position) []*Move {
+	bbEnemy := pos.board.blackSqs
+	if pos.Turn() == Black {
+		bbEnemy = pos.board.whiteSqs
+	}
+	// pawns may also capture en passant or promote on an empty square
+	bbPawnEnemy := bbEnemy | bbRank1 | bbRank8
+	if pos.enPassantSquare != NoSquare {
+		bbPawnEnemy |= bbForSquare(pos.enPassantSquare)
+	}
+	// castles never capture so only standard moves are needed
+	return standardMoves(pos, false, moveTargets{pieces: bbEnemy, pawns: bbPawnEnemy, king: bbEnemy})
+}
+
+func (engine) CalcPromotions(pos *Position) []*Move {
+	bbPromo := bbRank8
+	if pos.Turn() == Black {
+		bbPromo = bbRank1
+	}
+	return standardMoves(pos, false, moveTargets{pawns: bbPromo})
+}
+
+func (engine) CalcEvasions(pos *Position) []*Move {
+	kingSq := pos.board.kingSquare(pos.Turn())
+	if kingSq == NoSquare {
+		return []*Move{}
+	}
+	checkers := pos.board.attackersOf(kingSq, pos.Turn().Other(), ^pos.board.emptySqs)
+	switch checkers.count() {
+	case 0:
+		return []*Move{}
+	case 1:
+	default:
+		// double check can only be answered by a king move
+		return standardMoves(pos, false, moveTargets{king: ^bitboard(0)})
+	}
+	// a single check is answered by moving the king, capturing
+	// the checker or blocking the line between it and the king
+	var checkerSq Square
+	for sq := 0; sq < numOfSquaresInBoard; sq++ {
+		if checkers.Occupied(Square(sq)) {
+			checkerSq = Square(sq)
+			break
+		}
+	}
+	bbBlock := checkers | bbBetween(kingSq, checkerSq)
+	bbPawnBlock := bbBlock
+	// a checking pawn that just advanced two squares can be taken en passant
+	if pos.enPassantSquare != NoSquare && pos.board.Piece(checkerSq).Type() == Pawn &&
+		checkerSq.File() == pos.enPassantSquare.File() {
+		bbPawnBlock |= bbForSquare(pos.enPassantSquare)
 	}
+	// castling out of check is illegal so only standard moves and drops
+	// between the king and the checker are needed
+	moves := standardMoves(pos, false, moveTargets{pieces: bbBlock, pawns: bbPawnBlock, king: ^bitboard(0)})
+	return append(moves, dropMoves(pos, false, bbBetween(kingSq, checkerSq))...)
+}
+
+func (engine) Status(pos *Position) Method {
+	hasMove := pos.HasLegalMoves()
 	if !pos.inCheck && !hasMove {
 		return Stalemate
 	} else if pos.inCheck && !hasMove {
 		return Checkmate
+	} else if pos.standardInsufficientMaterial() {
+		return InsufficientMaterial
 	}
 	return NoMethod
 }
@@ -28,13 +87,86 @@ var (
 	promoPieceTypes = []PieceType{Queen, Rook, Bishop, Knight}
 )
 
-func standardMoves(pos *Position, first bool) []*Move {
+// moveTargets restricts the destination squares searched by
+// standardMoves for kings, pawns and all other pieces.
+type moveTargets struct {
+	pieces bitboard
+	pawns  bitboard
+	king   bitboard
+}
+
+var (
+	allTargets = moveTargets{pieces: ^bitboard(0), pawns: ^bitboard(0), king: ^bitboard(0)}
+)
+
+func (engine) AppendMoves(pos *Position, moves []Move) []Move {
+	moves = appendStandardMoves(pos, moves)
+	moves = appendCastleMoves(pos, moves)
+	for _, m := range dropMoves(pos, false, ^bitboard(0)) {
+		moves = append(moves, *m)
+	}
+	return moves
+}
+
+func (engine) CalcQuiets(pos *Position) []*Move {
+	// pawns may not capture en passant or promote
+	bbPawnQuiet := pos.board.emptySqs &^ (bbRank1 | bbRank8)
+	if pos.enPassantSquare != NoSquare {
+		bbPawnQuiet &^= bbForSquare(pos.enPassantSquare)
+	}
+	moves := standardMoves(pos, false, moveTargets{pieces: pos.board.emptySqs, pawns: bbPawnQuiet, king: pos.board.emptySqs})
+	moves = append(moves, castleMoves(pos)...)
+	return append(moves, dropMoves(pos, false, ^bitboard(0))...)
+}
+
+func (engine) CalcPseudoLegal(pos *Position) []*Move {
+	moves := []*Move{}
+	eachStandardMove(pos, allTargets, func(m Move) bool {
+		pos.NormalizeTags(&m)
+		moves = append(moves, &m)
+		return true
+	})
+	// castles and drops are checked for legality when generated
+	moves = append(moves, castleMoves(pos)...)
+	return append(moves, dropMoves(pos, false, ^bitboard(0))...)
+}
+
+func standardMoves(pos *Position, first bool, targets moveTargets) []*Move {
+	moves := []*Move{}
+	eachStandardMove(pos, targets, func(m Move) bool {
+		addTags(&m, pos)
+		// filter out moves that put king into check
+		if m.HasTag(inCheck) {
+			return true
+		}
+		moves = append(moves, &m)
+		return !first
+	})
+	return moves
+}
+
+// appendStandardMoves appends the legal moves of the side to move's
+// pieces to moves without allocating each move.
+func appendStandardMoves(pos *Position, moves []Move) []Move {
+	eachStandardMove(pos, allTargets, func(m Move) bool {
+		addTags(&m, pos)
+		if !m.HasTag(inCheck) {
+			moves = append(moves, m)
+		}
+		return true
+	})
+	return moves
+}
+
+// eachStandardMove calls f with the untagged moves of the side to move's
+// pieces to the target squares, including those that leave the king in
+// check, until f returns false.
+func eachStandardMove(pos *Position, targets moveTargets, f func(m Move) bool) {
 	// compute allowed destination bitboard
 	bbAllowed := ^pos.board.whiteSqs
 	if pos.Turn() == Black {
 		bbAllowed = ^pos.board.blackSqs
 	}
-	moves := []*Move{}
 	// iterate through pieces to find possible moves
 	for _, p := range allPieces {
 		if pos.Turn() != p.Color() {
@@ -50,7 +182,7 @@ func standardMoves(pos *Position, first bool) []*Move {
 				continue
 			}
 			// iterate through possible destination squares for piece
-			S2BB := bbForPossibleMoves(pos, p.Type(), Square(S1)) & bbAllowed
+			S2BB := bbForPossibleMoves(pos, p.Type(), Square(S1)) & bbAllowed & targets.forPieceType(p.Type())
 			if S2BB == 0 {
 				continue
 			}
@@ -61,54 +193,94 @@ func standardMoves(pos *Position, first bool) []*Move {
 				// add promotions if pawn on promo square
 				if (p == WhitePawn && Square(S2).Rank() == Rank8) || (p == BlackPawn && Square(S2).Rank() == Rank1) {
 					for _, pt := range promoPieceTypes {
-						m := &Move{S1: Square(S1), S2: Square(S2), promo: pt}
-						addTags(m, pos)
-						// filter out moves that put king into check
-						if !m.HasTag(inCheck) {
-							moves = append(moves, m)
-							if first {
-								return moves
-							}
-						}
-					}
-				} else {
-					m := &Move{S1: Square(S1), S2: Square(S2)}
-					addTags(m, pos)
-					// filter out moves that put king into check
-					if !m.HasTag(inCheck) {
-						moves = append(moves, m)
-						if first {
-							return moves
+						if !f(Move{S1: Square(S1), S2: Square(S2), promo: pt}) {
+							return
 						}
 					}
+				} else if !f(Move{S1: Square(S1), S2: Square(S2)}) {
+					return
 				}
 			}
 		}
 	}
-	return moves
+}
+
+func (t moveTargets) forPieceType(pt PieceType) bitboard {
+	switch pt {
+	case King:
+		return t.king
+	case Pawn:
+		return t.pawns
+	}
+	return t.pieces
 }
 
 func addTags(m *Move, pos *Position) {
 	p := pos.board.Piece(m.S1)
-	if pos.board.isOccupied(m.S2) {
+	// a Chess960 king may castle onto the square of its own rook
+	if pos.board.isOccupied(m.S2) && m.castleSide() == 0 {
 		m.addTag(Capture)
 	} else if m.S2 == pos.enPassantSquare && p.Type() == Pawn {
 		m.addTag(EnPassant)
 	}
-	// determine if in check after move (makes move invalid)
+	if pos.variant != nil {
+		addVariantCheckTags(m, pos)
+		return
+	}
+	// determine if in check after move (makes move invalid) on a board
+	// kept on the stack since this runs for every generated move
+	b := *pos.board
+	pos.updateBoard(&b, m)
+	cp := Position{board: &b, turn: pos.turn}
+	if isKingAttacked(&cp) {
+		m.addTag(inCheck)
+	}
+	// determine if opponent in check after move
+	cp.turn = cp.turn.Other()
+	if isKingAttacked(&cp) {
+		m.addTag(Check)
+		if b.attackersOf(b.kingSquare(cp.turn), pos.turn, ^b.emptySqs).count() > 1 {
+			m.addTag(DoubleCheck)
+		}
+	}
+}
+
+// addVariantCheckTags adds the check tags to the move by the variant's
+// rules for check.
+func addVariantCheckTags(m *Move, pos *Position) {
 	cp := pos.copy()
-	cp.board.update(m)
+	pos.updateBoard(cp.board, m)
 	if isInCheck(cp) {
 		m.addTag(inCheck)
 	}
-	// determine if opponent in check after move
 	cp.turn = cp.turn.Other()
 	if isInCheck(cp) {
 		m.addTag(Check)
+		kingSq := cp.board.kingSquare(cp.turn)
+		if cp.board.attackersOf(kingSq, pos.turn, ^cp.board.emptySqs).count() > 1 {
+			m.addTag(DoubleCheck)
+		}
 	}
 }
 
+// inChecker is implemented by variants that change when a king is in
+// check.
+type inChecker interface {
+	isInCheck(pos *Position) bool
+}
+
 func isInCheck(pos *Position) bool {
+	if pos.variant != nil {
+		if c, ok := pos.variant.(inChecker); ok {
+			return c.isInCheck(pos)
+		}
+	}
+	return isKingAttacked(pos)
+}
+
+// isKingAttacked returns true if the king of the side to move is
+// attacked as in standard chess.
+func isKingAttacked(pos *Position) bool {
 	kingSq := pos.board.whiteKingSq
 	if pos.Turn() == Black {
 		kingSq = pos.board.blackKingSq
@@ -182,6 +354,46 @@ func squaresAreAttacked(pos *Position, sqs ...Square) bool {
 	return false
 }
 
+// attackersOf returns the pieces of the given color that attack the
+// square using the given occupancy for sliding pieces.
+func (b *Board) attackersOf(sq Square, c Color, occ bitboard) bitboard {
+	queens := b.bbForPiece(getPiece(Queen, c))
+	rooks := b.bbForPiece(getPiece(Rook, c))
+	bishops := b.bbForPiece(getPiece(Bishop, c))
+	knights := b.bbForPiece(getPiece(Knight, c))
+	pawns := b.bbForPiece(getPiece(Pawn, c))
+	king := b.bbForPiece(getPiece(King, c))
+	return (hvAttack(occ, sq) & (queens | rooks)) |
+		(diaAttack(occ, sq) & (queens | bishops)) |
+		(bbKnightMoves[sq] & knights) |
+		(pawnAttacks(c.Other(), bbForSquare(sq)) & pawns) |
+		(bbKingMoves[sq] & king)
+}
+
+// pawnAttacks returns the squares attacked by pawns of the given color.
+func pawnAttacks(c Color, pawns bitboard) bitboard {
+	if c == White {
+		return ((pawns & ^bbFileH & ^bbRank8) >> 9) | ((pawns & ^bbFileA & ^bbRank8) >> 7)
+	}
+	return ((pawns & ^bbFileH & ^bbRank1) << 7) | ((pawns & ^bbFileA & ^bbRank1) << 9)
+}
+
+// bbBetween returns the squares strictly between two squares sharing
+// a rank, file or diagonal.  An empty bitboard is returned otherwise.
+func bbBetween(s1, s2 Square) bitboard {
+	df, dr, ok := rayDirection(s1, s2)
+	if !ok {
+		return 0
+	}
+	var bb bitboard
+	f, r := int(s1.File())+df, int(s1.Rank())+dr
+	for getSquare(File(f), Rank(r)) != s2 {
+		bb |= bbForSquare(getSquare(File(f), Rank(r)))
+		f, r = f+df, r+dr
+	}
+	return bb
+}
+
 func bbForPossibleMoves(pos *Position, pt PieceType, sq Square) bitboard {
 	switch pt {
 	case King:
@@ -200,54 +412,79 @@ func bbForPossibleMoves(pos *Position, pt PieceType, sq Square) bitboard {
 	return bitboard(0)
 }
 
-// TODO can calc isInCheck twice
 func castleMoves(pos *Position) []*Move {
 	moves := []*Move{}
-	kingSide := pos.castleRights.CanCastle(pos.Turn(), KingSide)
-	queenSide := pos.castleRights.CanCastle(pos.Turn(), QueenSide)
-	// white king side
-	if pos.turn == White && kingSide &&
-		(^pos.board.emptySqs&(bbForSquare(F1)|bbForSquare(G1))) == 0 &&
-		!squaresAreAttacked(pos, F1, G1) &&
-		!pos.inCheck {
-		m := &Move{S1: E1, S2: G1}
-		m.addTag(KingSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
+	for _, m := range appendCastleMoves(pos, nil) {
+		m := m
+		moves = append(moves, &m)
 	}
-	// white queen side
-	if pos.turn == White && queenSide &&
-		(^pos.board.emptySqs&(bbForSquare(B1)|bbForSquare(C1)|bbForSquare(D1))) == 0 &&
-		!squaresAreAttacked(pos, C1, D1) &&
-		!pos.inCheck {
-		m := &Move{S1: E1, S2: C1}
-		m.addTag(QueenSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
-	}
-	// black king side
-	if pos.turn == Black && kingSide &&
-		(^pos.board.emptySqs&(bbForSquare(F8)|bbForSquare(G8))) == 0 &&
-		!squaresAreAttacked(pos, F8, G8) &&
-		!pos.inCheck {
-		m := &Move{S1: E8, S2: G8}
-		m.addTag(KingSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
+	return moves
+}
+
+// appendCastleMoves appends the legal castles of the side to move to
+// moves.  The squares between the king and rook and their destinations
+// must be empty apart from the two of them and the king may not pass
+// through an attacked square or end in check, which also covers the
+// castles of Chess960 where a rook may shield the king until it moves.
+func appendCastleMoves(pos *Position, moves []Move) []Move {
+	if pos.inCheck || pos.castleRights == "-" {
+		return moves
 	}
-	// black queen side
-	if pos.turn == Black && queenSide &&
-		(^pos.board.emptySqs&(bbForSquare(B8)|bbForSquare(C8)|bbForSquare(D8))) == 0 &&
-		!squaresAreAttacked(pos, C8, D8) &&
-		!pos.inCheck {
-		m := &Move{S1: E8, S2: C8}
-		m.addTag(QueenSideCastle)
-		addTags(m, pos)
+	c := pos.turn
+	for _, side := range castleSides {
+		if !pos.castleRights.CanCastle(c, side) {
+			continue
+		}
+		// the rights alone aren't trusted, the king and rook must be on their home squares
+		king, rook := pos.castleSquares(c, side)
+		if pos.board.Piece(king) != getPiece(King, c) || pos.board.Piece(rook) != getPiece(Rook, c) {
+			continue
+		}
+		kingTo, rookTo := castleDestinations(c, side)
+		path := bbSpan(king, kingTo) | bbSpan(rook, rookTo)
+		if ^pos.board.emptySqs&path&^(bbForSquare(king)|bbForSquare(rook)) != 0 {
+			continue
+		}
+		if kingPathAttacked(pos, king, kingTo) {
+			continue
+		}
+		m := Move{S1: king, S2: kingTo}
+		if side == KingSide {
+			m.addTag(KingSideCastle)
+		} else {
+			m.addTag(QueenSideCastle)
+		}
+		addTags(&m, pos)
+		if m.HasTag(inCheck) {
+			continue
+		}
 		moves = append(moves, m)
 	}
 	return moves
 }
 
+// bbSpan returns the squares from s1 to s2 on a rank including both.
+func bbSpan(s1, s2 Square) bitboard {
+	return bbBetween(s1, s2) | bbForSquare(s1) | bbForSquare(s2)
+}
+
+// kingPathAttacked returns true if a square the king passes or lands on
+// when castling is attacked.  The king's home square isn't checked as
+// castling out of check is ruled out before.
+func kingPathAttacked(pos *Position, king, kingTo Square) bool {
+	step := 1
+	if kingTo < king {
+		step = -1
+	}
+	for sq := king; sq != kingTo; {
+		sq += Square(step)
+		if squaresAreAttacked(pos, sq) {
+			return true
+		}
+	}
+	return false
+}
+
 func pawnMoves(pos *Position, sq Square) bitboard {
 	bb := bbForSquare(sq)
 	var bbEnPassant bitboard
@@ -269,13 +506,27 @@ func pawnMoves(pos *Position, sq Square) bitboard {
 }
 
 func diaAttack(occupied bitboard, sq Square) bitboard {
+	m := &bishopTable[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+func hvAttack(occupied bitboard, sq Square) bitboard {
+	m := &rookTable[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// diaRayAttack computes the diagonal attacks from the square without
+// the magic tables.  It's used to fill them.
+func diaRayAttack(occupied bitboard, sq Square) bitboard {
 	pos := bbForSquare(sq)
 	dMask := bbDiagonals[sq]
 	adMask := bbAntiDiagonals[sq]
 	return linearAttack(occupied, pos, dMask) | linearAttack(occupied, pos, adMask)
 }
 
-func hvAttack(occupied bitboard, sq Square) bitboard {
+// hvRayAttack computes the horizontal and vertical attacks from the
+// square without the magic tables.  It's used to fill them.
+func hvRayAttack(occupied bitboard, sq Square) bitboard {
 	pos := bbForSquare(sq)
 	rankMask := bbRanks[Square(sq).Rank()]
 	fileMask := bbFiles[Square(sq).File()]
@@ -337,4 +588,6 @@ func init() {
 	for sq := 0; sq < 64; sq++ {
 		bbSquares[sq] = bitboard(uint64(1) << (uint8(63) - uint8(sq)))
 	}
+	// the magic tables are filled using the square bitboards
+	initMagics()
 }
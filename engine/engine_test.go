@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+func unsafeFEN(s string) *chess.Position {
+	pos, err := chess.FromFEN(s)
+	if err != nil {
+		panic(err)
+	}
+	return pos
+}
+
+func TestEvaluate(t *testing.T) {
+	if score := Evaluate(chess.StartingPosition()); score != 0 {
+		t.Fatalf("expected the starting position to evaluate to 0 but got %d", score)
+	}
+	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNB1KBNR w KQkq - 0 1")
+	if score := Evaluate(pos); score >= -800 {
+		t.Fatalf("expected white to be down a queen but got %d", score)
+	}
+	// the evaluation is from the side to move and symmetric
+	if score, mirrored := Evaluate(pos), Evaluate(pos.Mirror()); score != mirrored {
+		t.Fatalf("expected the mirrored position to evaluate to %d but got %d", score, mirrored)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tables := []struct {
+		fen   string
+		depth int
+		move  string
+		mate  int
+	}{
+		// back rank mate
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 2, "a1a8", 1},
+		// the hanging queen is taken
+		{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", 2, "d2d5", 0},
+		// king and rook mate in two
+		{"k7/8/2K5/8/8/8/8/1R6 w - - 0 1", 4, "", 2},
+		// black's only move runs into mate
+		{"7k/8/6K1/8/8/8/8/R7 b - - 0 1", 3, "h8g8", -1},
+	}
+	for _, table := range tables {
+		result := Search(context.Background(), unsafeFEN(table.fen), Limits{Depth: table.depth})
+		if result.BestMove == nil || (table.move != "" && result.BestMove.String() != table.move) {
+			t.Fatalf("expected %s to have best move %s but got %v", table.fen, table.move, result.BestMove)
+		}
+		if table.mate != 0 && result.Mate != table.mate {
+			t.Fatalf("expected %s to be mate in %d but got %d", table.fen, table.mate, result.Mate)
+		}
+		if result.PV[0] != result.BestMove || result.Nodes == 0 {
+			t.Fatalf("expected the pv to start with the best move but got %v", result.PV)
+		}
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	pos := chess.StartingPosition()
+	start := time.Now()
+	result := Search(context.Background(), pos, Limits{MoveTime: 100 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > time.Second || result.BestMove == nil {
+		t.Fatalf("expected a move within the time budget but got %v after %s", result.BestMove, elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := Search(ctx, pos, Limits{}); result.BestMove == nil || result.Depth != 1 {
+		t.Fatalf("expected a canceled search to complete the first iteration but got depth %d", result.Depth)
+	}
+	stalemate := unsafeFEN("k7/8/1Q6/8/8/8/8/K7 b - - 0 1")
+	if result := Search(context.Background(), stalemate, Limits{Depth: 3}); result.BestMove != nil || result.Score != 0 {
+		t.Fatalf("expected no move in stalemate but got %v", result.BestMove)
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import chess "github.com/Yoshi-Exeler/chesslib"
+
+// pieceValues are the material values of the piece types in centipawns.
+var pieceValues = map[chess.PieceType]int{
+	chess.Pawn:   100,
+	chess.Knight: 320,
+	chess.Bishop: 330,
+	chess.Rook:   500,
+	chess.Queen:  900,
+}
+
+// The piece square tables give a bonus in centipawns for a piece
+// standing on a square.  They are written from white's side with rank 8
+// in the first row.
+var (
+	pawnTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	queenTable = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingMiddleGameTable = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+	kingEndGameTable = [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+)
+
+// endGameMaterial is the most non pawn material of both sides in
+// centipawns for which the king is evaluated with the end game table.
+const endGameMaterial = 1300
+
+// Evaluate returns the static evaluation of the position in centipawns
+// from the perspective of the side to move.  The evaluation is the
+// material balance with a bonus for each piece from the piece square
+// tables.
+func Evaluate(pos *chess.Position) int {
+	b := pos.Board()
+	score, material := 0, 0
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p := b.Piece(sq)
+		if p.Type() != chess.Pawn {
+			material += pieceValues[p.Type()]
+		}
+	}
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p := b.Piece(sq)
+		if p == chess.NoPiece {
+			continue
+		}
+		v := pieceValues[p.Type()] + squareBonus(p, sq, material <= endGameMaterial)
+		if p.Color() == chess.Black {
+			v = -v
+		}
+		score += v
+	}
+	if pos.Turn() == chess.Black {
+		return -score
+	}
+	return score
+}
+
+func squareBonus(p chess.Piece, sq chess.Square, endGame bool) int {
+	// the tables are indexed from a8 so white's squares are mirrored
+	row := int(sq.Rank())
+	if p.Color() == chess.White {
+		row = 7 - row
+	}
+	i := row*8 + int(sq.File())
+	switch p.Type() {
+	case chess.Pawn:
+		return pawnTable[i]
+	case chess.Knight:
+		return knightTable[i]
+	case chess.Bishop:
+		return bishopTable[i]
+	case chess.Rook:
+		return rookTable[i]
+	case chess.Queen:
+		return queenTable[i]
+	case chess.King:
+		if endGame {
+			return kingEndGameTable[i]
+		}
+		return kingMiddleGameTable[i]
+	}
+	return 0
+}
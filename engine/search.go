@@ -0,0 +1,241 @@
+// Package engine is a simple chess engine built on the move generator.
+// It evaluates positions by material and piece square tables and
+// searches them with an iterative deepening alpha-beta search.  It is
+// meant for casual play and testing rather than strength.
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+const (
+	// MaxDepth is the deepest search in plies.
+	MaxDepth = 64
+
+	infinity  = 1 << 30
+	mateScore = 1 << 20
+)
+
+// Limits bound a search.  The search stops at the first limit reached
+// or when its context is canceled.  Zero values are unlimited and a
+// search without limits runs to MaxDepth.
+type Limits struct {
+	Depth    int
+	MoveTime time.Duration
+}
+
+// Result is the outcome of a search.  Score is the evaluation in
+// centipawns from the perspective of the side to move.  Mate is the
+// number of moves until mate, negative if the side to move is getting
+// mated, and zero if no mate was found.  BestMove is nil if the
+// position has no valid moves.
+type Result struct {
+	BestMove *chess.Move
+	PV       []*chess.Move
+	Score    int
+	Mate     int
+	Depth    int
+	Nodes    int
+}
+
+// Search returns the best move found for the position.  The search
+// deepens one ply at a time and returns the result of the deepest
+// completed iteration.  The first iteration always completes so that a
+// move is returned even if the search is stopped right away.
+func Search(ctx context.Context, pos *chess.Position, limits Limits) Result {
+	s := &searcher{ctx: ctx}
+	if limits.MoveTime > 0 {
+		s.deadline = time.Now().Add(limits.MoveTime)
+	}
+	maxDepth := MaxDepth
+	if limits.Depth > 0 && limits.Depth < maxDepth {
+		maxDepth = limits.Depth
+	}
+	result := Result{}
+	for depth := 1; depth <= maxDepth; depth++ {
+		s.stoppable = depth > 1
+		if s.stoppable && s.expired() {
+			break
+		}
+		score, pv := s.negamax(pos, depth, -infinity, infinity, 0, result.PV)
+		if s.stopped {
+			break
+		}
+		result.PV = pv
+		result.Score = score
+		result.Depth = depth
+		if len(pv) > 0 {
+			result.BestMove = pv[0]
+		}
+		// a shorter mate can't be found by searching deeper
+		if score > mateScore-MaxDepth || score < -mateScore+MaxDepth || len(pv) == 0 {
+			break
+		}
+	}
+	result.Nodes = s.nodes
+	switch {
+	case result.Score > mateScore-MaxDepth:
+		result.Mate = (mateScore - result.Score + 1) / 2
+	case result.Score < -mateScore+MaxDepth:
+		result.Mate = -(mateScore + result.Score) / 2
+	}
+	return result
+}
+
+type searcher struct {
+	ctx       context.Context
+	deadline  time.Time
+	nodes     int
+	stoppable bool
+	stopped   bool
+	// path holds the hashes of the positions searched from the root
+	// to detect repetitions
+	path []uint64
+}
+
+// stop returns true if the search has to stop.  The context and the
+// deadline are only checked every few thousand nodes.
+func (s *searcher) stop() bool {
+	s.nodes++
+	if s.stopped || !s.stoppable || s.nodes%2048 != 0 {
+		return s.stopped
+	}
+	s.stopped = s.expired()
+	return s.stopped
+}
+
+// expired returns true if the context is canceled or the deadline
+// has passed.
+func (s *searcher) expired() bool {
+	return s.ctx.Err() != nil || (!s.deadline.IsZero() && time.Now().After(s.deadline))
+}
+
+// negamax returns the score of the position and its principal
+// variation.  The moves of the previous iteration's principal variation
+// are searched first.
+func (s *searcher) negamax(pos *chess.Position, depth, alpha, beta, ply int, prevPV []*chess.Move) (int, []*chess.Move) {
+	if s.stop() {
+		return 0, nil
+	}
+	if ply > 0 && s.isDraw(pos) {
+		return 0, nil
+	}
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		if len(pos.CheckingPieces()) > 0 {
+			return -mateScore + ply, nil
+		}
+		return 0, nil
+	}
+	if depth <= 0 || ply >= MaxDepth {
+		return s.quiesce(pos, alpha, beta, ply), nil
+	}
+	var pvMove *chess.Move
+	if len(prevPV) > 0 {
+		pvMove = prevPV[0]
+	}
+	orderMoves(pos, moves, pvMove)
+	var pv []*chess.Move
+	for _, m := range moves {
+		var childPV []*chess.Move
+		if pvMove != nil && m.String() == pvMove.String() {
+			childPV = prevPV[1:]
+		}
+		s.path = append(s.path, pos.Hash())
+		score, line := s.negamax(pos.Update(m), depth-1, -beta, -alpha, ply+1, childPV)
+		s.path = s.path[:len(s.path)-1]
+		if s.stopped {
+			return 0, nil
+		}
+		if score = -score; score > alpha {
+			alpha = score
+			pv = append([]*chess.Move{m}, line...)
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+	return alpha, pv
+}
+
+// quiesce searches captures and promotions until the position is quiet
+// so that the static evaluation isn't taken in the middle of an
+// exchange.  All moves are searched when in check.
+func (s *searcher) quiesce(pos *chess.Position, alpha, beta, ply int) int {
+	if s.stop() {
+		return 0
+	}
+	var moves []*chess.Move
+	if len(pos.CheckingPieces()) > 0 {
+		moves = pos.ValidMoves()
+		if len(moves) == 0 {
+			return -mateScore + ply
+		}
+	} else {
+		standPat := Evaluate(pos)
+		if standPat >= beta || ply >= MaxDepth {
+			return standPat
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		moves = pos.CaptureMoves()
+	}
+	orderMoves(pos, moves, nil)
+	for _, m := range moves {
+		score := -s.quiesce(pos.Update(m), -beta, -alpha, ply+1)
+		if s.stopped {
+			return 0
+		}
+		if score > alpha {
+			alpha = score
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+	return alpha
+}
+
+// isDraw returns true if the position is drawn by the fifty move rule,
+// insufficient material or repeating a position of the search.
+func (s *searcher) isDraw(pos *chess.Position) bool {
+	if pos.HalfMoveClock() >= 100 || pos.InsufficientMaterial() {
+		return true
+	}
+	h := pos.Hash()
+	for i := len(s.path) - 2; i >= 0 && i >= len(s.path)-pos.HalfMoveClock(); i -= 2 {
+		if s.path[i] == h {
+			return true
+		}
+	}
+	return false
+}
+
+// orderMoves sorts the moves so that the principal variation move comes
+// first followed by captures of the most valuable victim by the least
+// valuable attacker and promotions.
+func orderMoves(pos *chess.Position, moves []*chess.Move, pvMove *chess.Move) {
+	scores := make(map[*chess.Move]int, len(moves))
+	b := pos.Board()
+	for _, m := range moves {
+		score := 0
+		switch {
+		case pvMove != nil && m.String() == pvMove.String():
+			score = infinity
+		case m.HasTag(chess.EnPassant):
+			score = 10 * pieceValues[chess.Pawn]
+		case m.HasTag(chess.Capture):
+			score = 10*pieceValues[b.Piece(m.S2).Type()] - pieceValues[b.Piece(m.S1).Type()]
+		}
+		score += pieceValues[m.Promo()]
+		scores[m] = score
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+}
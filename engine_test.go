@@ -0,0 +1,177 @@
+package chess
+
+import "testing"
+
+var (
+	moveGenFENs = []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+	}
+)
+
+func TestCaptureMoves(t *testing.T) {
+	for _, fen := range moveGenFENs {
+		expected := map[string]bool{}
+		for _, m := range unsafeFEN(fen).ValidMoves() {
+			if m.HasTag(Capture) || m.HasTag(EnPassant) || m.Promo() != NoPieceType {
+				expected[m.String()] = true
+			}
+		}
+		captures := unsafeFEN(fen).CaptureMoves()
+		if len(captures) != len(expected) {
+			t.Fatalf("%s expected %d capture moves but got %d", fen, len(expected), len(captures))
+		}
+		for _, m := range captures {
+			if !expected[m.String()] {
+				t.Fatalf("%s unexpected capture move %s", fen, m)
+			}
+		}
+	}
+}
+
+func TestQuietMoves(t *testing.T) {
+	for _, fen := range moveGenFENs {
+		expected := map[string]bool{}
+		for _, m := range unsafeFEN(fen).ValidMoves() {
+			expected[m.String()] = true
+		}
+		pos := unsafeFEN(fen)
+		moves := append(pos.CaptureMoves(), pos.QuietMoves()...)
+		if len(moves) != len(expected) {
+			t.Fatalf("%s expected %d capture and quiet moves but got %d", fen, len(expected), len(moves))
+		}
+		for _, m := range moves {
+			if !expected[m.String()] {
+				t.Fatalf("%s unexpected move %s", fen, m)
+			}
+			delete(expected, m.String())
+		}
+	}
+}
+
+func TestPseudoLegalMoves(t *testing.T) {
+	for _, fen := range moveGenFENs {
+		pos := unsafeFEN(fen)
+		legal := []*Move{}
+		for _, m := range pos.PseudoLegalMoves() {
+			if pos.IsLegal(m) {
+				legal = append(legal, m)
+			}
+		}
+		valid := unsafeFEN(fen).ValidMoves()
+		if len(legal) != len(valid) {
+			t.Fatalf("%s expected %d legal pseudo legal moves but got %d", fen, len(valid), len(legal))
+		}
+		for _, m := range legal {
+			v := moveSlice(valid).find(m)
+			if v == nil || v.tags != m.tags {
+				t.Fatalf("%s expected pseudo legal move %s to match a valid move", fen, m)
+			}
+		}
+	}
+	// the pinned knight's moves are pseudo legal
+	pos := unsafeFEN("4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1")
+	if n := len(pos.PseudoLegalMoves()); n != 10 {
+		t.Fatalf("expected 10 pseudo legal moves but got %d", n)
+	}
+}
+
+func TestEvasionMoves(t *testing.T) {
+	tables := []struct {
+		fen       string
+		kingMoves bool
+	}{
+		// single check by a rook that can be blocked or captured
+		{"4k3/8/8/8/4r3/8/3N1B2/R3K3 w Q - 0 1", false},
+		// single check by a knight
+		{"4k3/8/8/8/8/3n4/1B6/4K3 w - - 0 1", false},
+		// checking pawn that can be captured en passant
+		{"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1", false},
+		// double check by rook and bishop
+		{"4k3/8/8/8/1b6/8/7R/r3K3 w - - 0 1", true},
+	}
+	for _, table := range tables {
+		expected := map[string]bool{}
+		for _, m := range unsafeFEN(table.fen).ValidMoves() {
+			expected[m.String()] = true
+		}
+		evasions := unsafeFEN(table.fen).EvasionMoves()
+		if len(evasions) == 0 || len(evasions) != len(expected) {
+			t.Fatalf("%s expected %d evasions but got %d", table.fen, len(expected), len(evasions))
+		}
+		for _, m := range evasions {
+			if !expected[m.String()] {
+				t.Fatalf("%s unexpected evasion %s", table.fen, m)
+			}
+			if table.kingMoves && m.S1 != E1 {
+				t.Fatalf("%s expected only king moves in double check but got %s", table.fen, m)
+			}
+		}
+	}
+	if moves := StartingPosition().EvasionMoves(); len(moves) != 0 {
+		t.Fatalf("expected no evasions when not in check but got %d", len(moves))
+	}
+}
+
+func TestPromotionMoves(t *testing.T) {
+	// the b7 pawn can push or capture on a8 or c8 and the blocked g7 pawn can capture on h8
+	pos := unsafeFEN("r1n3nr/1P4P1/8/8/8/8/8/k3K3 w - - 0 1")
+	promos := pos.PromotionMoves()
+	expected := map[Square]int{A8: 4, B8: 4, C8: 4, H8: 4}
+	if len(promos) != len(expected) {
+		t.Fatalf("expected %d promotion squares but got %d", len(expected), len(promos))
+	}
+	for sq, n := range expected {
+		if len(promos[sq]) != n {
+			t.Fatalf("expected %d promotions to %s but got %d", n, sq, len(promos[sq]))
+		}
+		for _, m := range promos[sq] {
+			if m.S2 != sq || m.Promo() == NoPieceType {
+				t.Fatalf("unexpected move %s grouped under %s", m, sq)
+			}
+		}
+	}
+}
+
+func TestValidMovesInto(t *testing.T) {
+	buf := make([]Move, 0, 256)
+	for _, fen := range moveGenFENs {
+		pos := unsafeFEN(fen)
+		buf = pos.ValidMovesInto(buf[:0])
+		valid := unsafeFEN(fen).ValidMoves()
+		if len(buf) != len(valid) {
+			t.Fatalf("%s expected %d moves but got %d", fen, len(valid), len(buf))
+		}
+		for i := range buf {
+			if buf[i] != *valid[i] {
+				t.Fatalf("%s expected move %s but got %s", fen, valid[i], &buf[i])
+			}
+		}
+		if allocs := testing.AllocsPerRun(10, func() { buf = pos.ValidMovesInto(buf[:0]) }); allocs != 0 {
+			t.Fatalf("%s expected no allocations but got %v", fen, allocs)
+		}
+	}
+}
+
+func BenchmarkValidMovesInto(b *testing.B) {
+	pos := unsafeFEN("r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP2BPPP/R2QKB1R w KQ - 0 8")
+	buf := make([]Move, 0, 256)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		buf = pos.ValidMovesInto(buf[:0])
+	}
+}
+
+func BenchmarkValidMoves(b *testing.B) {
+	pos := unsafeFEN("r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP2BPPP/R2QKB1R w KQ - 0 8")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pos.validMoves = nil
+		pos.ValidMoves()
+	}
+}
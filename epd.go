@@ -0,0 +1,212 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EPD is a position in Extended Position Description format along
+// with its operations.  EPD is used by engine test suites such as WAC
+// and STS where the operations give the expected best move and the
+// position's id.
+type EPD struct {
+	Position *Position
+	// Operations are kept in order including the opcodes that
+	// aren't interpreted by the EPD's methods.
+	Operations []EPDOperation
+}
+
+// EPDOperation is an opcode with its operands.  Quoted string operands
+// are stored without the quotes.
+type EPDOperation struct {
+	Opcode   string
+	Operands []string
+}
+
+// DecodeEPD decodes a line in EPD format.  The position's half move
+// clock and move count are taken from the hmvc and fmvn operations if
+// present.  An error is returned if the position is invalid, a string
+// operand isn't terminated, the moves of the bm and am operations can't
+// be decoded or the ce, dm, hmvc and fmvn operands aren't integers.
+func DecodeEPD(s string) (*EPD, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("chess: epd %q must have at least four fields", s)
+	}
+	// the operations are taken from the line itself to keep the
+	// spacing of string operands
+	rest := s
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+	ops, err := parseEPDOperations(rest)
+	if err != nil {
+		return nil, err
+	}
+	e := &EPD{Operations: ops}
+	clocks := []string{"0", "1"}
+	for i, opcode := range []string{"hmvc", "fmvn"} {
+		if operands, ok := e.operands(opcode); ok {
+			if len(operands) != 1 {
+				return nil, fmt.Errorf("chess: epd %s must have a single operand", opcode)
+			}
+			clocks[i] = operands[0]
+		}
+	}
+	pos, err := FromFEN(strings.Join(append(fields[:4], clocks...), " "))
+	if err != nil {
+		return nil, err
+	}
+	e.Position = pos
+	for _, opcode := range []string{"bm", "am"} {
+		operands, _ := e.operands(opcode)
+		for _, operand := range operands {
+			if _, err := (RelaxedAlgebraicNotation{}).Decode(pos, operand); err != nil {
+				return nil, fmt.Errorf("chess: epd %s invalid move %s", opcode, operand)
+			}
+		}
+	}
+	for _, opcode := range []string{"ce", "dm"} {
+		if _, _, err := e.intOperand(opcode); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// EncodeEPD encodes the EPD into a single line.  The position's half
+// move clock and move count are only written if they're given as
+// operations.
+func EncodeEPD(e *EPD) string {
+	fields := strings.Fields(e.Position.String())
+	s := strings.Join(fields[:4], " ")
+	for _, op := range e.Operations {
+		s += " " + op.Opcode
+		for _, operand := range op.Operands {
+			if isEPDStringOpcode(op.Opcode) || strings.ContainsAny(operand, " ;") {
+				operand = `"` + operand + `"`
+			}
+			s += " " + operand
+		}
+		s += ";"
+	}
+	return s
+}
+
+// ID returns the operand of the id operation or an empty string.
+func (e *EPD) ID() string {
+	operands, _ := e.operands("id")
+	return strings.Join(operands, " ")
+}
+
+// BestMoves returns the moves of the bm operation.
+func (e *EPD) BestMoves() []*Move {
+	return e.moves("bm")
+}
+
+// AvoidMoves returns the moves of the am operation.
+func (e *EPD) AvoidMoves() []*Move {
+	return e.moves("am")
+}
+
+// CentipawnEvaluation returns the operand of the ce operation, the
+// evaluation from the perspective of the side to move.  False is returned
+// if there is no ce operation.
+func (e *EPD) CentipawnEvaluation() (int, bool) {
+	ce, ok, _ := e.intOperand("ce")
+	return ce, ok
+}
+
+// DirectMate returns the operand of the dm operation, the number of
+// moves until the side to move mates.  False is returned if there is
+// no dm operation.
+func (e *EPD) DirectMate() (int, bool) {
+	dm, ok, _ := e.intOperand("dm")
+	return dm, ok
+}
+
+func (e *EPD) operands(opcode string) ([]string, bool) {
+	for _, op := range e.Operations {
+		if op.Opcode == opcode {
+			return op.Operands, true
+		}
+	}
+	return nil, false
+}
+
+func (e *EPD) moves(opcode string) []*Move {
+	operands, _ := e.operands(opcode)
+	moves := []*Move{}
+	for _, operand := range operands {
+		if m, err := (RelaxedAlgebraicNotation{}).Decode(e.Position, operand); err == nil {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func (e *EPD) intOperand(opcode string) (int, bool, error) {
+	operands, ok := e.operands(opcode)
+	if !ok {
+		return 0, false, nil
+	}
+	if len(operands) != 1 {
+		return 0, false, fmt.Errorf("chess: epd %s must have a single operand", opcode)
+	}
+	n, err := strconv.Atoi(operands[0])
+	if err != nil {
+		return 0, false, fmt.Errorf("chess: epd %s invalid integer %s", opcode, operands[0])
+	}
+	return n, true, nil
+}
+
+// parseEPDOperations splits the operations on semicolons outside of
+// quoted string operands.
+func parseEPDOperations(s string) ([]EPDOperation, error) {
+	ops := []EPDOperation{}
+	tokens := []string{}
+	token, quoted, inQuote := "", false, false
+	endToken := func() {
+		if token != "" || quoted {
+			tokens = append(tokens, token)
+		}
+		token, quoted = "", false
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			endToken()
+			inQuote = !inQuote
+			quoted = inQuote
+		case inQuote:
+			token += string(r)
+		case r == ' ' || r == '\t':
+			endToken()
+		case r == ';':
+			endToken()
+			if len(tokens) > 0 {
+				ops = append(ops, EPDOperation{Opcode: tokens[0], Operands: tokens[1:]})
+			}
+			tokens = []string{}
+		default:
+			token += string(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("chess: epd unterminated string operand %q", token)
+	}
+	endToken()
+	// the last operation may omit its semicolon
+	if len(tokens) > 0 {
+		ops = append(ops, EPDOperation{Opcode: tokens[0], Operands: tokens[1:]})
+	}
+	return ops, nil
+}
+
+// isEPDStringOpcode returns true for opcodes with string operands such
+// as id and the comments c0 through c9.
+func isEPDStringOpcode(opcode string) bool {
+	return opcode == "id" || (len(opcode) == 2 && opcode[0] == 'c' && opcode[1] >= '0' && opcode[1] <= '9')
+}
@@ -0,0 +1,67 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeEPD(t *testing.T) {
+	s := `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`
+	e, err := DecodeEPD(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID() != "WAC.001" {
+		t.Fatalf("expected id WAC.001 but got %s", e.ID())
+	}
+	if bm := e.BestMoves(); len(bm) != 1 || bm[0].String() != "g3g6" {
+		t.Fatalf("expected best move g3g6 but got %v", bm)
+	}
+	if _, ok := e.CentipawnEvaluation(); ok {
+		t.Fatal("expected no centipawn evaluation")
+	}
+	if EncodeEPD(e) != s {
+		t.Fatalf("expected epd to encode as %s but got %s", s, EncodeEPD(e))
+	}
+
+	s = `6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra7 Kf2; bm Ra8#; ce 32767; dm 1; c0 "back rank;  mate"; hmvc 3; fmvn 40;`
+	e, err = DecodeEPD(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if am := e.AvoidMoves(); len(am) != 2 || am[0].String() != "a1a7" || am[1].String() != "g1f2" {
+		t.Fatalf("expected avoid moves a1a7 and g1f2 but got %v", am)
+	}
+	if ce, ok := e.CentipawnEvaluation(); !ok || ce != 32767 {
+		t.Fatalf("expected centipawn evaluation 32767 but got %d", ce)
+	}
+	if dm, ok := e.DirectMate(); !ok || dm != 1 {
+		t.Fatalf("expected direct mate 1 but got %d", dm)
+	}
+	expected := EPDOperation{Opcode: "c0", Operands: []string{"back rank;  mate"}}
+	if op := e.Operations[4]; !reflect.DeepEqual(op, expected) {
+		t.Fatalf("expected operation %v but got %v", expected, op)
+	}
+	if e.Position.HalfMoveClock() != 3 || e.Position.moveCount != 40 {
+		t.Fatalf("expected the clocks from hmvc and fmvn but got %s", e.Position)
+	}
+	if EncodeEPD(e) != s {
+		t.Fatalf("expected epd to encode as %s but got %s", s, EncodeEPD(e))
+	}
+}
+
+func TestDecodeEPDErrors(t *testing.T) {
+	for _, s := range []string{
+		"8/8/8/8/8/8/8/8 w -",
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra9;",
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - ce high;",
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - dm 1 2;",
+		`6k1/5ppp/8/8/8/8/8/R5K1 w - - id "unterminated;`,
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - fmvn 0;",
+		"6k1/5ppp/9/8/8/8/8/R5K1 w - - id test;",
+	} {
+		if _, err := DecodeEPD(s); err == nil {
+			t.Fatalf("expected %s to fail decoding", s)
+		}
+	}
+}
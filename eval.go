@@ -0,0 +1,316 @@
+package chess
+
+// RooksOnOpenFiles returns the number of rooks of the given color
+// that stand on an open file.  A file is open when it contains no
+// pawns of either color.
+func (pos *Position) RooksOnOpenFiles(c Color) int {
+	rooks := pos.board.bbForPiece(getPiece(Rook, c))
+	pawns := pos.board.bbWhitePawn | pos.board.bbBlackPawn
+	count := 0
+	for _, file := range bbFiles {
+		if pawns&file == 0 {
+			count += (rooks & file).count()
+		}
+	}
+	return count
+}
+
+// RooksOnSemiOpenFiles returns the number of rooks of the given color
+// that stand on a semi-open file.  A file is semi-open when it contains
+// no pawns of the rook's color but at least one pawn of the opponent.
+func (pos *Position) RooksOnSemiOpenFiles(c Color) int {
+	rooks := pos.board.bbForPiece(getPiece(Rook, c))
+	own := pos.board.bbForPiece(getPiece(Pawn, c))
+	enemy := pos.board.bbForPiece(getPiece(Pawn, c.Other()))
+	count := 0
+	for _, file := range bbFiles {
+		if own&file == 0 && enemy&file != 0 {
+			count += (rooks & file).count()
+		}
+	}
+	return count
+}
+
+// RooksOnSeventh returns the number of rooks of the given color on the
+// seventh rank relative to that color (the seventh rank for white and
+// the second rank for black).
+func (pos *Position) RooksOnSeventh(c Color) int {
+	rooks := pos.board.bbForPiece(getPiece(Rook, c))
+	seventh := bbRank7
+	if c == Black {
+		seventh = bbRank2
+	}
+	return (rooks & seventh).count()
+}
+
+// OppositeColoredBishops returns true if each side has exactly one
+// bishop and the two bishops stand on squares of opposite colors.
+func (pos *Position) OppositeColoredBishops() bool {
+	white := pos.board.bbWhiteBishop.squares()
+	black := pos.board.bbBlackBishop.squares()
+	if len(white) != 1 || len(black) != 1 {
+		return false
+	}
+	return white[0].color() != black[0].color()
+}
+
+// KnightOutposts returns the squares of the knights of the given color
+// that stand on an outpost.  An outpost is a square on the fifth, sixth
+// or seventh rank relative to the knight's color that is defended by a
+// friendly pawn and can never be attacked by an enemy pawn because no
+// enemy pawn remains ahead of it on an adjacent file.
+func (pos *Position) KnightOutposts(c Color) []Square {
+	defended := pawnAttacks(c, pos.board.bbForPiece(getPiece(Pawn, c)))
+	enemyPawns := pos.board.bbForPiece(getPiece(Pawn, c.Other()))
+	outposts := []Square{}
+	for _, sq := range pos.board.bbForPiece(getPiece(Knight, c)).squares() {
+		rank := sq.Rank()
+		if c == Black {
+			rank = Rank8 - rank
+		}
+		if rank < Rank5 || rank == Rank8 || defended&bbForSquare(sq) == 0 {
+			continue
+		}
+		if enemyPawns&pawnSpan(c, sq) == 0 {
+			outposts = append(outposts, sq)
+		}
+	}
+	return outposts
+}
+
+// pawnSpan returns the squares on the files adjacent to sq that are
+// ahead of sq from the perspective of the given color.
+func pawnSpan(c Color, sq Square) bitboard {
+	var bb bitboard
+	for _, df := range []int{-1, 1} {
+		s, ok := sq.Offset(df, 0)
+		if !ok {
+			continue
+		}
+		for {
+			if c == White {
+				s, ok = s.Up()
+			} else {
+				s, ok = s.Down()
+			}
+			if !ok {
+				break
+			}
+			bb |= bbForSquare(s)
+		}
+	}
+	return bb
+}
+
+// attack unit weights per king zone square attacked by each piece type
+var kingAttackWeights = map[PieceType]int{
+	Knight: 2,
+	Bishop: 2,
+	Rook:   3,
+	Queen:  5,
+}
+
+// kingSafetyTable maps attack units to a centipawn penalty.
+var kingSafetyTable = [100]int{
+	0, 0, 1, 2, 3, 5, 7, 9, 12, 15,
+	18, 22, 26, 30, 35, 39, 44, 50, 56, 62,
+	68, 75, 82, 85, 89, 97, 105, 113, 122, 131,
+	140, 150, 169, 180, 191, 202, 213, 225, 237, 248,
+	260, 272, 283, 295, 307, 319, 330, 342, 354, 366,
+	377, 389, 401, 412, 424, 436, 448, 459, 471, 483,
+	494, 500, 500, 500, 500, 500, 500, 500, 500, 500,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500,
+}
+
+// KingAttackUnits returns the attack units accumulated against the king
+// of the given color.  The king zone is the king's square and the squares
+// adjacent to it.  Each enemy knight or bishop adds 2 units, each rook 3
+// and each queen 5 for every king zone square it attacks.
+func (pos *Position) KingAttackUnits(c Color) int {
+	kingSq := pos.board.kingSquare(c)
+	if kingSq == NoSquare {
+		return 0
+	}
+	zone := bbKingMoves[kingSq] | bbForSquare(kingSq)
+	occ := ^pos.board.emptySqs
+	units := 0
+	for pt, weight := range kingAttackWeights {
+		for _, sq := range pos.board.bbForPiece(getPiece(pt, c.Other())).squares() {
+			var attacks bitboard
+			switch pt {
+			case Knight:
+				attacks = bbKnightMoves[sq]
+			case Bishop:
+				attacks = diaAttack(occ, sq)
+			case Rook:
+				attacks = hvAttack(occ, sq)
+			case Queen:
+				attacks = diaAttack(occ, sq) | hvAttack(occ, sq)
+			}
+			units += weight * (attacks & zone).count()
+		}
+	}
+	return units
+}
+
+// KingSafetyPenalty returns the centipawn penalty for the king of the
+// given color by mapping its KingAttackUnits through a safety table.
+func (pos *Position) KingSafetyPenalty(c Color) int {
+	units := pos.KingAttackUnits(c)
+	if units >= len(kingSafetyTable) {
+		units = len(kingSafetyTable) - 1
+	}
+	return kingSafetyTable[units]
+}
+
+// practicalDrawMargin is the number of half moves before the fifty
+// move rule within which drawish material is treated as a draw.
+const practicalDrawMargin = 20
+
+// IsPracticalDraw returns true if the position is almost certainly a
+// draw.  This is the case when there is insufficient material for
+// checkmate, when two knights face a lone king (mate exists but can't
+// be forced), or when the material is drawish and the half move clock
+// is within ten moves of the fifty move rule.  Drawish material is
+// an opposite colored bishop ending without other pieces (pawns are
+// allowed) or at most one minor piece per side without any pawns.
+func (pos *Position) IsPracticalDraw() bool {
+	if !pos.board.hasSufficientMaterial() || pos.board.isKnightsVersusKing() {
+		return true
+	}
+	if pos.halfMoveClock < 100-practicalDrawMargin {
+		return false
+	}
+	b := pos.board
+	if (b.bbWhiteQueen | b.bbBlackQueen | b.bbWhiteRook | b.bbBlackRook) != 0 {
+		return false
+	}
+	if (b.bbWhiteKnight|b.bbBlackKnight) == 0 && pos.OppositeColoredBishops() {
+		return true
+	}
+	whiteMinors := (b.bbWhiteBishop | b.bbWhiteKnight).count()
+	blackMinors := (b.bbBlackBishop | b.bbBlackKnight).count()
+	return (b.bbWhitePawn|b.bbBlackPawn) == 0 && whiteMinors <= 1 && blackMinors <= 1
+}
+
+// HasWinningCapture returns true if the side to move has a capture
+// that wins material according to static exchange evaluation.
+func (pos *Position) HasWinningCapture() bool {
+	for _, m := range pos.CaptureMoves() {
+		if (m.HasTag(Capture) || m.HasTag(EnPassant)) && pos.SEE(m) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// bishopPairBonus is the centipawn bonus for owning two or more bishops.
+const bishopPairBonus = 50
+
+// MaterialBalance returns the material difference in centipawns using
+// the standard piece values (P=100, N=320, B=330, R=500, Q=900).  A
+// positive value favors white and a negative value favors black.
+func (pos *Position) MaterialBalance() int {
+	return pos.material(White) - pos.material(Black)
+}
+
+// MaterialBalanceWithBishopPair returns the material balance like
+// MaterialBalance with an additional 50 centipawns for each side
+// owning the bishop pair.
+func (pos *Position) MaterialBalanceWithBishopPair() int {
+	balance := pos.MaterialBalance()
+	if pos.board.bbWhiteBishop.count() >= 2 {
+		balance += bishopPairBonus
+	}
+	if pos.board.bbBlackBishop.count() >= 2 {
+		balance -= bishopPairBonus
+	}
+	return balance
+}
+
+func (pos *Position) material(c Color) int {
+	total := 0
+	for _, pt := range []PieceType{Queen, Rook, Bishop, Knight, Pawn} {
+		total += pos.board.bbForPiece(getPiece(pt, c)).count() * seeValues[pt]
+	}
+	return total
+}
+
+// KingDistance returns the Chebyshev distance between the white and
+// black kings, which is the number of king moves needed to go from one
+// to the other.  Zero is returned if either king is missing.
+func (pos *Position) KingDistance() int {
+	white := pos.board.kingSquare(White)
+	black := pos.board.kingSquare(Black)
+	if white == NoSquare || black == NoSquare {
+		return 0
+	}
+	df := abs(int(white.File()) - int(black.File()))
+	dr := abs(int(white.Rank()) - int(black.Rank()))
+	return maxInt(df, dr)
+}
+
+// MopUpEval returns a bonus in centipawns for converting an endgame in
+// which one side is ahead in material, such as KQvK or KRvK, without a
+// tablebase.  The bonus rewards driving the weaker king away from the
+// center and bringing the kings close together:
+//
+//	47*cmd + 16*(14-md)
+//
+// cmd is the Manhattan distance of the weaker king to the nearest of
+// the four center squares (0 to 6) and md is the Manhattan distance
+// between the kings (0 to 14).  The result is positive if white has more
+// material, negative if black has more material and zero if material
+// is equal or a king is missing.
+func MopUpEval(pos *Position) int {
+	balance := pos.MaterialBalance()
+	white := pos.board.kingSquare(White)
+	black := pos.board.kingSquare(Black)
+	if balance == 0 || white == NoSquare || black == NoSquare {
+		return 0
+	}
+	weak, sign := black, 1
+	if balance < 0 {
+		weak, sign = white, -1
+	}
+	cmd := centerDistance(int(weak.File())) + centerDistance(int(weak.Rank()))
+	md := abs(int(white.File())-int(black.File())) + abs(int(white.Rank())-int(black.Rank()))
+	return sign * (47*cmd + 16*(14-md))
+}
+
+// centerDistance returns the distance of the file or rank index to the
+// nearer of the two central files or ranks.
+func centerDistance(i int) int {
+	return maxInt(3-i, i-4)
+}
+
+// phaseWeights are the contributions of the non-pawn pieces to the game phase.
+var phaseWeights = map[PieceType]int{
+	Knight: 1,
+	Bishop: 1,
+	Rook:   2,
+	Queen:  4,
+}
+
+// totalPhase is the phase weight of the non-pawn material in the starting position.
+const totalPhase = 24
+
+// Phase returns the game phase in the range 0 to 256 for interpolating
+// between opening and endgame evaluations.  It is derived from the
+// remaining non-pawn material where knights and bishops weigh 1, rooks
+// 2 and queens 4.  The full starting material (or more after promotions)
+// is 256 and a position with only kings and pawns is 0.
+func (pos *Position) Phase() int {
+	phase := 0
+	for pt, weight := range phaseWeights {
+		for _, c := range []Color{White, Black} {
+			phase += pos.board.bbForPiece(getPiece(pt, c)).count() * weight
+		}
+	}
+	if phase > totalPhase {
+		phase = totalPhase
+	}
+	return (phase*256 + totalPhase/2) / totalPhase
+}
@@ -0,0 +1,241 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRookFiles(t *testing.T) {
+	tables := []struct {
+		fen      string
+		color    Color
+		open     int
+		semiOpen int
+		seventh  int
+	}{
+		{"6k1/pp3ppp/8/8/8/8/PP3PPP/3R2K1 w - - 0 1", White, 1, 0, 0},
+		{"6k1/pp3ppp/8/8/8/8/P4PPP/1R4K1 w - - 0 1", White, 0, 1, 0},
+		{"6k1/1R3ppp/8/8/8/8/5PPP/6K1 w - - 0 1", White, 1, 0, 1},
+		{"6k1/5ppp/8/8/8/8/r4PPP/6K1 b - - 0 1", Black, 1, 0, 1},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", White, 0, 0, 0},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if n := pos.RooksOnOpenFiles(table.color); n != table.open {
+			t.Fatalf("%s expected %d rooks on open files but got %d", table.fen, table.open, n)
+		}
+		if n := pos.RooksOnSemiOpenFiles(table.color); n != table.semiOpen {
+			t.Fatalf("%s expected %d rooks on semi-open files but got %d", table.fen, table.semiOpen, n)
+		}
+		if n := pos.RooksOnSeventh(table.color); n != table.seventh {
+			t.Fatalf("%s expected %d rooks on the seventh but got %d", table.fen, table.seventh, n)
+		}
+	}
+}
+
+func TestOppositeColoredBishops(t *testing.T) {
+	tables := []struct {
+		fen      string
+		opposite bool
+	}{
+		{"4k3/4b3/8/8/8/8/2B5/4K3 w - - 0 1", true},
+		{"4k3/5b2/8/8/8/8/2B5/4K3 w - - 0 1", false},
+		{"4k3/4b3/8/8/8/8/2BB4/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/2B5/4K3 w - - 0 1", false},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if pos.OppositeColoredBishops() != table.opposite {
+			t.Fatalf("%s expected opposite colored bishops to be %t", table.fen, table.opposite)
+		}
+	}
+}
+
+func TestKingAttackUnits(t *testing.T) {
+	fens := []string{
+		"6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1",
+		"6k1/5ppp/8/6N1/8/8/5PPP/6K1 w - - 0 1",
+		"6k1/3R1ppp/8/6N1/8/8/5PPP/6K1 w - - 0 1",
+		"6k1/3R1ppp/8/6NQ/8/8/5PPP/6K1 w - - 0 1",
+	}
+	last := -1
+	for _, fen := range fens {
+		units := unsafeFEN(fen).KingAttackUnits(Black)
+		if units <= last {
+			t.Fatalf("%s expected more than %d attack units but got %d", fen, last, units)
+		}
+		last = units
+	}
+	if units := unsafeFEN(fens[0]).KingAttackUnits(Black); units != 0 {
+		t.Fatalf("expected no attack units without attackers but got %d", units)
+	}
+	pos := unsafeFEN(fens[3])
+	if penalty := pos.KingSafetyPenalty(Black); penalty != kingSafetyTable[pos.KingAttackUnits(Black)] {
+		t.Fatalf("expected safety penalty from table but got %d", penalty)
+	}
+}
+
+func TestIsPracticalDraw(t *testing.T) {
+	tables := []struct {
+		fen  string
+		draw bool
+	}{
+		// opposite colored bishops near the fifty move rule
+		{"4k3/4b3/4p3/3pP3/3P4/8/2B5/4K3 w - - 85 90", true},
+		// opposite colored bishops far from the fifty move rule
+		{"4k3/4b3/4p3/3pP3/3P4/8/2B5/4K3 w - - 10 50", false},
+		// bishop versus knight without pawns near the fifty move rule
+		{"4k3/4n3/8/8/8/8/2B5/4K3 w - - 90 90", true},
+		// insufficient material regardless of the clock
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		// two knights can't force mate
+		{"4k3/8/8/8/8/8/8/1N2K1N1 w - - 0 1", true},
+		// queen versus king is never a practical draw
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 90 90", false},
+	}
+	for _, table := range tables {
+		if unsafeFEN(table.fen).IsPracticalDraw() != table.draw {
+			t.Fatalf("%s expected practical draw to be %t", table.fen, table.draw)
+		}
+	}
+}
+
+func TestMaterialBalance(t *testing.T) {
+	tables := []struct {
+		fen        string
+		balance    int
+		bishopPair int
+	}{
+		{"8/8/8/8/8/8/8/8 w - - 0 1", 0, 0},
+		{startFEN, 0, 0},
+		// white is up a knight
+		{"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 320, 320},
+		// black has the bishop pair against bishop and knight
+		{"4kb2/2b5/8/8/8/8/8/2B1KN2 w - - 0 1", -10, -60},
+		// white queen against black rook and pawn
+		{"4k3/4p3/8/8/8/8/8/r2QK3 w - - 0 1", 300, 300},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if b := pos.MaterialBalance(); b != table.balance {
+			t.Fatalf("%s expected material balance %d but got %d", table.fen, table.balance, b)
+		}
+		if b := pos.MaterialBalanceWithBishopPair(); b != table.bishopPair {
+			t.Fatalf("%s expected material balance with bishop pair %d but got %d", table.fen, table.bishopPair, b)
+		}
+		if b := pos.Mirror().MaterialBalance(); b != -table.balance {
+			t.Fatalf("%s expected mirrored material balance %d but got %d", table.fen, -table.balance, b)
+		}
+	}
+}
+
+func TestKnightOutposts(t *testing.T) {
+	tables := []struct {
+		fen      string
+		c        Color
+		outposts []Square
+	}{
+		// the d5 knight is defended by the e4 pawn and no black pawn can challenge it
+		{"4k3/pp4pp/3p4/3N4/4P3/8/8/4K3 w - - 0 1", White, []Square{D5}},
+		// the c7 pawn can advance to attack the knight
+		{"4k3/ppp3pp/3p4/3N4/4P3/8/8/4K3 w - - 0 1", White, []Square{}},
+		// an undefended knight is not on an outpost
+		{"4k3/pp4pp/3p4/3N4/8/8/8/4K3 w - - 0 1", White, []Square{}},
+		// a knight in its own half is not on an outpost
+		{"4k3/pp4pp/8/8/3N4/4P3/8/4K3 w - - 0 1", White, []Square{}},
+		// black knight on e4 defended by the d5 pawn
+		{"4k3/8/8/3p4/4n3/8/PP4PP/4K3 b - - 0 1", Black, []Square{E4}},
+	}
+	for _, table := range tables {
+		outposts := unsafeFEN(table.fen).KnightOutposts(table.c)
+		if !reflect.DeepEqual(outposts, table.outposts) {
+			t.Fatalf("%s expected outposts %v but got %v", table.fen, table.outposts, outposts)
+		}
+	}
+}
+
+func TestKingDistance(t *testing.T) {
+	tables := []struct {
+		fen      string
+		distance int
+	}{
+		{startFEN, 7},
+		{"7k/8/8/8/8/8/8/K7 w - - 0 1", 7},
+		{"8/8/8/3k4/8/4K3/8/8 w - - 0 1", 2},
+		{"8/8/8/8/4K3/8/8/8 w - - 0 1", 0},
+	}
+	for _, table := range tables {
+		if d := unsafeFEN(table.fen).KingDistance(); d != table.distance {
+			t.Fatalf("%s expected king distance %d but got %d", table.fen, table.distance, d)
+		}
+	}
+	// adjacent kings are illegal but can be set up in partial positions
+	pos, err := FromFENUnchecked("8/8/8/3k4/4K3/8/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := pos.KingDistance(); d != 1 {
+		t.Fatalf("expected adjacent kings to have distance 1 but got %d", d)
+	}
+}
+
+func TestMopUpEval(t *testing.T) {
+	// the black king is driven from the center to the corner
+	fens := []string{
+		"8/8/8/4k3/8/8/8/Q3K3 b - - 0 1",
+		"8/8/8/8/6k1/8/8/Q3K3 b - - 0 1",
+		"8/8/8/8/8/7k/8/Q3K3 b - - 0 1",
+		"8/8/8/8/8/8/7k/Q3K3 b - - 0 1",
+		"8/8/8/8/8/8/8/Q3K2k b - - 0 1",
+	}
+	prev := MopUpEval(unsafeFEN(fens[0]))
+	for _, fen := range fens[1:] {
+		eval := MopUpEval(unsafeFEN(fen))
+		if eval <= prev {
+			t.Fatalf("%s expected mop up eval to increase from %d but got %d", fen, prev, eval)
+		}
+		prev = eval
+	}
+	// the white king approaching the cornered king increases it further
+	if eval := MopUpEval(unsafeFEN("8/8/8/8/8/8/8/Q4K1k b - - 0 1")); eval <= prev {
+		t.Fatalf("expected closer kings to increase mop up eval from %d but got %d", prev, eval)
+	}
+	if eval := MopUpEval(unsafeFEN("8/8/8/8/8/8/5k1K/q7 b - - 0 1")); eval >= 0 {
+		t.Fatalf("expected negative mop up eval when black is winning but got %d", eval)
+	}
+	if eval := MopUpEval(unsafeFEN("8/8/8/8/8/8/5k1K/8 w - - 0 1")); eval != 0 {
+		t.Fatalf("expected no mop up eval with equal material but got %d", eval)
+	}
+}
+
+func TestPhase(t *testing.T) {
+	tables := []struct {
+		fen   string
+		phase int
+	}{
+		{startFEN, 256},
+		// an extra queen after promotion saturates
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNQ w Qkq - 0 1", 256},
+		// without queens
+		{"rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNB1KBNR w KQkq - 0 1", 171},
+		// rook endgame
+		{"4k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K3 w Qk - 0 1", 43},
+		{"4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1", 0},
+	}
+	for _, table := range tables {
+		if phase := unsafeFEN(table.fen).Phase(); phase != table.phase {
+			t.Fatalf("%s expected phase %d but got %d", table.fen, table.phase, phase)
+		}
+	}
+	// removing pieces one by one decreases the phase
+	m := StartingPosition().Board().SquareMap()
+	prev := 256
+	for _, sq := range []Square{B1, C1, D1, A1, G8, F8, D8, H8} {
+		delete(m, sq)
+		phase := (&Position{board: NewBoard(m)}).Phase()
+		if phase >= prev {
+			t.Fatalf("expected phase to decrease from %d after removing %s but got %d", prev, sq, phase)
+		}
+		prev = phase
+	}
+}
@@ -1,21 +1,227 @@
 package chess
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
+// FromFEN decodes FEN notation into a position.  The piece placement,
+// castling rights, en passant square, half move clock and move count
+// are validated and an error is returned if the FEN can't be parsed or
+// the position is illegal.  Positions without kings are accepted so
+// that partial positions can be analyzed.  Castling rights naming the
+// files of the rooks as in Shredder-FEN or X-FEN, or rights whose king
+// and rooks aren't on their squares in standard chess, decode into a
+// Chess960 position as written by its String method.
+func FromFEN(fen string) (*Position, error) {
+	pos, err := decodeFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	pos.inCheck = isInCheck(pos)
+	return pos, nil
+}
+
+// FromVariantFEN decodes FEN notation into a position played by the
+// rules of the variant.  The position is validated as by FromFEN except
+// where the variant's rules differ, such as the pawns on the first rank
+// in Horde or the missing checks in Antichess.
+func FromVariantFEN(v Variant, fen string) (*Position, error) {
+	if _, ok := v.(Standard); ok {
+		return FromFEN(fen)
+	}
+	pos, err := parseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	pos.variant = v
+	if _, ok := v.(Chess960); ok && pos.castling == nil {
+		// K and Q name the outermost rooks wherever the kings stand
+		rights, cs, err := chess960Castling(pos.board, pos.castleRights.String())
+		if err != nil {
+			return nil, fmt.Errorf("chess: fen invalid %s position %s %s", v.Name(), fen, err)
+		}
+		pos.castleRights, pos.castling = rights, cs
+	}
+	validate := validateFENPosition
+	if fv, ok := v.(fenValidator); ok {
+		validate = fv.validateFEN
+	}
+	if err := validate(pos); err != nil {
+		return nil, fmt.Errorf("chess: fen invalid %s position %s %s", v.Name(), fen, err)
+	}
+	cp := pos.copy()
+	cp.turn = cp.turn.Other()
+	if isInCheck(cp) {
+		return nil, fmt.Errorf("chess: fen invalid %s position %s the side not to move is in check", v.Name(), fen)
+	}
+	if _, ok := v.(Crazyhouse); ok && pos.pockets == nil {
+		pos.pockets = &pockets{}
+	}
+	if _, ok := v.(ThreeCheck); ok && pos.checks == nil {
+		pos.checks = &[3]int{}
+	}
+	pos.inCheck = isInCheck(pos)
+	return pos, nil
+}
+
+// fenValidator is implemented by variants whose positions are
+// validated differently than in standard chess.
+type fenValidator interface {
+	validateFEN(pos *Position) error
+}
+
+// FromFENUnchecked decodes FEN notation into a position without
+// validating that the position is legal.  This allows partial positions,
+// such as positions without kings, to be constructed and rendered.  Move
+// generation on such positions is undefined.  An error is still returned
+// if the FEN can't be parsed.
+func FromFENUnchecked(fen string) (*Position, error) {
+	return parseFEN(fen)
+}
+
+// FromDiagram decodes an ASCII diagram into a position with the given
+// side to move.  The diagram has eight rows from rank 8 to rank 1 of
+// eight cells each, using upper case letters for white pieces, lower
+// case letters for black pieces and dots for empty squares.  Whitespace,
+// rank and file labels and borders made of '|', '+' and '-' are
+// ignored.  The position has no castling rights or en passant square.
+// An error is returned if the diagram is malformed or the position is
+// illegal.
+func FromDiagram(s string, sideToMove Color) (*Position, error) {
+	if sideToMove != White && sideToMove != Black {
+		return nil, fmt.Errorf("chess: diagram invalid side to move %s", sideToMove)
+	}
+	ranks := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		row := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || r == '|' {
+				return -1
+			}
+			return r
+		}, line)
+		row = strings.Trim(row, "12345678")
+		if strings.Trim(row, "+-") == "" || strings.EqualFold(row, "abcdefgh") {
+			continue
+		}
+		rank := ""
+		empty := 0
+		for _, r := range row {
+			if r == '.' {
+				empty++
+				continue
+			}
+			if fenPieceMap[string(r)] == NoPiece {
+				return nil, fmt.Errorf("chess: diagram invalid cell %c in row %d", r, len(ranks)+1)
+			}
+			if empty > 0 {
+				rank += strconv.Itoa(empty)
+				empty = 0
+			}
+			rank += string(r)
+		}
+		if n := len([]rune(row)); n != 8 {
+			return nil, fmt.Errorf("chess: diagram row %d has %d cells but must have 8", len(ranks)+1, n)
+		}
+		if empty > 0 {
+			rank += strconv.Itoa(empty)
+		}
+		ranks = append(ranks, rank)
+	}
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("chess: diagram has %d rows but must have 8", len(ranks))
+	}
+	return FromFEN(strings.Join(ranks, "/") + " " + sideToMove.String() + " - - 0 1")
+}
+
 // Decodes FEN notation into a GameState.  An error is returned
-// if there is a parsing error.  FEN notation format:
+// if there is a parsing error or the position is illegal.  FEN notation format:
 // rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+// The half move clock and move count may be omitted together
+// in which case they default to 0 and 1.
 func decodeFEN(fen string) (*Position, error) {
+	pos, err := parseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFENPosition(pos); err != nil {
+		return nil, fmt.Errorf("chess: fen invalid position %s %s", fen, err)
+	}
+	// the previous move can't have left its own king in check
+	b := pos.board
+	if kingSq := b.kingSquare(pos.turn.Other()); kingSq != NoSquare &&
+		b.attackersOf(kingSq, pos.turn, ^b.emptySqs) != 0 {
+		return nil, fmt.Errorf("chess: fen invalid position %s the side not to move is in check", fen)
+	}
+	return pos, nil
+}
+
+// validateFENPosition checks that the kings and pawns can occur in a
+// game, although kings may be missing in partial positions, and that
+// the castling rights and en passant square agree with the placement of
+// the pieces.
+func validateFENPosition(pos *Position) error {
+	b := pos.board
+	for _, c := range []Color{White, Black} {
+		if n := b.bbForPiece(getPiece(King, c)).count(); n > 1 {
+			return fmt.Errorf("%s has %d kings", c.Name(), n)
+		}
+	}
+	if (b.bbWhitePawn|b.bbBlackPawn)&(bbRank1|bbRank8) != 0 {
+		return errors.New("pawns can't be on the first or last rank")
+	}
+	for _, c := range []Color{White, Black} {
+		for _, side := range castleSides {
+			king, rook := pos.castleSquares(c, side)
+			if pos.castleRights.CanCastle(c, side) &&
+				(b.Piece(king) != getPiece(King, c) || b.Piece(rook) != getPiece(Rook, c)) {
+				return fmt.Errorf("castle right %s without the king and rook on %s and %s", castleChar(c, side), king, rook)
+			}
+		}
+	}
+	if sq := pos.enPassantSquare; sq != NoSquare {
+		// the pawn that just moved two squares must be in front of the
+		// en passant square with the squares it passed empty
+		rank, dir, pawn := Rank6, -1, BlackPawn
+		if pos.turn == Black {
+			rank, dir, pawn = Rank3, 1, WhitePawn
+		}
+		pawnSq, _ := sq.Offset(0, dir)
+		fromSq, _ := sq.Offset(0, -dir)
+		if sq.Rank() != rank || b.Piece(pawnSq) != pawn || b.isOccupied(sq) || b.isOccupied(fromSq) {
+			return fmt.Errorf("en passant square %s doesn't follow a two square pawn move", sq)
+		}
+	}
+	return nil
+}
+
+func parseFEN(fen string) (*Position, error) {
 	fen = strings.TrimSpace(fen)
 	parts := strings.Split(fen, " ")
+	// the remaining checks of Three-check follow the en passant square
+	var checks *[3]int
+	if len(parts) == 5 || len(parts) == 7 {
+		c, err := parseFENChecks(parts[4])
+		if err != nil {
+			return nil, err
+		}
+		checks = c
+		parts = append(parts[:4], parts[5:]...)
+	}
+	if len(parts) == 4 {
+		parts = append(parts, "0", "1")
+	}
 	if len(parts) != 6 {
-		return nil, fmt.Errorf("chess: fen invalid notiation %s must have 6 sections", fen)
+		return nil, fmt.Errorf("chess: fen invalid notiation %s must have 4 or 6 sections", fen)
+	}
+	boardStr, pk, err := parseFENPockets(parts[0])
+	if err != nil {
+		return nil, err
 	}
-	b, err := fenBoard(parts[0])
+	b, err := fenBoard(boardStr)
 	if err != nil {
 		return nil, err
 	}
@@ -24,8 +230,13 @@ func decodeFEN(fen string) (*Position, error) {
 		return nil, fmt.Errorf("chess: fen invalid turn %s", parts[1])
 	}
 	rights, err := formCastleRights(parts[2])
+	var cs *castling
 	if err != nil {
-		return nil, err
+		// rights naming the files of the rooks are Chess960's
+		var cerr error
+		if rights, cs, cerr = chess960Castling(b, parts[2]); cerr != nil {
+			return nil, err
+		}
 	}
 	sq, err := formEnPassant(parts[3])
 	if err != nil {
@@ -39,14 +250,31 @@ func decodeFEN(fen string) (*Position, error) {
 	if err != nil || moveCount < 1 {
 		return nil, fmt.Errorf("chess: fen invalid move count %s", parts[5])
 	}
-	return &Position{
+	pos := &Position{
 		board:           b,
 		turn:            turn,
 		castleRights:    rights,
 		enPassantSquare: sq,
 		halfMoveClock:   halfMoveClock,
 		moveCount:       moveCount,
-	}, nil
+		pockets:         pk,
+	}
+	if cs == nil && pos.castleRightsOnBoard() != rights {
+		// X-FEN writes Chess960 rights with the letters of standard
+		// chess which name the outermost rooks wherever the kings stand
+		if xrights, xcs, err := chess960Castling(b, parts[2]); err == nil {
+			pos.castleRights, cs = xrights, xcs
+		}
+	}
+	if cs != nil {
+		pos.castling = cs
+		pos.variant = Chess960{}
+	}
+	if checks != nil {
+		pos.checks = checks
+		pos.variant = ThreeCheck{}
+	}
+	return pos, nil
 }
 
 // generates board from fen format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
@@ -73,19 +301,23 @@ func fenFormRank(rankStr string) (map[File]Piece, error) {
 	count := 0
 	m := map[File]Piece{}
 	err := fmt.Errorf("chess: fen invalid rank %s", rankStr)
+	skipped := false
 	for _, r := range rankStr {
 		c := fmt.Sprintf("%c", r)
 		piece := fenPieceMap[c]
 		if piece == NoPiece {
-			skip, err := strconv.Atoi(c)
-			if err != nil {
+			// empty squares are a single digit from 1 to 8
+			skip, serr := strconv.Atoi(c)
+			if serr != nil || skip < 1 || skip > 8 || skipped {
 				return nil, err
 			}
 			count += skip
+			skipped = true
 			continue
 		}
 		m[File(count)] = piece
 		count++
+		skipped = false
 	}
 	if count != 8 {
 		return nil, err
@@ -100,6 +332,9 @@ func formCastleRights(castleStr string) (CastleRights, error) {
 			return "-", fmt.Errorf("chess: fen invalid castle rights %s", castleStr)
 		}
 	}
+	if castleStr == "" || (castleStr != "-" && strings.Contains(castleStr, "-")) {
+		return "-", fmt.Errorf("chess: fen invalid castle rights %s", castleStr)
+	}
 	for _, r := range castleStr {
 		c := fmt.Sprintf("%c", r)
 		switch c {
@@ -1,6 +1,9 @@
 package chess
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 var (
 	validFENs = []string{
@@ -30,6 +33,23 @@ var (
 		"8/8/8/8/4k3/8/3KP3/8 c - - 0 1",
 		"8/8/5k2/8/5K2/8/4P3P/8 w - - 0 1",
 		"r4rk1/1b2bppp/ppq1p3/2pp3n/5P2/1P1BP3/PBPPQ1PP/R4RK1 w e4 - 0 1",
+		// the side not to move is in check
+		"4k3/8/8/8/8/8/8/r3K3 b - - 0 1",
+		"4k3/8/3N4/8/8/8/8/4K3 w - - 0 1",
+		// more than one king or pawns on the back ranks
+		"4k3/8/8/8/8/8/8/3KK3 w - - 0 1",
+		"4k2P/8/8/8/8/8/8/4K3 w - - 0 1",
+		// castle rights without the king and rook on their home squares
+		"4k3/8/8/8/8/8/8/4K3 w K - 0 1",
+		"4k2r/8/8/8/8/8/8/4K3 w q - 0 1",
+		"4k3/8/8/8/8/8/8/4K3 w K- - 0 1",
+		// en passant squares that don't follow a two square pawn move
+		"4k3/8/8/8/8/8/4P3/4K3 b - e3 0 1",
+		"4k3/8/8/8/4P3/8/8/4K3 w - e3 0 1",
+		"4k3/8/8/8/4P3/4N3/8/4K3 b - e3 0 1",
+		// empty squares must be a single digit from 1 to 8
+		"4k3/8/8/8/8/8/44/4K3 w - - 0 1",
+		"4k3/8/8/8/8/8/08/4K3 w - - 0 1",
 	}
 )
 
@@ -52,3 +72,141 @@ func TestInvalidFENs(t *testing.T) {
 		}
 	}
 }
+
+func TestCompactFEN(t *testing.T) {
+	tables := []struct {
+		fen     string
+		compact string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"},
+		{"5k2/ppp5/4P3/3R3p/6P1/1K2Nr2/PP3P2/8 b - - 1 32", "5k2/ppp5/4P3/3R3p/6P1/1K2Nr2/PP3P2/8 b - - 1 32"},
+		{"8/8/8/4k3/8/8/8/R3K2R w KQ - 0 5", "8/8/8/4k3/8/8/8/R3K2R w KQ - 0 5"},
+	}
+	for _, table := range tables {
+		pos, err := decodeFEN(table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compact := pos.CompactFEN()
+		if compact != table.compact {
+			t.Fatalf("expected compact fen %s but got %s", table.compact, compact)
+		}
+		decoded, err := decodeFEN(compact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.String() != table.fen {
+			t.Fatalf("expected compact fen to round trip to %s but got %s", table.fen, decoded.String())
+		}
+	}
+}
+
+func TestFromFENUnchecked(t *testing.T) {
+	fens := []string{
+		// no kings
+		"8/8/8/3q4/8/8/2P5/8 w - - 0 1",
+		// adjacent kings
+		"8/8/8/3k4/4K3/8/8/8 w - - 0 1",
+		// the side not to move is in check
+		"4k3/8/8/8/8/8/8/r3K3 b - - 0 1",
+	}
+	for _, fen := range fens {
+		pos, err := FromFENUnchecked(fen)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", fen, err)
+		}
+		if pos.String() != fen {
+			t.Fatalf("expected %s but got %s", fen, pos.String())
+		}
+	}
+	if _, err := decodeFEN(fens[2]); err == nil {
+		t.Fatalf("expected strict decoding of %s to fail", fens[2])
+	}
+	if _, err := FromFENUnchecked("8/8/8/8/8/8/8 w - - 0 1"); err == nil {
+		t.Fatal("expected malformed fen to fail")
+	}
+}
+
+func TestFromDiagram(t *testing.T) {
+	tables := []struct {
+		diagram string
+		turn    Color
+		fen     string
+	}{
+		{`
+			rnbqkbnr
+			pppppppp
+			........
+			........
+			....P...
+			........
+			PPPP.PPP
+			RNBQKBNR
+		`, Black, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b - - 0 1"},
+		{`
+			8 | . . . . k . . . |
+			7 | . . . . . . . . |
+			6 | . . . . . . . . |
+			5 | . . . . . . . . |
+			4 | . . . . . . . . |
+			3 | . . . . . . . . |
+			2 | . . . . P . . . |
+			1 | . . . . K . . R |
+			    a b c d e f g h
+		`, White, "4k3/8/8/8/8/8/4P3/4K2R w - - 0 1"},
+	}
+	for _, table := range tables {
+		pos, err := FromDiagram(table.diagram, table.turn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.String() != table.fen {
+			t.Fatalf("expected diagram to decode to %s but got %s", table.fen, pos)
+		}
+	}
+}
+
+func TestFromDiagramErrors(t *testing.T) {
+	tables := []struct {
+		diagram string
+		err     string
+	}{
+		{"....k..\n........\n........\n........\n........\n........\n........\n....K...", "row 1 has 7 cells"},
+		{"....k...\n........\n........\n........\n........\n........\n....K...", "7 rows"},
+		{"....k...\n........\n........\n........\n........\n........\n........\n....K...\n........", "9 rows"},
+		{"....k...\n........\n........\n...x....\n........\n........\n........\n....K...", "invalid cell x in row 4"},
+		// the side not to move is in check
+		{"....k...\n........\n........\n........\n........\n........\n........\n....K..r", "in check"},
+	}
+	for _, table := range tables {
+		_, err := FromDiagram(table.diagram, Black)
+		if err == nil || !strings.Contains(err.Error(), table.err) {
+			t.Fatalf("expected diagram %q to fail with %s but got %v", table.diagram, table.err, err)
+		}
+	}
+}
+
+func TestFromFEN(t *testing.T) {
+	for _, f := range validFENs {
+		pos, err := FromFEN(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.FEN() != f {
+			t.Fatalf("expected fen %s but got %s", f, pos.FEN())
+		}
+	}
+	for _, f := range invalidFENs {
+		if _, err := FromFEN(f); err == nil {
+			t.Fatalf("expected error from %s", f)
+		}
+	}
+	pos, err := FromFEN("4k3/8/8/8/8/8/8/r3K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pos.inCheck {
+		t.Fatal("expected the white king to be in check")
+	}
+}
@@ -1,10 +1,13 @@
 package chess
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
+	"time"
 )
 
 // A Outcome is the result of a game.
@@ -55,6 +58,12 @@ const (
 	// InsufficientMaterial indicates that the game was automatically drawn
 	// because there was insufficient material for checkmate.
 	InsufficientMaterial
+	// Timeout indicates that a player ran out of time.  The game is
+	// drawn if the opponent doesn't have the material to checkmate.
+	Timeout
+	// VariantEnd indicates that the game ended by a rule of its variant
+	// such as a king reaching the center in King of the Hill.
+	VariantEnd
 )
 
 // TagPair represents metadata in a key value pairing used in the PGN format.
@@ -63,16 +72,22 @@ type TagPair struct {
 	Value string
 }
 
-// A Game represents a single chess game.
+// A Game represents a single chess game.  A Game isn't safe for
+// concurrent use, even when one goroutine only reads it while another
+// plays moves, since positions cache their moves as they are read.  Use
+// Clone to hand a copy of the game to other goroutines.
 type Game struct {
 	Notation             Notation
 	tagPairs             []*TagPair
 	moves                []*Move
 	positions            []*Position
 	pos                  *Position
+	root                 *Node
+	tail                 *Node
 	outcome              Outcome
 	method               Method
 	ignoreAutomaticDraws bool
+	clock                *Clock
 }
 
 // PGN takes a reader and returns a function that updates
@@ -106,12 +121,42 @@ func FEN(fen string) (func(*Game), error) {
 	}
 	return func(g *Game) {
 		pos.inCheck = isInCheck(pos)
-		g.pos = pos
-		g.positions = []*Position{pos}
-		g.updatePosition()
+		g.start(pos)
 	}, nil
 }
 
+// UseVariant returns a function that plays the game by the rules of the
+// variant from its starting position and sets the Variant tag pair.  It
+// is designed to be used in the NewGame constructor.  An error is
+// returned if the variant's starting FEN can't be decoded.
+func UseVariant(v Variant) (func(*Game), error) {
+	return VariantFEN(v, v.StartingFEN())
+}
+
+// VariantFEN returns a function that plays the game by the rules of the
+// variant from the position of the FEN and sets the Variant tag pair.
+// It is designed to be used in the NewGame constructor.  An error is
+// returned if the FEN isn't a valid position of the variant.
+func VariantFEN(v Variant, fen string) (func(*Game), error) {
+	pos, err := FromVariantFEN(v, fen)
+	if err != nil {
+		return nil, err
+	}
+	return func(g *Game) {
+		g.start(pos)
+		g.AddTagPair("Variant", v.Name())
+	}, nil
+}
+
+// start sets the game's starting position clearing any moves.
+func (g *Game) start(pos *Position) {
+	g.pos = pos
+	g.positions = []*Position{pos}
+	g.root = &Node{pos: pos}
+	g.tail = g.root
+	g.updatePosition()
+}
+
 // TagPairs returns a function that sets the tag pairs
 // to the given value.  The returned function is designed
 // to be used in the NewGame constructor.
@@ -132,16 +177,52 @@ func UseNotation(n Notation) func(*Game) {
 	}
 }
 
+// UseClock returns a function that times the game's moves with the
+// clock.  The clock is set to the side to move and started unless the
+// game has been completed.  If the game's moves have [%clk ...]
+// comments, such as the ones of a game decoded from PGN, the clock is
+// resumed from the last time of each side.  The returned function is
+// designed to be used in the NewGame constructor after any functions
+// setting the game's moves.
+func UseClock(c *Clock) func(*Game) {
+	return func(g *Game) {
+		g.clock = c
+		c.Stop()
+		c.turn = g.pos.Turn()
+		c.spent = 0
+		resumed := map[Color]bool{}
+		nodes := g.mainLine()
+		for i := len(nodes) - 1; i >= 0; i-- {
+			mover := g.positions[i].Turn()
+			if d, ok := nodes[i].Clock(); ok && !resumed[mover] {
+				c.remaining[mover] = d
+				resumed[mover] = true
+			}
+		}
+		if c.moves[White] == 0 && c.moves[Black] == 0 {
+			for i := range g.moves {
+				c.countMove(g.positions[i].Turn())
+			}
+		}
+		if g.outcome == NoOutcome {
+			c.Start()
+		}
+	}
+}
+
 // NewGame defaults to returning a game in the standard
 // opening position.  Options can be given to configure
 // the game's initial state.
 func NewGame(options ...func(*Game)) *Game {
 	pos := StartingPosition()
+	root := &Node{pos: pos}
 	game := &Game{
 		Notation:  AlgebraicNotation{},
 		moves:     []*Move{},
 		pos:       pos,
 		positions: []*Position{pos},
+		root:      root,
+		tail:      root,
 		outcome:   NoOutcome,
 		method:    NoMethod,
 	}
@@ -154,16 +235,71 @@ func NewGame(options ...func(*Game)) *Game {
 }
 
 // Move updates the game with the given move.  An error is returned
-// if the move is invalid or the game has already been completed.
+// if the move is invalid or the game has already been completed.  If
+// the game has a clock it is pressed and the mover's remaining time is
+// stored in the move's comment as a [%clk ...] command.  If the mover
+// has run out of time the move isn't played, an error is returned and
+// the game ends with the Timeout method: the opponent wins, or the game
+// is drawn if the opponent can't checkmate.  An error is also returned
+// without ending the game if the clock is stopped.
 func (g *Game) Move(m *Move) error {
 	valid := moveSlice(g.ValidMoves()).find(m)
 	if valid == nil {
 		return fmt.Errorf("chess: invalid move %s", m)
 	}
+	mover := g.pos.Turn()
+	if g.clock != nil {
+		if err := g.clock.Press(); err != nil {
+			g.checkClock()
+			return err
+		}
+	}
 	g.moves = append(g.moves, valid)
 	g.pos = g.pos.Update(valid)
 	g.positions = append(g.positions, g.pos)
+	g.tail = g.tail.addMainLine(valid, g.pos)
 	g.updatePosition()
+	if g.clock != nil {
+		g.tail.SetClock(g.clock.Remaining(mover))
+		g.checkClock()
+	}
+	return nil
+}
+
+// PlayRandom plays a uniformly random valid move chosen with the given
+// source of randomness.  An error is returned if the game has already
+// been completed or there are no valid moves.
+func (g *Game) PlayRandom(rng *rand.Rand) error {
+	if g.outcome != NoOutcome {
+		return fmt.Errorf("chess: game has already been completed with %s", g.outcome)
+	}
+	m, ok := g.pos.RandomMove(rng)
+	if !ok {
+		return errors.New("chess: no valid moves")
+	}
+	return g.Move(m)
+}
+
+// PlayUCILine decodes each of the moves in UCI notation and plays them
+// in order.  If a move can't be decoded or is invalid an error naming
+// its index and text is returned and the game is left unchanged.  The
+// error wraps the cause, which is an *IllegalMoveError explaining why
+// an invalid move can't be played.
+func (g *Game) PlayUCILine(moves []string) error {
+	line := g.Clone()
+	for i, s := range moves {
+		m, err := UCINotation{}.Decode(line.pos, s)
+		if err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d: %w", s, i, err)
+		}
+		if err := line.pos.ValidateMove(m); err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d: %w", s, i, err)
+		}
+		if err := line.Move(m); err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d: %w", s, i, err)
+		}
+	}
+	g.copy(line)
 	return nil
 }
 
@@ -171,11 +307,18 @@ func (g *Game) Move(m *Move) error {
 // and calls the Move function.  An error is returned if
 // the move can't be decoded or the move is invalid.
 func (g *Game) MoveStr(s string) error {
-	m, err := g.Notation.Decode(g.pos, s)
+	text, glyph := splitMoveGlyph(s)
+	m, err := g.Notation.Decode(g.pos, text)
 	if err != nil {
 		return err
 	}
-	return g.Move(m)
+	if err := g.Move(m); err != nil {
+		return err
+	}
+	if glyph != "" {
+		g.tail.SetAnnotation(MoveAnnotation(nagFromText(glyph)))
+	}
+	return nil
 }
 
 // ValidMoves returns a list of valid moves in the
@@ -194,23 +337,215 @@ func (g *Game) Moves() []*Move {
 	return append([]*Move(nil), g.moves...)
 }
 
+// MoveHistory returns the game's moves encoded in the given notation.
+// Each move is encoded against the position it was played in so that
+// disambiguation and check marks match that ply.
+func (g *Game) MoveHistory(notation Notation) []string {
+	history := make([]string, len(g.moves))
+	for i, m := range g.moves {
+		history[i] = notation.Encode(g.positions[i], m)
+	}
+	return history
+}
+
+// AnnotateForcedMoves marks every move of the game that was the only
+// legal move in its position with the SingularMoveNAG glyph.  The
+// glyphs are written when the game is encoded as PGN.
+func (g *Game) AnnotateForcedMoves() {
+	for i, node := range g.mainLine() {
+		if g.positions[i].IsForced() {
+			node.AddNAG(SingularMoveNAG)
+		}
+	}
+}
+
+// CapturedPieces returns the pieces white and black have lost during
+// the game in the order they were lost.  A promoted pawn counts as a
+// lost pawn and the promoted piece is lost if it is captured later.
+func (g *Game) CapturedPieces() (white, black []Piece) {
+	white, black = []Piece{}, []Piece{}
+	lose := func(p Piece) {
+		if p.Color() == White {
+			white = append(white, p)
+		} else {
+			black = append(black, p)
+		}
+	}
+	for i, m := range g.moves {
+		pos := g.positions[i]
+		if m.HasTag(EnPassant) {
+			lose(getPiece(Pawn, pos.turn.Other()))
+		} else if m.HasTag(Capture) {
+			lose(pos.board.Piece(m.S2))
+		}
+		if m.promo != NoPieceType {
+			lose(getPiece(Pawn, pos.turn))
+		}
+	}
+	return white, black
+}
+
+// Annotations returns the numeric annotation glyphs and the comment
+// attached to the move at the given index of Moves.  Glyphs written as
+// move suffixes in PGN, such as !?, are returned as their numeric codes.
+// Nil and an empty comment are returned if the index is out of range.
+func (g *Game) Annotations(ply int) (nags []int, comment string) {
+	if ply < 0 || ply >= len(g.moves) {
+		return nil, ""
+	}
+	node := g.mainLine()[ply]
+	return node.NAGs(), node.comment
+}
+
+// MoveClock returns the mover's remaining time after the move at the
+// given index of Moves, read from its [%clk ...] comment as exported by
+// lichess and written by games with a clock.  False is returned if the
+// move has no clock time or the index is out of range.
+func (g *Game) MoveClock(ply int) (time.Duration, bool) {
+	if ply < 0 || ply >= len(g.moves) {
+		return 0, false
+	}
+	return g.mainLine()[ply].Clock()
+}
+
+// MoveEval returns the evaluation after the move at the given index of
+// Moves, read from its [%eval ...] comment.  False is returned if the
+// move has no evaluation or the index is out of range.
+func (g *Game) MoveEval(ply int) (Evaluation, bool) {
+	if ply < 0 || ply >= len(g.moves) {
+		return Evaluation{}, false
+	}
+	return g.mainLine()[ply].Eval()
+}
+
 // TagPairs returns the game's tag pairs.
 func (g *Game) TagPairs() []*TagPair {
 	return append([]*TagPair(nil), g.tagPairs...)
 }
 
+// Root returns the root node of the game tree which holds the
+// starting position.  The game's moves are the main line from the
+// root to the current position.  Variations can be explored and
+// added through the nodes.
+func (g *Game) Root() *Node {
+	return g.root
+}
+
+// PromoteVariation makes the node the main line continuation of its
+// parent.  If the parent's main line was part of the game's moves the
+// game follows the node's line to its end instead and its outcome is
+// recomputed.  An error is returned if the node isn't a move in the
+// game tree.
+func (g *Game) PromoteVariation(n *Node) error {
+	if n.parent == nil || !g.inTree(n) {
+		return errors.New("chess: node isn't a move in the game tree")
+	}
+	parent := n.parent
+	if parent.children[0] == n {
+		return nil
+	}
+	followed := parent != g.tail && g.onMainLine(parent)
+	children := []*Node{n}
+	for _, c := range parent.children {
+		if c != n {
+			children = append(children, c)
+		}
+	}
+	parent.children = children
+	if followed {
+		tail := n
+		for tail.MainLine() != nil {
+			tail = tail.MainLine()
+		}
+		g.followLine(tail)
+	}
+	return nil
+}
+
+// DeleteVariation removes the node and its continuations from the
+// game tree.  If the node is part of the game's moves the game is
+// taken back to the node's parent and its outcome is recomputed.  An
+// error is returned if the node isn't a move in the game tree.
+func (g *Game) DeleteVariation(n *Node) error {
+	if n.parent == nil || !g.inTree(n) {
+		return errors.New("chess: node isn't a move in the game tree")
+	}
+	followed := g.onMainLine(n)
+	parent := n.parent
+	children := []*Node{}
+	for _, c := range parent.children {
+		if c != n {
+			children = append(children, c)
+		}
+	}
+	parent.children = children
+	n.parent = nil
+	if followed {
+		g.followLine(parent)
+	}
+	return nil
+}
+
+// inTree returns true if the node belongs to the game's tree.
+func (g *Game) inTree(n *Node) bool {
+	for ; n != nil; n = n.parent {
+		if n == g.root {
+			return true
+		}
+	}
+	return false
+}
+
+// onMainLine returns true if the node is the root or one of the nodes
+// of the game's moves.
+func (g *Game) onMainLine(n *Node) bool {
+	for cur := g.tail; cur != nil; cur = cur.parent {
+		if cur == n {
+			return true
+		}
+	}
+	return false
+}
+
+// followLine makes the line from the root to the node the game's moves
+// and recomputes the outcome.
+func (g *Game) followLine(tail *Node) {
+	nodes := []*Node{}
+	for n := tail; n.parent != nil; n = n.parent {
+		nodes = append([]*Node{n}, nodes...)
+	}
+	g.moves = []*Move{}
+	g.positions = []*Position{g.root.pos}
+	for _, n := range nodes {
+		g.moves = append(g.moves, n.move)
+		g.positions = append(g.positions, n.pos)
+	}
+	g.pos = tail.pos
+	g.tail = tail
+	g.outcome = NoOutcome
+	g.method = NoMethod
+	g.updatePosition()
+}
+
 // Position returns the game's current position.
 func (g *Game) Position() *Position {
 	return g.pos
 }
 
+// Clock returns the game's clock or nil if the game isn't timed.
+func (g *Game) Clock() *Clock {
+	return g.clock
+}
+
 // Outcome returns the game outcome.
 func (g *Game) Outcome() Outcome {
+	g.checkClock()
 	return g.outcome
 }
 
 // Method returns the method in which the outcome occurred.
 func (g *Game) Method() Method {
+	g.checkClock()
 	return g.method
 }
 
@@ -231,7 +566,7 @@ func (g *Game) MarshalText() (text []byte, err error) {
 	return []byte(encodePGN(g)), nil
 }
 
-// UnmarshalText implements the encoding.TextUnarshaler interface and
+// UnmarshalText implements the encoding.TextUnmarshaler interface and
 // assumes the data is in the PGN format.
 func (g *Game) UnmarshalText(text []byte) error {
 	game, err := decodePGN(string(text))
@@ -242,6 +577,91 @@ func (g *Game) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// gameJSON is the JSON representation of a game.
+type gameJSON struct {
+	Tags   []gameJSONTag `json:"tags"`
+	FEN    string        `json:"fen"`
+	Moves  []string      `json:"moves"`
+	SAN    []string      `json:"san"`
+	Result string        `json:"result"`
+}
+
+type gameJSONTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// MarshalJSON implements the json.Marshaler interface and encodes the
+// game's tag pairs, starting FEN, moves in UCI and algebraic notation
+// and result.
+func (g *Game) MarshalJSON() ([]byte, error) {
+	data := gameJSON{
+		Tags:   []gameJSONTag{},
+		FEN:    g.positions[0].String(),
+		Moves:  []string{},
+		SAN:    []string{},
+		Result: string(g.outcome),
+	}
+	for _, tp := range g.tagPairs {
+		data.Tags = append(data.Tags, gameJSONTag{Key: tp.Key, Value: tp.Value})
+	}
+	for i, m := range g.moves {
+		data.Moves = append(data.Moves, UCINotation{}.Encode(g.positions[i], m))
+		data.SAN = append(data.SAN, AlgebraicNotation{}.Encode(g.positions[i], m))
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and rebuilds
+// the game by replaying the moves from the starting FEN.  The moves are
+// read in UCI notation or, if there are none, in algebraic notation.  An
+// error is returned if the FEN or result is invalid or a move can't be
+// played.
+func (g *Game) UnmarshalJSON(b []byte) error {
+	var data gameJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	tagPairs := []*TagPair{}
+	for _, tp := range data.Tags {
+		tagPairs = append(tagPairs, &TagPair{Key: tp.Key, Value: tp.Value})
+	}
+	gameFuncs := []func(*Game){TagPairs(tagPairs)}
+	startFunc, err := startingPosition(tagPairs, data.FEN)
+	if err != nil {
+		return err
+	}
+	if startFunc != nil {
+		gameFuncs = append(gameFuncs, startFunc)
+	}
+	game := NewGame(gameFuncs...)
+	game.ignoreAutomaticDraws = true
+	var decoder Decoder = UCINotation{}
+	moves := data.Moves
+	if len(moves) == 0 {
+		decoder, moves = AlgebraicNotation{}, data.SAN
+	}
+	for i, s := range moves {
+		m, err := decoder.Decode(game.pos, s)
+		if err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d: %s", s, i, err)
+		}
+		if err := game.Move(m); err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d", s, i)
+		}
+	}
+	switch outcome := Outcome(data.Result); outcome {
+	case "":
+		game.outcome = NoOutcome
+	case NoOutcome, WhiteWon, BlackWon, Draw:
+		game.outcome = outcome
+	default:
+		return fmt.Errorf("chess: invalid result %s", data.Result)
+	}
+	g.copy(game)
+	return nil
+}
+
 // Draw attempts to draw the game by the given method.  If the
 // method is valid, then the game is updated to a draw by that
 // method.  If the method isn't valid then an error is returned.
@@ -261,6 +681,7 @@ func (g *Game) Draw(method Method) error {
 	}
 	g.outcome = Draw
 	g.method = method
+	g.checkClock()
 	return nil
 }
 
@@ -276,6 +697,18 @@ func (g *Game) Resign(color Color) {
 		g.outcome = WhiteWon
 	}
 	g.method = Resignation
+	g.checkClock()
+}
+
+// Finalize closes the game for export.  If the game isn't completed
+// the current position is checked for checkmate, stalemate and the
+// automatic draws, and the Result tag pair is set to the game's
+// outcome so it matches the result token in the PGN.
+func (g *Game) Finalize() {
+	if g.outcome == NoOutcome {
+		g.updatePosition()
+	}
+	g.AddTagPair("Result", string(g.outcome))
 }
 
 // EligibleDraws returns valid inputs for the Draw() method.
@@ -290,6 +723,12 @@ func (g *Game) EligibleDraws() []Method {
 	return draws
 }
 
+// CanClaimDraw returns true if the player to move can claim a draw by
+// threefold repetition or the fifty move rule using the Draw method.
+func (g *Game) CanClaimDraw() bool {
+	return g.outcome == NoOutcome && len(g.EligibleDraws()) > 1
+}
+
 // AddTagPair adds or updates a tag pair with the given key and
 // value and returns true if the value is overwritten.
 func (g *Game) AddTagPair(k, v string) bool {
@@ -330,19 +769,33 @@ func (g *Game) RemoveTagPair(k string) bool {
 	return found
 }
 
-func (g *Game) updatePosition() {
-	method := g.pos.Status()
-	if method == Stalemate {
-		g.method = Stalemate
-		g.outcome = Draw
-	} else if method == Checkmate {
-		g.method = Checkmate
-		g.outcome = WhiteWon
-		if g.pos.Turn() == White {
-			g.outcome = BlackWon
-		}
+// StartTime returns the time the game started in UTC as given by the
+// UTCDate and UTCTime tag pairs.  If UTCTime is missing the start of
+// the day is used.  False is returned if UTCDate is missing, incomplete
+// (ex. 2021.??.??) or either tag can't be parsed.
+func (g *Game) StartTime() (time.Time, bool) {
+	date := g.GetTagPair("UTCDate")
+	if date == nil {
+		return time.Time{}, false
 	}
-	if g.outcome != NoOutcome {
+	value := date.Value
+	layout := "2006.01.02"
+	if clock := g.GetTagPair("UTCTime"); clock != nil {
+		value += " " + clock.Value
+		layout += " 15:04:05"
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (g *Game) updatePosition() {
+	outcome, method := g.pos.status()
+	if method != NoMethod && method != InsufficientMaterial {
+		g.outcome = outcome
+		g.method = method
 		return
 	}
 
@@ -352,14 +805,15 @@ func (g *Game) updatePosition() {
 		g.method = FivefoldRepetition
 	}
 
-	// 75 move rule creates automatic draw
-	if !g.ignoreAutomaticDraws && g.pos.halfMoveClock >= 150 && g.method != Checkmate {
+	// 75 move rule creates automatic draw, checkmate on the last move
+	// has already ended the game above
+	if !g.ignoreAutomaticDraws && g.pos.halfMoveClock >= 150 {
 		g.outcome = Draw
 		g.method = SeventyFiveMoveRule
 	}
 
 	// insufficient material creates automatic draw
-	if !g.ignoreAutomaticDraws && !g.pos.board.hasSufficientMaterial() {
+	if !g.ignoreAutomaticDraws && method == InsufficientMaterial {
 		g.outcome = Draw
 		g.method = InsufficientMaterial
 	}
@@ -370,26 +824,106 @@ func (g *Game) copy(game *Game) {
 	g.moves = game.Moves()
 	g.positions = game.Positions()
 	g.pos = game.pos
+	g.root = game.root
+	g.tail = game.tail
 	g.outcome = game.outcome
 	g.method = game.method
+	// keep the caller's clock when copying a line played on a clone
+	switch {
+	case game.clock == nil:
+	case g.clock == nil:
+		g.clock = game.clock
+	case g.clock != game.clock:
+		*g.clock = *game.clock.copy()
+	}
+}
+
+// checkClock ends the game on time if a player has run out of time
+// and stops the clock once the game has been completed.  A player
+// running out of time loses unless the opponent can't checkmate in
+// which case the game is drawn.
+func (g *Game) checkClock() {
+	if g.clock == nil {
+		return
+	}
+	if color, flagged := g.clock.Flagged(); flagged && g.outcome == NoOutcome {
+		g.method = Timeout
+		switch {
+		case !canCheckmate(g.pos.board, color.Other()):
+			g.outcome = Draw
+		case color == White:
+			g.outcome = BlackWon
+		default:
+			g.outcome = WhiteWon
+		}
+	}
+	if g.outcome != NoOutcome {
+		g.clock.Stop()
+	}
 }
 
+// Clone returns a copy of the game including its tags, game tree and
+// clock.  The copy shares no state that either game changes, so a clone
+// made by the goroutine playing the moves can be read or encoded by
+// other goroutines while the original game continues.
 func (g *Game) Clone() *Game {
+	root := g.root.copyTree(nil)
+	positions := []*Position{root.pos}
+	tail := root
+	for range g.moves {
+		tail = tail.MainLine()
+		positions = append(positions, tail.pos)
+	}
+	tagPairs := make([]*TagPair, len(g.tagPairs))
+	for i, tp := range g.tagPairs {
+		cp := *tp
+		tagPairs[i] = &cp
+	}
 	return &Game{
-		tagPairs:  g.TagPairs(),
+		tagPairs:  tagPairs,
 		Notation:  g.Notation,
 		moves:     g.Moves(),
-		positions: g.Positions(),
-		pos:       g.pos,
+		positions: positions,
+		pos:       tail.pos,
+		root:      root,
+		tail:      tail,
 		outcome:   g.outcome,
 		method:    g.method,
+
+		ignoreAutomaticDraws: g.ignoreAutomaticDraws,
+		clock:                g.cloneClock(),
+	}
+}
+
+func (g *Game) cloneClock() *Clock {
+	if g.clock == nil {
+		return nil
+	}
+	return g.clock.copy()
+}
+
+// mainLine returns the nodes of the game's moves in order.
+func (g *Game) mainLine() []*Node {
+	nodes := make([]*Node, len(g.moves))
+	n := g.tail
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i] = n
+		n = n.parent
 	}
+	return nodes
 }
 
+// numOfRepitions returns the number of times the current position has
+// occurred in the game.  Positions are compared by their zobrist hashes
+// which include the castling rights and a legal en passant square.
 func (g *Game) numOfRepitions() int {
 	count := 0
-	for _, pos := range g.Positions() {
-		if g.pos.samePosition(pos) {
+	key := g.pos.repetitionHash()
+	// positions before the last capture or pawn move can't repeat
+	last := len(g.positions) - 1
+	for i := last; i >= 0 && i >= last-g.pos.halfMoveClock; i-- {
+		pos := g.positions[i]
+		if pos.repetitionHash() == key && g.pos.SamePosition(pos) {
 			count++
 		}
 	}
@@ -0,0 +1,465 @@
+package chess
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGameStartTime(t *testing.T) {
+	tables := []struct {
+		tags     []*TagPair
+		expected time.Time
+		ok       bool
+	}{
+		{
+			[]*TagPair{{Key: "UTCDate", Value: "2013.01.01"}, {Key: "UTCTime", Value: "21:43:35"}},
+			time.Date(2013, time.January, 1, 21, 43, 35, 0, time.UTC), true,
+		},
+		{
+			[]*TagPair{{Key: "UTCDate", Value: "2013.01.01"}},
+			time.Date(2013, time.January, 1, 0, 0, 0, 0, time.UTC), true,
+		},
+		{[]*TagPair{{Key: "UTCDate", Value: "2013.??.??"}}, time.Time{}, false},
+		{[]*TagPair{{Key: "UTCTime", Value: "21:43:35"}}, time.Time{}, false},
+		{[]*TagPair{{Key: "UTCDate", Value: "2013.01.01"}, {Key: "UTCTime", Value: "??:??:??"}}, time.Time{}, false},
+	}
+	for _, table := range tables {
+		g := NewGame(TagPairs(table.tags))
+		actual, ok := g.StartTime()
+		if ok != table.ok || !actual.Equal(table.expected) {
+			t.Fatalf("expected start time (%s, %t) but got (%s, %t)", table.expected, table.ok, actual, ok)
+		}
+	}
+}
+
+func TestGameFinalize(t *testing.T) {
+	g := NewGame(TagPairs([]*TagPair{{Key: "Result", Value: "*"}}))
+	for _, move := range []string{"f3", "e5", "g4", "Qh4#"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Finalize()
+	if g.Outcome() != BlackWon || g.Method() != Checkmate {
+		t.Fatalf("expected black to win by checkmate but got %s by %s", g.Outcome(), g.Method())
+	}
+	if tag := g.GetTagPair("Result"); tag == nil || tag.Value != "0-1" {
+		t.Fatalf("expected result tag 0-1 but got %v", tag)
+	}
+	if s := g.String(); !strings.Contains(s, "[Result \"0-1\"]") || !strings.HasSuffix(s, " 0-1") {
+		t.Fatalf("expected consistent result in pgn but got %s", s)
+	}
+	// an unfinished game is marked as ongoing
+	g = NewGame(TagPairs([]*TagPair{{Key: "Result", Value: "1-0"}}))
+	g.Finalize()
+	if tag := g.GetTagPair("Result"); tag.Value != "*" {
+		t.Fatalf("expected result tag * but got %s", tag.Value)
+	}
+}
+
+func TestGameMoveHistory(t *testing.T) {
+	g := NewGame()
+	for _, move := range []string{"Nf3", "Nf6", "d4", "d5", "Nbd2", "e6", "e4", "dxe4", "Nxe4", "Be7", "Nxf6+", "Bxf6"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tables := []struct {
+		notation Notation
+		ply      int
+		expected string
+	}{
+		{AlgebraicNotation{}, 0, "Nf3"},
+		// both knights could reach d2 when the move was played
+		{AlgebraicNotation{}, 4, "Nbd2"},
+		{AlgebraicNotation{}, 10, "Nxf6+"},
+		{UCINotation{}, 10, "e4f6"},
+		{LongAlgebraicNotation{}, 7, "d5xe4"},
+	}
+	for _, table := range tables {
+		history := g.MoveHistory(table.notation)
+		if len(history) != 12 {
+			t.Fatalf("expected 12 moves in history but got %d", len(history))
+		}
+		if history[table.ply] != table.expected {
+			t.Fatalf("expected ply %d in %s to be %s but got %s", table.ply, table.notation, table.expected, history[table.ply])
+		}
+	}
+}
+
+func TestGameAnnotateForcedMoves(t *testing.T) {
+	opt, err := FEN("5rk1/6pp/8/8/8/8/4B1PP/5R1K b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	// the bishop has to recapture on f1
+	for _, move := range []string{"Rxf1+", "Bxf1", "h6"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.AnnotateForcedMoves()
+	g.AnnotateForcedMoves()
+	expected := [][]int{nil, {SingularMoveNAG}, nil}
+	for i, node := range g.mainLine() {
+		if nags := node.NAGs(); !reflect.DeepEqual(nags, expected[i]) {
+			t.Fatalf("expected move %d to have glyphs %v but got %v", i, expected[i], nags)
+		}
+	}
+	if s := g.String(); !strings.Contains(s, "Bxf1 $8") {
+		t.Fatalf("expected forced move glyph in pgn but got %s", s)
+	}
+}
+
+func TestGameCapturedPieces(t *testing.T) {
+	g := NewGame()
+	moves := []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qe5+", "Be2", "Qxe2+",
+		"Ngxe2", "e5", "d4", "e4", "d5", "c6", "dxc6", "Nf6", "cxb7", "Bd6", "bxa8=Q", "O-O", "Qxb8"}
+	for _, move := range moves {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	white, black := g.CapturedPieces()
+	// the b pawn promotes on a8 and the new queen takes the b8 knight
+	expectedWhite := []Piece{WhitePawn, WhiteBishop, WhitePawn}
+	expectedBlack := []Piece{BlackPawn, BlackQueen, BlackPawn, BlackPawn, BlackRook, BlackKnight}
+	if !reflect.DeepEqual(white, expectedWhite) {
+		t.Fatalf("expected white to have lost %v but got %v", expectedWhite, white)
+	}
+	if !reflect.DeepEqual(black, expectedBlack) {
+		t.Fatalf("expected black to have lost %v but got %v", expectedBlack, black)
+	}
+}
+
+func TestGamePlayRandom(t *testing.T) {
+	play := func(seed int64) *Game {
+		g := NewGame()
+		rng := rand.New(rand.NewSource(seed))
+		for i := 0; i < 40 && g.Outcome() == NoOutcome; i++ {
+			if err := g.PlayRandom(rng); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return g
+	}
+	g := play(42)
+	if len(g.Moves()) == 0 {
+		t.Fatal("expected random moves to be played")
+	}
+	if g.String() != play(42).String() {
+		t.Fatal("expected the same seed to play the same game")
+	}
+	// no moves can be played in a finished game
+	g = NewGame()
+	for _, move := range []string{"f3", "e5", "g4", "Qh4#"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	if m, ok := g.Position().RandomMove(rng); ok || m != nil {
+		t.Fatalf("expected no random move after checkmate but got %s", m)
+	}
+	if err := g.PlayRandom(rng); err == nil {
+		t.Fatal("expected error playing a random move after checkmate")
+	}
+}
+
+func TestGamePlayUCILine(t *testing.T) {
+	g := NewGame()
+	if err := g.PlayUCILine([]string{"e2e4", "e7e5", "g1f3"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Moves()) != 3 || g.Position().Turn() != Black {
+		t.Fatalf("expected three moves to be played but got %d", len(g.Moves()))
+	}
+	before := g.String()
+	tables := []struct {
+		line   []string
+		index  string
+		reason string
+	}{
+		{[]string{"b8c6", "f1c4", "c6c4"}, "c6c4 at index 2", "the piece on c6 can't move to c4"},
+		{[]string{"b8c6", "a7a6"}, "a7a6 at index 1", "the piece on a7 belongs to black"},
+		{[]string{"b8c6", "x"}, "x at index 1", ""},
+	}
+	for _, table := range tables {
+		err := g.PlayUCILine(table.line)
+		if err == nil || !strings.Contains(err.Error(), table.index) {
+			t.Fatalf("expected error naming %s but got %v", table.index, err)
+		}
+		var illegal *IllegalMoveError
+		if errors.As(err, &illegal) != (table.reason != "") || (illegal != nil && illegal.Reason != table.reason) {
+			t.Fatalf("expected error with the reason %q but got %v", table.reason, err)
+		}
+		if g.String() != before || len(g.Root().MainLine().MainLine().MainLine().Children()) != 0 {
+			t.Fatalf("expected game to be unchanged after %v but got %s", table.line, g.String())
+		}
+	}
+}
+
+func TestGameJSON(t *testing.T) {
+	fen, _ := FEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	tables := []*Game{
+		NewGame(),
+		NewGame(fen),
+	}
+	tables[0].AddTagPair("Event", "Example")
+	for _, s := range []string{"f3", "e5", "g4", "Qh4"} {
+		if err := tables[0].MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tables[1].MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	tables[1].Resign(White)
+	horde, _ := UseVariant(Horde{})
+	tables = append(tables, NewGame(horde))
+	if err := tables[2].MoveStr("a5"); err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range tables {
+		b, err := json.Marshal(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp := NewGame()
+		if err := json.Unmarshal(b, cp); err != nil {
+			t.Fatal(err)
+		}
+		if cp.String() != g.String() || cp.Outcome() != g.Outcome() || cp.FEN() != g.FEN() {
+			t.Fatalf("expected game %s to round trip through %s but got %s", g, b, cp)
+		}
+	}
+}
+
+func TestGameJSONAlgebraic(t *testing.T) {
+	g := NewGame()
+	if err := json.Unmarshal([]byte(`{"san":["e4","e5","Nf3"]}`), g); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"moves":["e2e4","e7e5","g1f3"],"san":["e4","e5","Nf3"]`) {
+		t.Fatalf("expected the moves in uci and algebraic notation but got %s", b)
+	}
+}
+
+func TestGameBinary(t *testing.T) {
+	promo, _ := FEN("4k3/1P6/8/8/8/8/6p1/4K2R w K - 0 1")
+	crazyhouse, _ := UseVariant(Crazyhouse{})
+	tables := []struct {
+		options []func(*Game)
+		moves   []string
+	}{
+		{nil, []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "O-O"}},
+		{[]func(*Game){promo}, []string{"b8=N", "gxh1=Q+"}},
+		{[]func(*Game){crazyhouse}, []string{"e4", "d5", "exd5", "Qxd5", "P@e4"}},
+		{nil, []string{"f3", "e5", "g4", "Qh4#"}},
+	}
+	for _, table := range tables {
+		g := NewGame(table.options...)
+		g.AddTagPair("Event", "Example")
+		for _, s := range table.moves {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g.Outcome() == NoOutcome {
+			g.Resign(Black)
+		}
+		b, err := g.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp := NewGame()
+		if err := cp.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if cp.String() != g.String() || cp.Method() != g.Method() || cp.FEN() != g.FEN() {
+			t.Fatalf("expected game %s to round trip but got %s", g, cp)
+		}
+	}
+	// each move takes twelve bits after the header
+	g := NewGame()
+	for _, s := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6"} {
+		g.MoveStr(s)
+	}
+	b, _ := g.MarshalBinary()
+	if len(b) != 6+12 {
+		t.Fatalf("expected eight moves to take twelve bytes after the header but got %d bytes", len(b))
+	}
+	for _, data := range [][]byte{{}, {9, 0, 0}, {1, 0, 0, 0, 0, 2, 0x31, 0x0c}} {
+		if err := NewGame().UnmarshalBinary(data); err == nil {
+			t.Fatalf("expected an error decoding %v", data)
+		}
+	}
+}
+
+func TestGameUnmarshalJSONErrors(t *testing.T) {
+	tables := []struct {
+		data string
+		err  string
+	}{
+		{`{"moves":["e2e4","e7e5","e4e5"]}`, "e4e5 at index 2"},
+		{`{"moves":["e2e4","zz"]}`, "zz at index 1"},
+		{`{"fen":"8/8/8 w - - 0 1"}`, "fen"},
+		{`{"result":"2-0"}`, "invalid result"},
+	}
+	for _, table := range tables {
+		g := NewGame()
+		err := json.Unmarshal([]byte(table.data), g)
+		if err == nil || !strings.Contains(err.Error(), table.err) {
+			t.Fatalf("expected %s to fail with %s but got %v", table.data, table.err, err)
+		}
+		if len(g.Moves()) != 0 {
+			t.Fatalf("expected game to be unchanged after %s", table.data)
+		}
+	}
+}
+
+func TestGameRepetition(t *testing.T) {
+	g := NewGame()
+	shuffle := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	for i := 0; i < 2; i++ {
+		if g.CanClaimDraw() {
+			t.Fatalf("expected no draw claim after %d repetitions", i+1)
+		}
+		for _, s := range shuffle {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !g.CanClaimDraw() {
+		t.Fatal("expected a draw claim after three repetitions")
+	}
+	if err := g.Draw(ThreefoldRepetition); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != Draw || g.Method() != ThreefoldRepetition || g.CanClaimDraw() {
+		t.Fatalf("expected a draw by threefold repetition but got %s by %s", g.Outcome(), g.Method())
+	}
+	// the fifth repetition draws automatically
+	g = NewGame()
+	for i := 0; i < 4; i++ {
+		for _, s := range shuffle {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if g.Outcome() != Draw || g.Method() != FivefoldRepetition {
+		t.Fatalf("expected a draw by fivefold repetition but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestGameRepetitionCastlingRights(t *testing.T) {
+	// the first position had castling rights so it isn't repeated
+	fen, _ := FEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	g := NewGame(fen)
+	for _, s := range []string{"Ke2", "Ke7", "Ke1", "Ke8", "Ke2", "Ke7", "Ke1", "Ke8"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.CanClaimDraw() {
+		t.Fatal("expected the lost castling rights to prevent a threefold claim")
+	}
+	for _, s := range []string{"Ke2", "Ke7", "Ke1", "Ke8"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !g.CanClaimDraw() {
+		t.Fatal("expected a threefold claim once the position without castling rights repeats")
+	}
+}
+
+func TestGameMoveRules(t *testing.T) {
+	fen, _ := FEN("4k3/8/8/8/8/8/R7/4K3 w - - 99 80")
+	g := NewGame(fen)
+	if g.CanClaimDraw() {
+		t.Fatal("expected no fifty move claim before the hundredth half move")
+	}
+	if err := g.Draw(FiftyMoveRule); err == nil {
+		t.Fatal("expected an error claiming the fifty move rule early")
+	}
+	if err := g.MoveStr("Ra3"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.CanClaimDraw() || g.Outcome() != NoOutcome {
+		t.Fatal("expected a fifty move claim but no automatic draw")
+	}
+	if err := g.Draw(FiftyMoveRule); err != nil {
+		t.Fatal(err)
+	}
+	if g.Method() != FiftyMoveRule {
+		t.Fatalf("expected a draw by the fifty move rule but got %s", g.Method())
+	}
+	// a pawn move resets the clock
+	fen, _ = FEN("4k3/8/8/8/8/8/R3P3/4K3 w - - 99 80")
+	g = NewGame(fen)
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Position().HalfMoveClock() != 0 || g.CanClaimDraw() {
+		t.Fatalf("expected the pawn move to reset the half move clock but got %d", g.Position().HalfMoveClock())
+	}
+	// the seventy five move rule draws automatically unless the move mates
+	tables := []struct {
+		fen     string
+		move    string
+		outcome Outcome
+		method  Method
+	}{
+		{"4k3/8/8/8/8/8/R7/4K3 w - - 149 110", "Ra3", Draw, SeventyFiveMoveRule},
+		{"6k1/8/6K1/8/8/8/8/R7 w - - 149 110", "Ra8#", WhiteWon, Checkmate},
+	}
+	for _, table := range tables {
+		fen, _ := FEN(table.fen)
+		g := NewGame(fen)
+		if err := g.MoveStr(table.move); err != nil {
+			t.Fatal(err)
+		}
+		if g.Outcome() != table.outcome || g.Method() != table.method {
+			t.Fatalf("%s expected %s by %s after %s but got %s by %s",
+				table.fen, table.outcome, table.method, table.move, g.Outcome(), g.Method())
+		}
+	}
+}
+
+func TestCloneConcurrentRead(t *testing.T) {
+	g := NewGame()
+	g.AddTagPair("Event", "Live")
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	clone := g.Clone()
+	done := make(chan string)
+	// run with -race to check the clone shares no state the game changes
+	go func() {
+		clone.ValidMoves()
+		done <- clone.String()
+	}()
+	for _, s := range []string{"e5", "Nf3"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.AddTagPair("Event", "Finished")
+	pgn := <-done
+	if !strings.Contains(pgn, `[Event "Live"]`) || !strings.HasSuffix(pgn, "1.e4 *") {
+		t.Fatalf("expected the clone to keep the game as it was cloned but got %s", pgn)
+	}
+}
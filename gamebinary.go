@@ -0,0 +1,219 @@
+package chess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// gameBinaryVersion is the version of the binary game format written by
+// Game's MarshalBinary method.
+const gameBinaryVersion = 1
+
+// binaryOutcomes maps the outcomes to their byte in the binary format.
+var binaryOutcomes = []Outcome{NoOutcome, WhiteWon, BlackWon, Draw}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.  The
+// game is written compactly for storing large numbers of games: after a
+// header with the outcome, method, tag pairs and starting FEN, each move
+// of the main line takes twelve bits for its squares and three more
+// bits for promotions and drops.  Chess960 castles are written as the
+// king capturing its own rook as in UCI notation.  Comments and variations are left out.
+func (g *Game) MarshalBinary() (data []byte, err error) {
+	buf := &bytes.Buffer{}
+	outcome := 0
+	for i, o := range binaryOutcomes {
+		if o == g.outcome {
+			outcome = i
+		}
+	}
+	buf.Write([]byte{gameBinaryVersion, byte(outcome), byte(g.method)})
+	writeUvarint(buf, uint64(len(g.tagPairs)))
+	for _, tp := range g.tagPairs {
+		writeString(buf, tp.Key)
+		writeString(buf, tp.Value)
+	}
+	fen := ""
+	if start := g.positions[0]; !start.IsStandardStart() {
+		fen = start.String()
+	}
+	writeString(buf, fen)
+	writeUvarint(buf, uint64(len(g.moves)))
+	w := &bitWriter{buf: buf}
+	for i, m := range g.moves {
+		// Chess960 castles are written as the king capturing its rook
+		// since the king may stay put or step to the next file
+		w.write(uint64(m.S1)<<6|uint64(g.positions[i].castleTarget(m)), 12)
+		switch {
+		case m.drop != NoPieceType:
+			w.write(uint64(m.drop), 3)
+		case isPromotion(g.positions[i], m):
+			w.write(uint64(m.promo), 3)
+		}
+	}
+	w.flush()
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// and rebuilds the game written by MarshalBinary by replaying its moves.
+// An error is returned if the data is malformed or a move can't be
+// played.
+func (g *Game) UnmarshalBinary(data []byte) error {
+	buf := bytes.NewReader(data)
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(buf, header); err != nil {
+		return errors.New("chess: binary game is missing its header")
+	}
+	if header[0] != gameBinaryVersion {
+		return fmt.Errorf("chess: binary game has unknown version %d", header[0])
+	}
+	if int(header[1]) >= len(binaryOutcomes) || Method(header[2]) > VariantEnd {
+		return errors.New("chess: binary game has an invalid outcome")
+	}
+	n, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return errors.New("chess: binary game has invalid tag pairs")
+	}
+	tagPairs := []*TagPair{}
+	for i := uint64(0); i < n; i++ {
+		key, err := readString(buf)
+		if err != nil {
+			return errors.New("chess: binary game has invalid tag pairs")
+		}
+		value, err := readString(buf)
+		if err != nil {
+			return errors.New("chess: binary game has invalid tag pairs")
+		}
+		tagPairs = append(tagPairs, &TagPair{Key: key, Value: value})
+	}
+	fen, err := readString(buf)
+	if err != nil {
+		return errors.New("chess: binary game has an invalid fen")
+	}
+	gameFuncs := []func(*Game){TagPairs(tagPairs)}
+	startFunc, err := startingPosition(tagPairs, fen)
+	if err != nil {
+		return err
+	}
+	if startFunc != nil {
+		gameFuncs = append(gameFuncs, startFunc)
+	}
+	game := NewGame(gameFuncs...)
+	game.ignoreAutomaticDraws = true
+	count, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return errors.New("chess: binary game has an invalid move count")
+	}
+	r := &bitReader{r: buf}
+	for i := uint64(0); i < count; i++ {
+		sqs, err := r.read(12)
+		if err != nil {
+			return fmt.Errorf("chess: binary game is missing move %d", i)
+		}
+		m := &Move{S1: Square(sqs >> 6), S2: Square(sqs & 63)}
+		if castle := game.pos.kingTakesRook(m.S1, m.S2); castle != nil {
+			m = castle
+		} else if m.S1 == m.S2 || isPromotion(game.pos, m) {
+			pt, err := r.read(3)
+			if err != nil {
+				return fmt.Errorf("chess: binary game is missing move %d", i)
+			}
+			if m.S1 == m.S2 {
+				m.drop = PieceType(pt)
+			} else {
+				m.promo = PieceType(pt)
+			}
+		}
+		if err := game.Move(m); err != nil {
+			return fmt.Errorf("chess: invalid move %s at index %d", m, i)
+		}
+	}
+	if outcome := binaryOutcomes[header[1]]; game.outcome == NoOutcome {
+		game.outcome = outcome
+		game.method = Method(header[2])
+	}
+	g.copy(game)
+	return nil
+}
+
+// isPromotion returns true if the move takes a pawn of the side to move
+// to the last rank.
+func isPromotion(pos *Position, m *Move) bool {
+	p := pos.board.Piece(m.S1)
+	return m.drop == NoPieceType && p.Type() == Pawn && p.Color() == pos.turn &&
+		(m.S2.Rank() == Rank8 || m.S2.Rank() == Rank1)
+}
+
+func writeUvarint(buf *bytes.Buffer, n uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	buf.Write(b[:binary.PutUvarint(b, n)])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", errors.New("chess: invalid string")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// bitWriter writes values of up to 64 bits most significant bit first.
+type bitWriter struct {
+	buf  *bytes.Buffer
+	acc  uint64
+	bits uint
+}
+
+func (w *bitWriter) write(v uint64, bits uint) {
+	for i := bits; i > 0; i-- {
+		w.acc = w.acc<<1 | (v>>(i-1))&1
+		w.bits++
+		if w.bits == 8 {
+			w.buf.WriteByte(byte(w.acc))
+			w.acc, w.bits = 0, 0
+		}
+	}
+}
+
+// flush writes the remaining bits padded with zeros.
+func (w *bitWriter) flush() {
+	if w.bits > 0 {
+		w.buf.WriteByte(byte(w.acc << (8 - w.bits)))
+		w.acc, w.bits = 0, 0
+	}
+}
+
+// bitReader reads the values written by bitWriter.
+type bitReader struct {
+	r    io.ByteReader
+	cur  byte
+	bits uint
+}
+
+func (r *bitReader) read(bits uint) (uint64, error) {
+	var v uint64
+	for i := uint(0); i < bits; i++ {
+		if r.bits == 0 {
+			b, err := r.r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			r.cur, r.bits = b, 8
+		}
+		r.bits--
+		v = v<<1 | uint64(r.cur>>r.bits)&1
+	}
+	return v, nil
+}
@@ -1,14 +1,14 @@
-// Package image is a go library that creates images from board positions
+// Package image is a go library that creates SVG and PNG images from
+// board positions
 package image
 
 import (
 	"fmt"
 	"image/color"
 	"io"
-	"strings"
+	"math"
 
 	chess "github.com/Yoshi-Exeler/chesslib"
-	"github.com/Yoshi-Exeler/chesslib/image/internal"
 	svg "github.com/ajstarks/svgo"
 )
 
@@ -21,7 +21,7 @@ func SVG(w io.Writer, b *chess.Board, opts ...func(*encoder)) error {
 }
 
 // SquareColors is designed to be used as an optional argument
-// to the SVG function.  It changes the default light and
+// to the SVG and PNG functions.  It changes the default light and
 // dark square colors to the colors given.
 func SquareColors(light, dark color.Color) func(*encoder) {
 	return func(e *encoder) {
@@ -31,7 +31,7 @@ func SquareColors(light, dark color.Color) func(*encoder) {
 }
 
 // MarkSquares is designed to be used as an optional argument
-// to the SVG function.  It marks the given squares with the
+// to the SVG and PNG functions.  It marks the given squares with the
 // color.  A possible usage includes marking squares of the
 // previous move.
 func MarkSquares(c color.Color, sqs ...chess.Square) func(*encoder) {
@@ -42,12 +42,41 @@ func MarkSquares(c color.Color, sqs ...chess.Square) func(*encoder) {
 	}
 }
 
+// Perspective is designed to be used as an optional argument
+// to the SVG and PNG functions.  It draws the board from the given
+// color's side so that Black flips the board with rank 1 at
+// the top and the h file on the left.
+func Perspective(c chess.Color) func(*encoder) {
+	return func(e *encoder) {
+		e.perspective = c
+	}
+}
+
+// Arrow is designed to be used as an optional argument to the
+// SVG and PNG functions.  It draws an arrow with the color from the
+// center of one square to the center of the other.  Possible
+// usages include showing the previous move or a threat.
+func Arrow(c color.Color, from, to chess.Square) func(*encoder) {
+	return func(e *encoder) {
+		e.arrows = append(e.arrows, arrow{c: c, from: from, to: to})
+	}
+}
+
+type arrow struct {
+	c        color.Color
+	from, to chess.Square
+}
+
 // A Encoder encodes chess boards into images.
 type encoder struct {
-	w     io.Writer
-	light color.Color
-	dark  color.Color
-	marks map[chess.Square]color.Color
+	w           io.Writer
+	light       color.Color
+	dark        color.Color
+	marks       map[chess.Square]color.Color
+	arrows      []arrow
+	perspective chess.Color
+	pieces      PieceSet
+	docs        map[chess.Piece][]byte
 }
 
 // New returns an encoder that writes to the given writer.
@@ -59,6 +88,10 @@ func new(w io.Writer, options []func(*encoder)) *encoder {
 		light: color.RGBA{235, 209, 166, 1},
 		dark:  color.RGBA{165, 117, 81, 1},
 		marks: map[chess.Square]color.Color{},
+
+		perspective: chess.White,
+		pieces:      DefaultPieces,
+		docs:        map[chess.Piece][]byte{},
 	}
 	for _, op := range options {
 		op(e)
@@ -89,7 +122,7 @@ func (e *encoder) EncodeSVG(b *chess.Board) error {
 
 	for i := 0; i < 64; i++ {
 		sq := chess.Square(i)
-		x, y := xyForSquare(sq)
+		x, y := e.xyForSquare(sq)
 		// draw square
 		c := e.colorForSquare(sq)
 		canvas.Rect(x, y, sqWidth, sqHeight, "fill: "+colorToHex(c))
@@ -100,27 +133,76 @@ func (e *encoder) EncodeSVG(b *chess.Board) error {
 		// draw piece
 		p := boardMap[sq]
 		if p != chess.NoPiece {
-			xml := pieceXML(x, y, p)
+			doc, err := e.pieceSVG(p)
+			if err != nil {
+				return err
+			}
+			xml, err := pieceXML(x, y, doc)
+			if err != nil {
+				return err
+			}
 			if _, err := io.WriteString(canvas.Writer, xml); err != nil {
 				return err
 			}
 		}
-		// draw rank text on file A
+		// draw rank text on the left file and file text on the bottom rank
 		txtColor := e.colorForText(sq)
-		if sq.File() == chess.FileA {
+		leftFile, bottomRank := chess.FileA, chess.Rank1
+		if e.perspective == chess.Black {
+			leftFile, bottomRank = chess.FileH, chess.Rank8
+		}
+		if sq.File() == leftFile {
 			style := "font-size:11px;fill: " + colorToHex(txtColor)
 			canvas.Text(x+(sqWidth*1/20), y+(sqHeight*5/20), sq.Rank().String(), style)
 		}
-		// draw file text on rank 1
-		if sq.Rank() == chess.Rank1 {
+		if sq.Rank() == bottomRank {
 			style := "text-anchor:end;font-size:11px;fill: " + colorToHex(txtColor)
 			canvas.Text(x+(sqWidth*19/20), y+sqHeight-(sqHeight*1/15), sq.File().String(), style)
 		}
 	}
+	for _, a := range e.arrows {
+		e.drawArrow(canvas, a)
+	}
 	canvas.End()
 	return nil
 }
 
+// drawArrow draws the arrow's shaft and head between the centers of
+// its squares.
+func (e *encoder) drawArrow(canvas *svg.SVG, a arrow) {
+	if a.from == a.to {
+		return
+	}
+	x1, y1 := e.xyForSquare(a.from)
+	x2, y2 := e.xyForSquare(a.to)
+	x1, y1, x2, y2 = x1+sqWidth/2, y1+sqHeight/2, x2+sqWidth/2, y2+sqHeight/2
+	const headLength, headWidth = 18.0, 12.0
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	length := math.Hypot(dx, dy)
+	ux, uy := dx/length, dy/length
+	// the shaft ends where the head begins
+	bx, by := float64(x2)-ux*headLength, float64(y2)-uy*headLength
+	style := "stroke-linecap:round;stroke-opacity:0.8;stroke-width:8;stroke: " + colorToHex(a.c)
+	canvas.Line(x1, y1, int(math.Round(bx)), int(math.Round(by)), style)
+	xs := []int{x2, int(math.Round(bx - uy*headWidth)), int(math.Round(bx + uy*headWidth))}
+	ys := []int{y2, int(math.Round(by + ux*headWidth)), int(math.Round(by - ux*headWidth))}
+	canvas.Polygon(xs, ys, "fill-opacity:0.8;fill: "+colorToHex(a.c))
+}
+
+// pieceSVG returns the piece's SVG document from the encoder's piece
+// set, reading each piece only once.
+func (e *encoder) pieceSVG(p chess.Piece) ([]byte, error) {
+	if doc, ok := e.docs[p]; ok {
+		return doc, nil
+	}
+	doc, err := e.pieces.SVG(p)
+	if err != nil {
+		return nil, fmt.Errorf("image: reading piece %s: %w", pieceFileName(p), err)
+	}
+	e.docs[p] = doc
+	return doc, nil
+}
+
 func (e *encoder) colorForSquare(sq chess.Square) color.Color {
 	sqSum := int(sq.File()) + int(sq.Rank())
 	if sqSum%2 == 0 {
@@ -137,9 +219,12 @@ func (e *encoder) colorForText(sq chess.Square) color.Color {
 	return e.dark
 }
 
-func xyForSquare(sq chess.Square) (x, y int) {
+func (e *encoder) xyForSquare(sq chess.Square) (x, y int) {
 	fileIndex := int(sq.File())
 	rankIndex := 7 - int(sq.Rank())
+	if e.perspective == chess.Black {
+		fileIndex, rankIndex = 7-fileIndex, 7-rankIndex
+	}
 	return fileIndex * sqWidth, rankIndex * sqHeight
 }
 
@@ -147,22 +232,3 @@ func colorToHex(c color.Color) string {
 	r, g, b, _ := c.RGBA()
 	return fmt.Sprintf("#%02x%02x%02x", uint8(float64(r)+0.5), uint8(float64(g)*1.0+0.5), uint8(float64(b)*1.0+0.5))
 }
-
-func pieceXML(x, y int, p chess.Piece) string {
-	fileName := fmt.Sprintf("pieces/%s%s.svg", p.Color().String(), pieceTypeMap[p.Type()])
-	svgStr := string(internal.MustAsset(fileName))
-	old := `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="45" height="45">`
-	new := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="360" height="360" viewBox="%d %d 360 360">`, (-1 * x), (-1 * y))
-	return strings.Replace(svgStr, old, new, 1)
-}
-
-var (
-	pieceTypeMap = map[chess.PieceType]string{
-		chess.King:   "K",
-		chess.Queen:  "Q",
-		chess.Rook:   "R",
-		chess.Bishop: "B",
-		chess.Knight: "N",
-		chess.Pawn:   "P",
-	}
-)
@@ -0,0 +1,40 @@
+package image
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+func TestSVGPerspective(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	board := chess.StartingPosition().Board()
+	mark := MarkSquares(color.RGBA{255, 255, 0, 1}, chess.A1)
+	if err := SVG(buf, board, mark, Perspective(chess.Black)); err != nil {
+		t.Fatal(err)
+	}
+	// from black's side a1 is the top right square
+	s := buf.String()
+	if !strings.Contains(s, `<rect x="315" y="0" width="45" height="45" style="fill-opacity:0.2;fill: #ffff00"`) {
+		t.Fatalf("expected a1 to be marked in the top right corner but got %s", s)
+	}
+}
+
+func TestSVGArrow(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	board := chess.StartingPosition().Board()
+	arrow := Arrow(color.RGBA{0, 128, 0, 1}, chess.E2, chess.E4)
+	if err := SVG(buf, board, arrow); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, `<line x1="202" y1="292" x2="202" y2="220"`) {
+		t.Fatalf("expected the arrow shaft from e2 towards e4 but got %s", s)
+	}
+	if !strings.Contains(s, `<polygon points="202,202 214,220 190,220"`) {
+		t.Fatalf("expected the arrow head on e4 but got %s", s)
+	}
+}
@@ -0,0 +1,91 @@
+package image
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+	"github.com/Yoshi-Exeler/chesslib/image/internal"
+)
+
+// A PieceSet provides the images of the pieces drawn on the board.
+// Each image is an SVG document whose root element's viewBox, or width
+// and height without one, is scaled to fit a square.  PNG images
+// support the subset of SVG with paths, basic shapes, solid fills and
+// strokes and transforms, which is what piece sets usually consist of.
+type PieceSet interface {
+	SVG(p chess.Piece) ([]byte, error)
+}
+
+// DefaultPieces is the built in piece set used unless the Pieces
+// option is given.
+var DefaultPieces PieceSet = builtinPieces{}
+
+type builtinPieces struct{}
+
+func (builtinPieces) SVG(p chess.Piece) ([]byte, error) {
+	return internal.Asset("pieces/" + pieceFileName(p))
+}
+
+// PiecesFS returns the piece set with the SVG files of the file system,
+// which are named like the built in pieces with the color and the
+// upper case piece type such as wK.svg for the white king or bN.svg
+// for the black knight.  A theme is usually embedded or read from a
+// directory with os.DirFS.
+func PiecesFS(fsys fs.FS) PieceSet {
+	return fsPieces{fsys: fsys}
+}
+
+type fsPieces struct {
+	fsys fs.FS
+}
+
+func (s fsPieces) SVG(p chess.Piece) ([]byte, error) {
+	return fs.ReadFile(s.fsys, pieceFileName(p))
+}
+
+// Pieces is designed to be used as an optional argument to
+// the SVG and PNG functions.  It draws the pieces with the
+// set given, such as a theme loaded with PiecesFS.
+func Pieces(set PieceSet) func(*encoder) {
+	return func(e *encoder) {
+		e.pieces = set
+	}
+}
+
+func pieceFileName(p chess.Piece) string {
+	return p.Color().String() + pieceTypeMap[p.Type()] + ".svg"
+}
+
+// pieceXML returns the contents of the piece's SVG document in a group
+// scaling it onto the square at x, y.
+func pieceXML(x, y int, doc []byte) (string, error) {
+	t, err := fitTransform(doc, float64(x), float64(y), sqWidth)
+	if err != nil {
+		return "", err
+	}
+	s := string(doc)
+	start := strings.Index(s, "<svg")
+	end := strings.LastIndex(s, "</svg>")
+	if start < 0 || end < start {
+		// a root element without contents draws nothing
+		return "", nil
+	}
+	open := strings.Index(s[start:], ">") + start + 1
+	if open > end {
+		return "", nil
+	}
+	return fmt.Sprintf(`<g transform="matrix(%g %g %g %g %g %g)">%s</g>`, t[0], t[1], t[2], t[3], t[4], t[5], s[open:end]), nil
+}
+
+var (
+	pieceTypeMap = map[chess.PieceType]string{
+		chess.King:   "K",
+		chess.Queen:  "Q",
+		chess.Rook:   "R",
+		chess.Bishop: "B",
+		chess.Knight: "N",
+		chess.Pawn:   "P",
+	}
+)
@@ -0,0 +1,137 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// PNG writes the board PNG representation into the writer.
+// It takes the same options as SVG and draws the same image
+// at one pixel per SVG unit.  An error is returned if a piece
+// image can't be drawn or there is an error writing data.
+func PNG(w io.Writer, b *chess.Board, opts ...func(*encoder)) error {
+	e := new(w, opts)
+	return e.EncodePNG(b)
+}
+
+// EncodePNG writes the board PNG representation into
+// the Encoder's writer.  An error is returned if a piece
+// image can't be drawn or there is an error writing data.
+func (e *encoder) EncodePNG(b *chess.Board) error {
+	img := image.NewRGBA(image.Rect(0, 0, boardWidth, boardHeight))
+	boardMap := b.SquareMap()
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		x, y := e.xyForSquare(sq)
+		r := image.Rect(x, y, x+sqWidth, y+sqHeight)
+		// draw square
+		fillRect(img, r, e.colorForSquare(sq), 1)
+		if markColor, ok := e.marks[sq]; ok {
+			fillRect(img, r, markColor, 0.2)
+		}
+		// draw piece
+		if p := boardMap[sq]; p != chess.NoPiece {
+			doc, err := e.pieceSVG(p)
+			if err != nil {
+				return err
+			}
+			t, err := fitTransform(doc, float64(x), float64(y), sqWidth)
+			if err != nil {
+				return err
+			}
+			if err := renderSVG(img, doc, t); err != nil {
+				return err
+			}
+		}
+		// draw rank text on the left file and file text on the bottom
+		// rank where the SVG text is placed
+		txtColor := e.colorForText(sq)
+		leftFile, bottomRank := chess.FileA, chess.Rank1
+		if e.perspective == chess.Black {
+			leftFile, bottomRank = chess.FileH, chess.Rank8
+		}
+		if sq.File() == leftFile {
+			drawGlyph(img, x+2, y+4, sq.Rank().String()[0], txtColor)
+		}
+		if sq.Rank() == bottomRank {
+			drawGlyph(img, x+sqWidth-2-glyphWidth, y+sqHeight-3-glyphHeight, sq.File().String()[0], txtColor)
+		}
+	}
+	for _, a := range e.arrows {
+		e.drawArrowPNG(img, a)
+	}
+	return png.Encode(e.w, img)
+}
+
+// drawArrowPNG draws the arrow like drawArrow.
+func (e *encoder) drawArrowPNG(img *image.RGBA, a arrow) {
+	if a.from == a.to {
+		return
+	}
+	x1, y1 := e.xyForSquare(a.from)
+	x2, y2 := e.xyForSquare(a.to)
+	from := point{float64(x1 + sqWidth/2), float64(y1 + sqHeight/2)}
+	to := point{float64(x2 + sqWidth/2), float64(y2 + sqHeight/2)}
+	const headLength, headWidth = 18.0, 12.0
+	u := to.sub(from).unit()
+	base := to.sub(u.mul(headLength))
+	st := stroke{width: 8, cap: "round"}
+	shaft := []subpath{{pts: []point{from, base}}}
+	fill(st.outline(shaft), false, img.Bounds()).draw(img, a.c, 0.8)
+	n := u.perp().mul(headWidth)
+	head := [][]point{{to, base.add(n), base.sub(n)}}
+	fill(head, false, img.Bounds()).draw(img, a.c, 0.8)
+}
+
+// fillRect blends the color into the rectangle with the opacity.
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color, opacity float64) {
+	src := opaque(c)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			blend(img, x, y, src, opacity)
+		}
+	}
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs are the bitmaps of the coordinate labels with one row of five
+// pixels per byte, the leftmost in the highest bit.
+var glyphs = map[byte][glyphHeight]uint8{
+	'1': {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2': {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3': {0x1e, 0x01, 0x01, 0x0e, 0x01, 0x01, 0x1e},
+	'4': {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5': {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6': {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'a': {0x00, 0x00, 0x0e, 0x01, 0x0f, 0x11, 0x0f},
+	'b': {0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x1e},
+	'c': {0x00, 0x00, 0x0e, 0x10, 0x10, 0x11, 0x0e},
+	'd': {0x01, 0x01, 0x0d, 0x13, 0x11, 0x11, 0x0f},
+	'e': {0x00, 0x00, 0x0e, 0x11, 0x1f, 0x10, 0x0e},
+	'f': {0x06, 0x09, 0x08, 0x1c, 0x08, 0x08, 0x08},
+	'g': {0x00, 0x0f, 0x11, 0x11, 0x0f, 0x01, 0x0e},
+	'h': {0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x11},
+}
+
+// drawGlyph draws the label character with its top left corner at x, y.
+func drawGlyph(img *image.RGBA, x, y int, ch byte, c color.Color) {
+	src := opaque(c)
+	rows := glyphs[ch]
+	for dy, row := range rows {
+		for dx := 0; dx < glyphWidth; dx++ {
+			if row&(1<<(glyphWidth-1-dx)) != 0 {
+				blend(img, x+dx, y+dy, src, 1)
+			}
+		}
+	}
+}
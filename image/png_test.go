@@ -0,0 +1,161 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+func decodePNG(t *testing.T, opts ...func(*encoder)) image.Image {
+	t.Helper()
+	buf := bytes.NewBuffer([]byte{})
+	if err := PNG(buf, chess.StartingPosition().Board(), opts...); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func expectPixel(t *testing.T, img image.Image, x, y int, c color.RGBA) {
+	t.Helper()
+	r, g, b, _ := img.At(x, y).RGBA()
+	got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+	diff := func(a, b uint8) bool { return int(a)-int(b) > 1 || int(b)-int(a) > 1 }
+	if diff(got.R, c.R) || diff(got.G, c.G) || diff(got.B, c.B) {
+		t.Fatalf("expected pixel %d,%d to be %v but got %v", x, y, c, got)
+	}
+}
+
+func TestPNGSquares(t *testing.T) {
+	yellow := color.RGBA{255, 255, 0, 1}
+	img := decodePNG(t, MarkSquares(yellow, chess.D4))
+	if b := img.Bounds(); b.Dx() != boardWidth || b.Dy() != boardHeight {
+		t.Fatalf("expected a %dx%d image but got %v", boardWidth, boardHeight, b)
+	}
+	// e4 is light and the marked d4 is dark blended with yellow
+	expectPixel(t, img, 202, 202, color.RGBA{235, 209, 166, 255})
+	expectPixel(t, img, 157, 202, color.RGBA{183, 145, 65, 255})
+	// the rank label of the light a8 square is drawn in the dark color
+	expectPixel(t, img, 4, 4, color.RGBA{165, 117, 81, 255})
+}
+
+func TestPNGPerspective(t *testing.T) {
+	yellow := color.RGBA{255, 255, 0, 1}
+	img := decodePNG(t, MarkSquares(yellow, chess.A4), Perspective(chess.Black))
+	// from black's side a4 is on the right edge of the fourth row
+	expectPixel(t, img, 337, 157, color.RGBA{239, 218, 133, 255})
+	expectPixel(t, img, 337, 202, color.RGBA{165, 117, 81, 255})
+}
+
+func TestPNGArrow(t *testing.T) {
+	green := color.RGBA{0, 128, 0, 1}
+	img := decodePNG(t, Arrow(green, chess.E3, chess.E6))
+	// the shaft on e4 and the head on e6 are green over the squares
+	expectPixel(t, img, 202, 202, color.RGBA{47, 144, 33, 255})
+	expectPixel(t, img, 202, 118, color.RGBA{47, 144, 33, 255})
+	// next to the shaft the square is untouched
+	expectPixel(t, img, 190, 202, color.RGBA{235, 209, 166, 255})
+}
+
+// redPieces have a red square filling the middle of a 100 unit view box
+// with a hole cut out by the even odd rule.
+var redPieces = fstest.MapFS{}
+
+func init() {
+	doc := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+  <path d="M10 10H90V90H10Z M40 40h20v20h-20z" fill="#ff0000" fill-rule="evenodd"/>
+</svg>`
+	for _, c := range []string{"w", "b"} {
+		for _, p := range []string{"K", "Q", "R", "B", "N", "P"} {
+			redPieces[c+p+".svg"] = &fstest.MapFile{Data: []byte(doc)}
+		}
+	}
+}
+
+func TestPiecesFS(t *testing.T) {
+	img := decodePNG(t, Pieces(PiecesFS(redPieces)))
+	// e1 has a red square with a hole showing the dark square
+	expectPixel(t, img, 182, 320, color.RGBA{165, 117, 81, 255})
+	expectPixel(t, img, 190, 325, color.RGBA{255, 0, 0, 255})
+	expectPixel(t, img, 202, 337, color.RGBA{165, 117, 81, 255})
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := SVG(buf, chess.StartingPosition().Board(), Pieces(PiecesFS(redPieces))); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, `<g transform="matrix(0.45 0 0 0.45 180 315)">`) {
+		t.Fatalf("expected the piece on e1 to be scaled onto the square but got %s", s)
+	}
+	if n := strings.Count(s, "<?xml"); n != 1 {
+		t.Fatalf("expected the pieces without their xml declarations but got %d", n)
+	}
+}
+
+func TestPiecesFSMissing(t *testing.T) {
+	set := PiecesFS(fstest.MapFS{})
+	board := chess.StartingPosition().Board()
+	if err := PNG(bytes.NewBuffer([]byte{}), board, Pieces(set)); err == nil {
+		t.Fatal("expected an error for the missing PNG pieces")
+	}
+	if err := SVG(bytes.NewBuffer([]byte{}), board, Pieces(set)); err == nil {
+		t.Fatal("expected an error for the missing SVG pieces")
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	white, black := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	tests := []struct {
+		doc  string
+		x, y int
+		c    color.RGBA
+	}{
+		// an inner subpath winding the same way stays filled with non zero
+		{`<svg width="20" height="20"><path d="M0 0H20V20H0Z M5 5H15V15H5Z"/></svg>`, 10, 10, black},
+		{`<svg width="20" height="20"><path d="M0 0H20V20H0Z M5 5H15V15H5Z" fill-rule="evenodd"/></svg>`, 10, 10, white},
+		// strokes are centered on the line
+		{`<svg width="20" height="20"><line x1="0" y1="10" x2="20" y2="10" stroke="#000" stroke-width="4"/></svg>`, 10, 8, black},
+		{`<svg width="20" height="20"><line x1="0" y1="10" x2="20" y2="10" stroke="#000" stroke-width="4"/></svg>`, 10, 6, white},
+		// styles are inherited and the style attribute wins
+		{`<svg width="20" height="20"><g fill="#000" style="fill:#ff0000"><circle cx="10" cy="10" r="5"/></g></svg>`, 10, 10, color.RGBA{255, 0, 0, 255}},
+		{`<svg width="20" height="20"><g transform="translate(10 0)"><rect width="10" height="20"/></g></svg>`, 5, 10, white},
+		{`<svg width="20" height="20"><g transform="translate(10 0)"><rect width="10" height="20"/></g></svg>`, 15, 10, black},
+		// the large clockwise arc with flags written without separators
+		// bulges above the chord
+		{`<svg width="20" height="20"><path d="M2 10a8 8 0 1110 0Z"/></svg>`, 10, 6, black},
+		{`<svg width="20" height="20"><path d="M2 10a8 8 0 1110 0Z"/></svg>`, 7, 12, white},
+	}
+	for _, test := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		fillRect(img, img.Bounds(), white, 1)
+		if err := renderSVG(img, []byte(test.doc), identity); err != nil {
+			t.Fatal(err)
+		}
+		expectPixel(t, img, test.x, test.y, test.c)
+	}
+}
+
+func TestRenderSVGInvalid(t *testing.T) {
+	docs := []string{
+		`<svg width="20" height="20"><path d="M0 0L"/></svg>`,
+		`<svg width="20" height="20"><path d="X0 0"/></svg>`,
+		`<svg width="20" height="20"><g transform="spin(3)"/></svg>`,
+		`<svg width="20" height="20"><path d="M0 0"></svg>`,
+	}
+	for _, doc := range docs {
+		img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		if err := renderSVG(img, []byte(doc), identity); err == nil {
+			t.Fatalf("expected an error for %s", doc)
+		}
+	}
+}
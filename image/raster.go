@@ -0,0 +1,466 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// subSamples is the number of rows sampled per pixel row.  Coverage is
+// exact horizontally so this is enough for smooth edges.
+const subSamples = 8
+
+type point struct {
+	x, y float64
+}
+
+func (p point) add(o point) point      { return point{p.x + o.x, p.y + o.y} }
+func (p point) sub(o point) point      { return point{p.x - o.x, p.y - o.y} }
+func (p point) mul(f float64) point    { return point{p.x * f, p.y * f} }
+func (p point) dot(o point) float64    { return p.x*o.x + p.y*o.y }
+func (p point) cross(o point) float64  { return p.x*o.y - p.y*o.x }
+func (p point) length() float64        { return math.Hypot(p.x, p.y) }
+func (p point) perp() point            { return point{-p.y, p.x} }
+func (p point) equal(o point) bool     { return math.Abs(p.x-o.x) < 1e-9 && math.Abs(p.y-o.y) < 1e-9 }
+func (p point) unit() point            { return p.mul(1 / p.length()) }
+func lerp(a, b point, t float64) point { return a.add(b.sub(a).mul(t)) }
+
+// polylineLength returns the length of the lines through the points.
+func polylineLength(pts ...point) (n float64) {
+	for i := 1; i < len(pts); i++ {
+		n += pts[i].sub(pts[i-1]).length()
+	}
+	return n
+}
+
+// transform is an affine transform written as in the SVG matrix(a b c d
+// e f) function, mapping x and y to a*x+c*y+e and b*x+d*y+f.
+type transform [6]float64
+
+var identity = transform{1, 0, 0, 1, 0, 0}
+
+func (t transform) apply(p point) point {
+	return point{t[0]*p.x + t[2]*p.y + t[4], t[1]*p.x + t[3]*p.y + t[5]}
+}
+
+// then returns the transform applying o and then t.
+func (t transform) then(o transform) transform {
+	return transform{
+		t[0]*o[0] + t[2]*o[1],
+		t[1]*o[0] + t[3]*o[1],
+		t[0]*o[2] + t[2]*o[3],
+		t[1]*o[2] + t[3]*o[3],
+		t[0]*o[4] + t[2]*o[5] + t[4],
+		t[1]*o[4] + t[3]*o[5] + t[5],
+	}
+}
+
+// scale returns the factor the transform scales lengths by on average.
+func (t transform) scale() float64 {
+	return math.Sqrt(math.Abs(t[0]*t[3] - t[1]*t[2]))
+}
+
+// subpath is a flattened part of a path in device coordinates.
+type subpath struct {
+	pts    []point
+	closed bool
+}
+
+// pathBuilder flattens path segments given in user coordinates into
+// subpaths in device coordinates.
+type pathBuilder struct {
+	t    transform
+	subs []subpath
+}
+
+func (b *pathBuilder) moveTo(p point) {
+	b.subs = append(b.subs, subpath{pts: []point{b.t.apply(p)}})
+}
+
+// current returns the subpath being drawn.  Drawing after a subpath is
+// closed continues from its start in a new subpath.
+func (b *pathBuilder) current() *subpath {
+	if len(b.subs) == 0 {
+		b.moveTo(point{})
+	} else if last := b.subs[len(b.subs)-1]; last.closed {
+		b.subs = append(b.subs, subpath{pts: []point{last.pts[0]}})
+	}
+	return &b.subs[len(b.subs)-1]
+}
+
+func (b *pathBuilder) lineTo(p point) {
+	s := b.current()
+	s.pts = append(s.pts, b.t.apply(p))
+}
+
+// curveTo flattens the Bézier curve with the control points, which may
+// be quadratic or cubic, into enough lines to be smooth at its size.
+func (b *pathBuilder) curveTo(ctrl ...point) {
+	s := b.current()
+	pts := []point{s.pts[len(s.pts)-1]}
+	for _, p := range ctrl {
+		pts = append(pts, b.t.apply(p))
+	}
+	n := int(math.Ceil(polylineLength(pts...) / 2))
+	if n < 4 {
+		n = 4
+	} else if n > 200 {
+		n = 200
+	}
+	tmp := make([]point, len(pts))
+	for i := 1; i <= n; i++ {
+		copy(tmp, pts)
+		t := float64(i) / float64(n)
+		// de Casteljau's algorithm
+		for k := len(tmp) - 1; k > 0; k-- {
+			for j := 0; j < k; j++ {
+				tmp[j] = lerp(tmp[j], tmp[j+1], t)
+			}
+		}
+		s.pts = append(s.pts, tmp[0])
+	}
+}
+
+// arcTo adds the elliptical arc of an SVG path from the current point
+// in user coordinates cur, using the endpoint to center conversion of
+// the SVG specification.
+func (b *pathBuilder) arcTo(cur point, rx, ry, rotation float64, large, sweep bool, end point) {
+	if cur.equal(end) {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		b.lineTo(end)
+		return
+	}
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (cur.x-end.x)/2, (cur.y-end.y)/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1, cy1 := coef*rx*y1/ry, -coef*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (cur.x+end.x)/2
+	cy := sin*cx1 + cos*cy1 + (cur.y+end.y)/2
+	angle := func(u, v point) float64 {
+		return math.Atan2(u.cross(v), u.dot(v))
+	}
+	theta := angle(point{1, 0}, point{(x1 - cx1) / rx, (y1 - cy1) / ry})
+	delta := angle(point{(x1 - cx1) / rx, (y1 - cy1) / ry}, point{(-x1 - cx1) / rx, (-y1 - cy1) / ry})
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	n := int(math.Ceil(math.Abs(delta) * math.Max(rx, ry) * b.t.scale() / 2))
+	if n < 8 {
+		n = 8
+	} else if n > 200 {
+		n = 200
+	}
+	for i := 1; i < n; i++ {
+		t := theta + delta*float64(i)/float64(n)
+		x, y := rx*math.Cos(t), ry*math.Sin(t)
+		b.lineTo(point{cx + x*cos - y*sin, cy + x*sin + y*cos})
+	}
+	b.lineTo(end)
+}
+
+func (b *pathBuilder) close() {
+	if len(b.subs) > 0 {
+		b.subs[len(b.subs)-1].closed = true
+	}
+}
+
+// ellipse adds a closed ellipse.
+func (b *pathBuilder) ellipse(c point, rx, ry float64) {
+	n := int(math.Ceil(2 * math.Pi * math.Max(rx, ry) * b.t.scale() / 2))
+	if n < 16 {
+		n = 16
+	} else if n > 200 {
+		n = 200
+	}
+	for i := 0; i < n; i++ {
+		t := 2 * math.Pi * float64(i) / float64(n)
+		p := point{c.x + rx*math.Cos(t), c.y + ry*math.Sin(t)}
+		if i == 0 {
+			b.moveTo(p)
+		} else {
+			b.lineTo(p)
+		}
+	}
+	b.close()
+}
+
+// stroke describes how lines are drawn.
+type stroke struct {
+	width      float64
+	cap        string
+	join       string
+	miterLimit float64
+}
+
+// outline returns the polygons covering the subpaths drawn with the
+// stroke, all wound the same way so that filling them with the non zero
+// rule draws their union.
+func (st stroke) outline(subs []subpath) [][]point {
+	hw := st.width / 2
+	if hw <= 0 {
+		return nil
+	}
+	polys := [][]point{}
+	add := func(pts ...point) {
+		area := 0.0
+		for i := range pts {
+			area += pts[i].cross(pts[(i+1)%len(pts)])
+		}
+		if area < 0 {
+			for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		polys = append(polys, pts)
+	}
+	circle := func(c point) {
+		n := int(math.Ceil(hw * 4))
+		if n < 12 {
+			n = 12
+		}
+		pts := make([]point, n)
+		for i := range pts {
+			t := 2 * math.Pi * float64(i) / float64(n)
+			pts[i] = point{c.x + hw*math.Cos(t), c.y + hw*math.Sin(t)}
+		}
+		add(pts...)
+	}
+	for _, s := range subs {
+		pts := []point{}
+		for _, p := range s.pts {
+			if len(pts) == 0 || !pts[len(pts)-1].equal(p) {
+				pts = append(pts, p)
+			}
+		}
+		if s.closed && len(pts) > 1 && pts[0].equal(pts[len(pts)-1]) {
+			pts = pts[:len(pts)-1]
+		}
+		if len(pts) == 1 {
+			if st.cap == "round" {
+				circle(pts[0])
+			}
+			continue
+		}
+		segs := len(pts) - 1
+		if s.closed {
+			segs = len(pts)
+		}
+		dirs := make([]point, segs)
+		for i := range dirs {
+			p0, p1 := pts[i], pts[(i+1)%len(pts)]
+			d := p1.sub(p0).unit()
+			dirs[i] = d
+			n := d.perp().mul(hw)
+			add(p0.add(n), p1.add(n), p1.sub(n), p0.sub(n))
+		}
+		// joins between consecutive segments
+		for i := range dirs {
+			if i == len(dirs)-1 && !s.closed {
+				break
+			}
+			d0, d1 := dirs[i], dirs[(i+1)%len(dirs)]
+			v := pts[(i+1)%len(pts)]
+			st.joinAt(v, d0, d1, hw, add, circle)
+		}
+		if s.closed {
+			continue
+		}
+		first, last := pts[0], pts[len(pts)-1]
+		switch st.cap {
+		case "round":
+			circle(first)
+			circle(last)
+		case "square":
+			d0, d1 := dirs[0].mul(hw), dirs[len(dirs)-1].mul(hw)
+			n0, n1 := d0.perp(), d1.perp()
+			add(first.add(n0), first.sub(n0), first.sub(n0).sub(d0), first.add(n0).sub(d0))
+			add(last.add(n1), last.add(n1).add(d1), last.sub(n1).add(d1), last.sub(n1))
+		}
+	}
+	return polys
+}
+
+// joinAt adds the join at v between the segments with the directions.
+func (st stroke) joinAt(v, d0, d1 point, hw float64, add func(...point), circle func(point)) {
+	cross := d0.cross(d1)
+	if math.Abs(cross) < 1e-9 && d0.dot(d1) > 0 {
+		return
+	}
+	if st.join == "round" {
+		circle(v)
+		return
+	}
+	// the offsets diverge on the outer side of the turn
+	side := 1.0
+	if cross > 0 {
+		side = -1
+	}
+	n0, n1 := d0.perp().mul(hw*side), d1.perp().mul(hw*side)
+	if st.join == "bevel" || math.Abs(cross) < 1e-9 {
+		add(v, v.add(n0), v.add(n1))
+		return
+	}
+	bisector := n0.add(n1)
+	if bisector.length() < 1e-9 {
+		add(v, v.add(n0), v.add(n1))
+		return
+	}
+	bisector = bisector.unit()
+	dist := hw * hw / bisector.dot(n0)
+	limit := st.miterLimit
+	if limit < 1 {
+		limit = 4
+	}
+	if dist/hw > limit {
+		add(v, v.add(n0), v.add(n1))
+		return
+	}
+	add(v, v.add(n0), v.add(bisector.mul(dist)), v.add(n1))
+}
+
+// mask is the coverage of a shape in a region of the image.
+type mask struct {
+	rect image.Rectangle
+	cov  []float32
+}
+
+// fill returns the coverage of the polygons, which are implicitly
+// closed, within the bounds.
+func fill(polys [][]point, evenOdd bool, bounds image.Rectangle) *mask {
+	type edge struct {
+		p0, p1 point
+		dir    int
+	}
+	edges := []edge{}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, pts := range polys {
+		for i := range pts {
+			p0, p1 := pts[i], pts[(i+1)%len(pts)]
+			minX, maxX = math.Min(minX, p0.x), math.Max(maxX, p0.x)
+			minY, maxY = math.Min(minY, p0.y), math.Max(maxY, p0.y)
+			switch {
+			case p0.y < p1.y:
+				edges = append(edges, edge{p0, p1, 1})
+			case p0.y > p1.y:
+				edges = append(edges, edge{p1, p0, -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	r := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).Intersect(bounds)
+	if r.Empty() {
+		return nil
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].p0.y < edges[j].p0.y })
+	m := &mask{rect: r, cov: make([]float32, r.Dx()*r.Dy())}
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	crossings := []crossing{}
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		row := m.cov[(py-r.Min.Y)*r.Dx() : (py-r.Min.Y+1)*r.Dx()]
+		for s := 0; s < subSamples; s++ {
+			y := float64(py) + (float64(s)+0.5)/subSamples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if e.p0.y > y {
+					break
+				}
+				if y >= e.p1.y {
+					continue
+				}
+				x := e.p0.x + (y-e.p0.y)*(e.p1.x-e.p0.x)/(e.p1.y-e.p0.y)
+				crossings = append(crossings, crossing{x, e.dir})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding := 0
+			for i, c := range crossings {
+				inside := winding != 0
+				if evenOdd {
+					inside = winding%2 != 0
+				}
+				if inside && i > 0 {
+					addSpan(row, crossings[i-1].x-float64(r.Min.X), c.x-float64(r.Min.X))
+				}
+				winding += c.dir
+			}
+		}
+	}
+	return m
+}
+
+// addSpan adds a sub row's coverage of the pixels between x0 and x1.
+func addSpan(row []float32, x0, x1 float64) {
+	x0, x1 = math.Max(x0, 0), math.Min(x1, float64(len(row)))
+	if x1 <= x0 {
+		return
+	}
+	const w = 1.0 / subSamples
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		row[i0] += float32((x1 - x0) * w)
+		return
+	}
+	row[i0] += float32((float64(i0+1) - x0) * w)
+	for i := i0 + 1; i < i1; i++ {
+		row[i] += w
+	}
+	if i1 < len(row) {
+		row[i1] += float32((x1 - float64(i1)) * w)
+	}
+}
+
+// draw blends the color into the image where the mask covers it.  The
+// color's alpha is ignored as in the SVG output and opacity is used
+// instead.
+func (m *mask) draw(img *image.RGBA, c color.Color, opacity float64) {
+	if m == nil {
+		return
+	}
+	src := opaque(c)
+	for y := m.rect.Min.Y; y < m.rect.Max.Y; y++ {
+		for x := m.rect.Min.X; x < m.rect.Max.X; x++ {
+			a := float64(m.cov[(y-m.rect.Min.Y)*m.rect.Dx()+x-m.rect.Min.X])
+			if a <= 0 {
+				continue
+			}
+			blend(img, x, y, src, math.Min(a, 1)*opacity)
+		}
+	}
+}
+
+// blend mixes the color into the pixel with the given alpha.
+func blend(img *image.RGBA, x, y int, c color.RGBA, a float64) {
+	i := img.PixOffset(x, y)
+	pix := img.Pix[i : i+4]
+	mix := func(d, s uint8) uint8 {
+		return uint8(math.Round(float64(d)*(1-a) + float64(s)*a))
+	}
+	pix[0], pix[1], pix[2] = mix(pix[0], c.R), mix(pix[1], c.G), mix(pix[2], c.B)
+	pix[3] = 255
+}
+
+// opaque returns the color's red, green and blue without its alpha.
+func opaque(c color.Color) color.RGBA {
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+}
@@ -0,0 +1,610 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The renderer draws the subset of SVG used by piece sets: svg, g,
+// path, circle, ellipse, rect, line, polyline and polygon elements with
+// solid fills and strokes, fill rules, opacities and transforms.
+// Gradients, clipping, text and CSS style sheets are not supported.
+
+// paint is a fill or stroke color, or none.
+type paint struct {
+	none bool
+	c    color.RGBA
+}
+
+// style is the inherited drawing state of an element.
+type style struct {
+	t             transform
+	fill, stroke  paint
+	fillOpacity   float64
+	strokeOpacity float64
+	opacity       float64
+	evenOdd       bool
+	st            stroke
+}
+
+func defaultStyle(t transform) style {
+	return style{
+		t:             t,
+		fill:          paint{c: color.RGBA{0, 0, 0, 255}},
+		stroke:        paint{none: true},
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		opacity:       1,
+		st:            stroke{width: 1, cap: "butt", join: "miter", miterLimit: 4},
+	}
+}
+
+// viewBox returns the area of the SVG document's root element to draw.
+// Without a viewBox attribute it is given by the width and height.
+func viewBox(doc []byte) (minX, minY, width, height float64, err error) {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("image: no svg element: %w", err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if el.Name.Local != "svg" {
+			return 0, 0, 0, 0, fmt.Errorf("image: root element is %s instead of svg", el.Name.Local)
+		}
+		width, height = 45, 45
+		for _, a := range el.Attr {
+			switch a.Name.Local {
+			case "width":
+				width = parseLength(a.Value, width)
+			case "height":
+				height = parseLength(a.Value, height)
+			}
+		}
+		if v := attr(el, "viewBox"); v != "" {
+			nums, err := parseNumbers(v)
+			if err != nil || len(nums) != 4 {
+				return 0, 0, 0, 0, fmt.Errorf("image: invalid viewBox %q", v)
+			}
+			minX, minY, width, height = nums[0], nums[1], nums[2], nums[3]
+		}
+		if width <= 0 || height <= 0 {
+			return 0, 0, 0, 0, errors.New("image: svg element has no size")
+		}
+		return minX, minY, width, height, nil
+	}
+}
+
+// fitTransform maps the document's view box onto the square with the
+// top left corner x, y and the size, centering it like the default
+// preserveAspectRatio of SVG.
+func fitTransform(doc []byte, x, y, size float64) (transform, error) {
+	minX, minY, w, h, err := viewBox(doc)
+	if err != nil {
+		return identity, err
+	}
+	s := math.Min(size/w, size/h)
+	x += (size - w*s) / 2
+	y += (size - h*s) / 2
+	return transform{s, 0, 0, s, x - minX*s, y - minY*s}, nil
+}
+
+// renderSVG draws the SVG document into the image with the transform
+// from the document's coordinates to the image's.
+func renderSVG(img *image.RGBA, doc []byte, t transform) error {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	stack := []style{defaultStyle(t)}
+	skip := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("image: invalid svg: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch tok.Name.Local {
+			case "defs", "title", "desc", "metadata", "style", "clipPath", "mask", "symbol", "text", "linearGradient", "radialGradient", "pattern", "marker":
+				skip = 1
+				continue
+			}
+			s, err := stack[len(stack)-1].inherit(tok)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, s)
+			if err := drawElement(img, tok, s); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// inherit returns the style of the element from the presentation
+// attributes and style attribute, which takes precedence.  Invalid
+// values are ignored as browsers do.
+func (s style) inherit(el xml.StartElement) (style, error) {
+	props := [][2]string{}
+	for _, a := range el.Attr {
+		if a.Name.Local != "style" && a.Name.Local != "transform" {
+			props = append(props, [2]string{a.Name.Local, a.Value})
+		}
+	}
+	for _, decl := range strings.Split(attr(el, "style"), ";") {
+		if i := strings.Index(decl, ":"); i >= 0 {
+			props = append(props, [2]string{strings.TrimSpace(decl[:i]), strings.TrimSpace(decl[i+1:])})
+		}
+	}
+	for _, p := range props {
+		name, v := p[0], p[1]
+		num, numErr := strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+		switch name {
+		case "fill", "stroke":
+			c, ok := parsePaint(v)
+			if !ok {
+				continue
+			}
+			if name == "fill" {
+				s.fill = c
+			} else {
+				s.stroke = c
+			}
+		case "fill-rule":
+			s.evenOdd = v == "evenodd"
+		case "stroke-linecap":
+			s.st.cap = v
+		case "stroke-linejoin":
+			s.st.join = v
+		case "stroke-width":
+			if numErr == nil {
+				s.st.width = num
+			}
+		case "stroke-miterlimit":
+			if numErr == nil {
+				s.st.miterLimit = num
+			}
+		case "fill-opacity":
+			if numErr == nil {
+				s.fillOpacity = num
+			}
+		case "stroke-opacity":
+			if numErr == nil {
+				s.strokeOpacity = num
+			}
+		case "opacity":
+			// group opacity is approximated by applying it to each shape
+			if numErr == nil {
+				s.opacity *= num
+			}
+		}
+	}
+	if v := attr(el, "transform"); v != "" {
+		t, err := parseTransform(v)
+		if err != nil {
+			return s, err
+		}
+		s.t = s.t.then(t)
+	}
+	return s, nil
+}
+
+// drawElement fills and then strokes the shape of the element.
+func drawElement(img *image.RGBA, el xml.StartElement, s style) error {
+	b := &pathBuilder{t: s.t}
+	num := func(name string) float64 {
+		return parseLength(attr(el, name), 0)
+	}
+	switch el.Name.Local {
+	case "path":
+		if err := parsePath(b, attr(el, "d")); err != nil {
+			return err
+		}
+	case "circle":
+		b.ellipse(point{num("cx"), num("cy")}, num("r"), num("r"))
+	case "ellipse":
+		b.ellipse(point{num("cx"), num("cy")}, num("rx"), num("ry"))
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		b.moveTo(point{x, y})
+		b.lineTo(point{x + w, y})
+		b.lineTo(point{x + w, y + h})
+		b.lineTo(point{x, y + h})
+		b.close()
+	case "line":
+		b.moveTo(point{num("x1"), num("y1")})
+		b.lineTo(point{num("x2"), num("y2")})
+	case "polyline", "polygon":
+		nums, err := parseNumbers(attr(el, "points"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(nums); i += 2 {
+			if i == 0 {
+				b.moveTo(point{nums[i], nums[i+1]})
+			} else {
+				b.lineTo(point{nums[i], nums[i+1]})
+			}
+		}
+		if el.Name.Local == "polygon" {
+			b.close()
+		}
+	default:
+		return nil
+	}
+	bounds := img.Bounds()
+	if !s.fill.none && el.Name.Local != "line" {
+		polys := make([][]point, len(b.subs))
+		for i, sub := range b.subs {
+			polys[i] = sub.pts
+		}
+		fill(polys, s.evenOdd, bounds).draw(img, s.fill.c, s.fillOpacity*s.opacity)
+	}
+	if !s.stroke.none {
+		st := s.st
+		st.width *= s.t.scale()
+		fill(st.outline(b.subs), false, bounds).draw(img, s.stroke.c, s.strokeOpacity*s.opacity)
+	}
+	return nil
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func parseLength(s string, def float64) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+var colorNames = map[string]color.RGBA{
+	"black":  {0, 0, 0, 255},
+	"white":  {255, 255, 255, 255},
+	"red":    {255, 0, 0, 255},
+	"green":  {0, 128, 0, 255},
+	"blue":   {0, 0, 255, 255},
+	"yellow": {255, 255, 0, 255},
+	"gray":   {128, 128, 128, 255},
+	"grey":   {128, 128, 128, 255},
+}
+
+func parsePaint(s string) (paint, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "none" {
+		return paint{none: true}, true
+	}
+	if c, ok := colorNames[s]; ok {
+		return paint{c: c}, true
+	}
+	if strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")") {
+		nums, err := parseNumbers(s[4 : len(s)-1])
+		if err != nil || len(nums) != 3 {
+			return paint{}, false
+		}
+		c := color.RGBA{A: 255}
+		for i, p := range []*uint8{&c.R, &c.G, &c.B} {
+			*p = uint8(math.Max(0, math.Min(255, nums[i])))
+		}
+		return paint{c: c}, true
+	}
+	if !strings.HasPrefix(s, "#") {
+		return paint{}, false
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return paint{}, false
+	}
+	return paint{c: color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}}, true
+}
+
+// parseTransform parses a list of SVG transform functions.
+func parseTransform(s string) (transform, error) {
+	t := identity
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		open, end := strings.Index(rest, "("), strings.Index(rest, ")")
+		if open < 0 || end < open {
+			return t, fmt.Errorf("image: invalid transform %q", s)
+		}
+		name := strings.Trim(rest[:open], " \t\n,")
+		args, err := parseNumbers(rest[open+1 : end])
+		if err != nil {
+			return t, fmt.Errorf("image: invalid transform %q", s)
+		}
+		rest = strings.TrimLeft(rest[end+1:], " \t\n,")
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		var f transform
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(f[:], args)
+		case name == "translate" && len(args) > 0:
+			f = transform{1, 0, 0, 1, args[0], arg(1, 0)}
+		case name == "scale" && len(args) > 0:
+			f = transform{args[0], 0, 0, arg(1, args[0]), 0, 0}
+		case name == "rotate" && len(args) > 0:
+			a := args[0] * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			f = transform{1, 0, 0, 1, cx, cy}.
+				then(transform{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}).
+				then(transform{1, 0, 0, 1, -cx, -cy})
+		case name == "skewX" && len(args) == 1:
+			f = transform{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			f = transform{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return t, fmt.Errorf("image: invalid transform %q", s)
+		}
+		t = t.then(f)
+	}
+	return t, nil
+}
+
+// pathScanner reads the commands, numbers and flags of path data.
+type pathScanner struct {
+	s   string
+	pos int
+}
+
+func (sc *pathScanner) skip() {
+	for sc.pos < len(sc.s) && strings.IndexByte(" \t\r\n,", sc.s[sc.pos]) >= 0 {
+		sc.pos++
+	}
+}
+
+// command returns the next command letter if there is one.
+func (sc *pathScanner) command() (byte, bool) {
+	sc.skip()
+	if sc.pos < len(sc.s) {
+		c := sc.s[sc.pos]
+		if (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'e' && c != 'E' {
+			sc.pos++
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// more reports whether a number follows.
+func (sc *pathScanner) more() bool {
+	sc.skip()
+	if sc.pos >= len(sc.s) {
+		return false
+	}
+	c := sc.s[sc.pos]
+	return c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9'
+}
+
+func (sc *pathScanner) number() (float64, error) {
+	sc.skip()
+	start, i := sc.pos, sc.pos
+	if i < len(sc.s) && (sc.s[i] == '-' || sc.s[i] == '+') {
+		i++
+	}
+	dot := false
+	for i < len(sc.s) && (sc.s[i] >= '0' && sc.s[i] <= '9' || sc.s[i] == '.' && !dot) {
+		dot = dot || sc.s[i] == '.'
+		i++
+	}
+	if i < len(sc.s) && (sc.s[i] == 'e' || sc.s[i] == 'E') {
+		j := i + 1
+		if j < len(sc.s) && (sc.s[j] == '-' || sc.s[j] == '+') {
+			j++
+		}
+		if j < len(sc.s) && sc.s[j] >= '0' && sc.s[j] <= '9' {
+			i = j
+			for i < len(sc.s) && sc.s[i] >= '0' && sc.s[i] <= '9' {
+				i++
+			}
+		}
+	}
+	f, err := strconv.ParseFloat(sc.s[start:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("image: invalid number in path at %d", start)
+	}
+	sc.pos = i
+	return f, nil
+}
+
+// flag reads an arc flag, which may be written without a separator.
+func (sc *pathScanner) flag() (bool, error) {
+	sc.skip()
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == '0' || sc.s[sc.pos] == '1') {
+		sc.pos++
+		return sc.s[sc.pos-1] == '1', nil
+	}
+	return false, fmt.Errorf("image: invalid arc flag in path at %d", sc.pos)
+}
+
+func parseNumbers(s string) ([]float64, error) {
+	sc := &pathScanner{s: s}
+	nums := []float64{}
+	for sc.more() {
+		f, err := sc.number()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, f)
+	}
+	if sc.skip(); sc.pos != len(s) {
+		return nil, fmt.Errorf("image: invalid numbers %q", s)
+	}
+	return nums, nil
+}
+
+// parsePath adds the path data to the builder.
+func parsePath(b *pathBuilder, d string) error {
+	sc := &pathScanner{s: d}
+	var cur, start, ctrl point
+	var last byte
+	for {
+		cmd, ok := sc.command()
+		if !ok {
+			if sc.pos >= len(sc.s) {
+				return nil
+			}
+			if last == 0 || !sc.more() {
+				return fmt.Errorf("image: invalid path data at %d", sc.pos)
+			}
+			// repeated arguments repeat the command, lines after a move
+			cmd = last
+			if cmd == 'M' {
+				cmd = 'L'
+			} else if cmd == 'm' {
+				cmd = 'l'
+			}
+		}
+		rel := cmd >= 'a'
+		vals := func(n int) ([]float64, error) {
+			v := make([]float64, n)
+			for i := range v {
+				f, err := sc.number()
+				if err != nil {
+					return nil, err
+				}
+				v[i] = f
+			}
+			return v, nil
+		}
+		pt := func(x, y float64) point {
+			if rel {
+				return point{cur.x + x, cur.y + y}
+			}
+			return point{x, y}
+		}
+		// reflect the previous control point for smooth curves
+		reflect := func(prev ...byte) point {
+			for _, c := range prev {
+				if last == c || last == c+'a'-'A' {
+					return cur.mul(2).sub(ctrl)
+				}
+			}
+			return cur
+		}
+		var err error
+		var v []float64
+		switch cmd {
+		case 'M', 'm':
+			if v, err = vals(2); err == nil {
+				cur = pt(v[0], v[1])
+				start = cur
+				b.moveTo(cur)
+			}
+		case 'L', 'l':
+			if v, err = vals(2); err == nil {
+				cur = pt(v[0], v[1])
+				b.lineTo(cur)
+			}
+		case 'H', 'h':
+			if v, err = vals(1); err == nil {
+				if rel {
+					cur.x += v[0]
+				} else {
+					cur.x = v[0]
+				}
+				b.lineTo(cur)
+			}
+		case 'V', 'v':
+			if v, err = vals(1); err == nil {
+				if rel {
+					cur.y += v[0]
+				} else {
+					cur.y = v[0]
+				}
+				b.lineTo(cur)
+			}
+		case 'C', 'c':
+			if v, err = vals(6); err == nil {
+				c1, c2, p := pt(v[0], v[1]), pt(v[2], v[3]), pt(v[4], v[5])
+				b.curveTo(c1, c2, p)
+				ctrl, cur = c2, p
+			}
+		case 'S', 's':
+			if v, err = vals(4); err == nil {
+				c1 := reflect('C', 'S')
+				c2, p := pt(v[0], v[1]), pt(v[2], v[3])
+				b.curveTo(c1, c2, p)
+				ctrl, cur = c2, p
+			}
+		case 'Q', 'q':
+			if v, err = vals(4); err == nil {
+				c, p := pt(v[0], v[1]), pt(v[2], v[3])
+				b.curveTo(c, p)
+				ctrl, cur = c, p
+			}
+		case 'T', 't':
+			if v, err = vals(2); err == nil {
+				c := reflect('Q', 'T')
+				p := pt(v[0], v[1])
+				b.curveTo(c, p)
+				ctrl, cur = c, p
+			}
+		case 'A', 'a':
+			if v, err = vals(3); err != nil {
+				break
+			}
+			var large, sweep bool
+			if large, err = sc.flag(); err != nil {
+				break
+			}
+			if sweep, err = sc.flag(); err != nil {
+				break
+			}
+			var end []float64
+			if end, err = vals(2); err == nil {
+				p := pt(end[0], end[1])
+				b.arcTo(cur, v[0], v[1], v[2], large, sweep, p)
+				cur = p
+			}
+		case 'Z', 'z':
+			b.close()
+			cur = start
+		default:
+			return fmt.Errorf("image: unsupported path command %q", cmd)
+		}
+		if err != nil {
+			return err
+		}
+		last = cmd
+	}
+}
@@ -0,0 +1,98 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IllegalMoveError is returned by ValidateMove when a move can't be
+// played in a position.  Reason explains why.
+type IllegalMoveError struct {
+	Move   *Move
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *IllegalMoveError) Error() string {
+	return fmt.Sprintf("chess: illegal move %s: %s", e.Move, e.Reason)
+}
+
+// IsLegal returns true if the move can be played in the position.
+func (pos *Position) IsLegal(m *Move) bool {
+	return pos.ValidateMove(m) == nil
+}
+
+// ValidateMove returns an *IllegalMoveError explaining why the move
+// can't be played in the position or nil if it is legal.  It is meant
+// for validating moves decoded from untrusted input, such as UCI moves
+// submitted by a client, since the notations' Decode methods don't
+// validate.  In standard chess only the moved piece is examined so the
+// position's other moves aren't generated.
+func (pos *Position) ValidateMove(m *Move) error {
+	if m == nil {
+		return &IllegalMoveError{Reason: "no move given"}
+	}
+	if m.IsNull() {
+		return &IllegalMoveError{Move: m, Reason: "the null move isn't a legal move"}
+	}
+	if m.S1 < A1 || m.S1 > H8 || m.S2 < A1 || m.S2 > H8 {
+		return &IllegalMoveError{Move: m, Reason: "the move's squares are off the board"}
+	}
+	if pos.validMoves != nil || pos.variant != nil {
+		if moveSlice(pos.calcMoves()).find(m) == nil {
+			return &IllegalMoveError{Move: m, Reason: fmt.Sprintf("the move isn't legal in %s", pos.Variant().Name())}
+		}
+		return nil
+	}
+	if m.drop != NoPieceType {
+		return &IllegalMoveError{Move: m, Reason: "pieces can only be dropped in crazyhouse"}
+	}
+	p := pos.board.Piece(m.S1)
+	switch {
+	case p == NoPiece:
+		return &IllegalMoveError{Move: m, Reason: fmt.Sprintf("there is no piece on %s", m.S1)}
+	case p.Color() != pos.turn:
+		return &IllegalMoveError{Move: m, Reason: fmt.Sprintf("the piece on %s belongs to %s", m.S1, strings.ToLower(p.Color().Name()))}
+	}
+	if p.Type() == King && (m.S1 == E1 || m.S1 == E8) && (m.S2 == m.S1+2 || m.S2 == m.S1-2) {
+		if m.promo != NoPieceType || moveSlice(castleMoves(pos)).find(m) == nil {
+			return &IllegalMoveError{Move: m, Reason: "castling isn't allowed"}
+		}
+		return nil
+	}
+	own := pos.board.whiteSqs
+	if pos.turn == Black {
+		own = pos.board.blackSqs
+	}
+	if bbForPossibleMoves(pos, p.Type(), m.S1)&^own&bbForSquare(m.S2) == 0 {
+		return &IllegalMoveError{Move: m, Reason: fmt.Sprintf("the piece on %s can't move to %s", m.S1, m.S2)}
+	}
+	lastRank := p.Type() == Pawn && (m.S2.Rank() == Rank8 || m.S2.Rank() == Rank1)
+	switch {
+	case lastRank && m.promo == NoPieceType:
+		return &IllegalMoveError{Move: m, Reason: "the pawn must promote"}
+	case lastRank && !m.promo.promotableTo(), !lastRank && m.promo != NoPieceType:
+		return &IllegalMoveError{Move: m, Reason: "the move has an invalid promotion"}
+	}
+	mv := &Move{S1: m.S1, S2: m.S2, promo: m.promo}
+	addTags(mv, pos)
+	if mv.HasTag(inCheck) {
+		return &IllegalMoveError{Move: m, Reason: "the move leaves the king in check"}
+	}
+	return nil
+}
+
+// DecodeUCI decodes the move in UCI notation and validates it in the
+// position.  The move returned is the position's valid move with its
+// tags.  An error is returned if the text isn't a UCI move, and an
+// *IllegalMoveError if the move can't be played.
+func (pos *Position) DecodeUCI(s string) (*Move, error) {
+	m, err := UCINotation{}.Decode(pos, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := pos.ValidateMove(m); err != nil {
+		return nil, err
+	}
+	return moveSlice(pos.calcMoves()).find(m), nil
+}
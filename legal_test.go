@@ -0,0 +1,92 @@
+package chess
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateMoveMatchesValidMoves(t *testing.T) {
+	fens := []string{
+		startFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"4k3/8/8/2KPp2r/8/8/8/8 w - e6 0 1",
+	}
+	promos := []PieceType{NoPieceType, King, Queen, Rook, Bishop, Knight, Pawn}
+	for _, fen := range fens {
+		pos := unsafeFEN(fen)
+		valid := unsafeFEN(fen).ValidMoves()
+		for s1 := A1; s1 <= H8; s1++ {
+			for s2 := A1; s2 <= H8; s2++ {
+				for _, promo := range promos {
+					m := &Move{S1: s1, S2: s2, promo: promo}
+					expected := moveSlice(valid).find(m) != nil
+					if legal := pos.IsLegal(m); legal != expected {
+						t.Fatalf("expected %s to be legal %t in %s but got %t", m, expected, fen, legal)
+					}
+				}
+			}
+		}
+		if pos.validMoves != nil {
+			t.Fatalf("expected validating moves not to generate the moves of %s", fen)
+		}
+	}
+}
+
+func TestValidateMoveReasons(t *testing.T) {
+	tables := []struct {
+		fen    string
+		move   string
+		reason string
+	}{
+		{startFEN, "e3e4", "there is no piece on e3"},
+		{startFEN, "e7e5", "the piece on e7 belongs to black"},
+		{startFEN, "e2e5", "the piece on e2 can't move to e5"},
+		{startFEN, "0000", "the null move isn't a legal move"},
+		{"4k3/8/8/8/8/8/8/R3K2r w Q - 0 1", "e1c1", "castling isn't allowed"},
+		{"4k3/8/8/8/8/8/8/R3K2r w Q - 0 1", "a1a2", "the move leaves the king in check"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8", "the pawn must promote"},
+		{"4k3/8/8/8/8/8/P7/4K3 w - - 0 1", "a2a3q", "the move has an invalid promotion"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := UCINotation{}.Decode(pos, table.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pos.ValidateMove(m)
+		var illegal *IllegalMoveError
+		if !errors.As(err, &illegal) || illegal.Reason != table.reason {
+			t.Fatalf("expected %s in %s to be illegal because %s but got %v", table.move, table.fen, table.reason, err)
+		}
+		if !strings.HasPrefix(err.Error(), "chess: illegal move "+table.move) {
+			t.Fatalf("expected the error to name the move %s but got %s", table.move, err)
+		}
+	}
+	pos, _ := FromVariantFEN(Antichess{}, "rnbqkbnr/p1pppppp/8/1p6/8/4P3/PPPP1PPP/RNBQKBNR w - - 0 2")
+	m, _ := UCINotation{}.Decode(pos, "e3e4")
+	if err := pos.ValidateMove(m); err == nil || !strings.Contains(err.Error(), "Antichess") {
+		t.Fatalf("expected e3e4 to be illegal by the rules of antichess but got %v", err)
+	}
+}
+
+func TestDecodeUCI(t *testing.T) {
+	pos := unsafeFEN("r3k3/8/8/8/8/8/8/R3K2R w KQq - 0 1")
+	m, err := pos.DecodeUCI("e1g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.HasTag(KingSideCastle) {
+		t.Fatalf("expected the decoded move to have the tags of the valid move but got %s", m)
+	}
+	var illegal *IllegalMoveError
+	if _, err := pos.DecodeUCI("e1e3"); !errors.As(err, &illegal) {
+		t.Fatalf("expected an illegal move error but got %v", err)
+	}
+	if _, err := pos.DecodeUCI("e9"); err == nil || errors.As(err, &illegal) {
+		t.Fatalf("expected a notation error but got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package chess
+
+import "fmt"
+
+// Sliding piece attacks are looked up with magic bitboards.  The
+// occupancy of the squares relevant to a square is multiplied by the
+// square's magic number and the top bits of the product index a table
+// of precomputed attacks.  The magics were found by a random search for
+// this package's bit order where A1 is the most significant bit.
+
+var rookMagics = [64]uint64{
+	0x1400002413410182, 0x8c03000442000081, 0x8001001806040003, 0x0492002010890402,
+	0x0244082005001001, 0x4022081043200103, 0x0406002880401102, 0x0000102040810202,
+	0x3068288044111a00, 0xc210180902300c00, 0x1c00800400020080, 0x1008000804008180,
+	0x0202800806100080, 0x2004200080100880, 0x0802004681310200, 0x0040400080082880,
+	0x200020804102000c, 0x300031100a0c0008, 0x4010040002008080, 0x070c080004008080,
+	0x0082100101250008, 0x81a0004021010010, 0x0041008200420020, 0x4000804000208009,
+	0x0004006c02000089, 0x0022010844000210, 0x0000402008010410, 0x4008020040400400,
+	0x4000800804801000, 0x0080806002805001, 0x0000402000401000, 0x2040008248800420,
+	0x8e04112200008044, 0x4010080400020110, 0x4002000280040080, 0x4000040180080180,
+	0x0006080080100280, 0xa088110100402000, 0x1000400040201000, 0x0020248680084001,
+	0x0200020002618405, 0x0284040001081002, 0x0102010100040008, 0x0210808008000400,
+	0x2088018028300080, 0x8000110020004100, 0x0040050021088040, 0x8180004000402000,
+	0x0201000980450012, 0x2011002d00044200, 0x8002808004000200, 0x6000800800040080,
+	0x0000801000080080, 0x0006002202804010, 0x0802404000201000, 0x4011800080400021,
+	0x220000820425004c, 0x0400080100842210, 0x1200900200080400, 0x0200080502001020,
+	0x0100081001000620, 0x0100081100200040, 0x114000c090002004, 0x3080002e10400080,
+}
+
+var bishopMagics = [64]uint64{
+	0x90d0041008420820, 0x1050410893042289, 0x0050002004418200, 0x0000000012020200,
+	0x0440400000840409, 0x0428021100411044, 0x20002114008c4400, 0x4042410808124202,
+	0x1008500100411000, 0x0040950800990448, 0x1202112029090110, 0x0100181102020021,
+	0x8400040041109138, 0x1000082221100401, 0x0820411888201400, 0x8c0c030813900810,
+	0x000c210612028024, 0x106484084a000040, 0x0814011011002208, 0x0101200410110100,
+	0x020000a018020108, 0x4b88201410000200, 0x80c1010110002040, 0x2081044241002000,
+	0x0001020480102420, 0x0001020400c0840c, 0x0810008021d20200, 0x1002120400020082,
+	0x0001100820040400, 0x80a0209001080028, 0x4824010910141048, 0x2210280400281080,
+	0x0824030802804101, 0x1141120001009082, 0x0001020401005102, 0x0c00840110802000,
+	0x1010040000440008, 0x6202010208280020, 0x0081041010042800, 0x0c82a04048481000,
+	0x0000820a20982804, 0x040204204202a060, 0x3300202410041000, 0x0004000600a20a45,
+	0x00c0804802064000, 0x0008011088001420, 0x10200202680200a0, 0x40c0108802848408,
+	0x0c88108229012000, 0x81218c2088080804, 0x0824308220200050, 0x0024540421080000,
+	0x8400282053c82104, 0x1202080801003005, 0x51a0821081110108, 0xa200080890442040,
+	0x0092062084046008, 0x00260222a0240000, 0x6012021044800400, 0x0821104000012042,
+	0x0642408500542810, 0xc148082040900080, 0x93502108049188c0, 0x0a02100409040124,
+}
+
+// magic is the attack lookup for a sliding piece on one square.
+type magic struct {
+	mask    bitboard
+	magic   uint64
+	shift   uint
+	attacks []bitboard
+}
+
+func (m *magic) index(occupied bitboard) uint64 {
+	return (uint64(occupied&m.mask) * m.magic) >> m.shift
+}
+
+var (
+	rookTable   [64]magic
+	bishopTable [64]magic
+)
+
+func initMagics() {
+	for sq := 0; sq < numOfSquaresInBoard; sq++ {
+		initMagic(&rookTable[sq], Square(sq), rookMagics[sq], hvRayAttack)
+		initMagic(&bishopTable[sq], Square(sq), bishopMagics[sq], diaRayAttack)
+	}
+}
+
+// initMagic fills the attack table for every occupancy of the relevant
+// squares, which are the squares the piece slides over excluding the
+// edges of the board unless the piece is on them.  Two occupancies may
+// share an entry only if they have the same attacks, so initMagic
+// panics if the magic number maps occupancies with different attacks
+// to the same entry.
+func initMagic(m *magic, sq Square, magicNum uint64, attack func(bitboard, Square) bitboard) {
+	edges := ((bbRank1 | bbRank8) & ^bbRanks[sq.Rank()]) | ((bbFileA | bbFileH) & ^bbFiles[sq.File()])
+	m.mask = attack(0, sq) & ^edges
+	m.magic = magicNum
+	m.shift = uint(64 - m.mask.count())
+	m.attacks = make([]bitboard, 1<<uint(m.mask.count()))
+	filled := make([]bool, len(m.attacks))
+	// enumerate the subsets of the mask with the carry rippler trick
+	var occupied bitboard
+	for {
+		i, attacks := m.index(occupied), attack(occupied, sq)
+		if filled[i] && m.attacks[i] != attacks {
+			panic(fmt.Sprintf("chess: magic number %#x for %s maps occupancies with different attacks to entry %d", magicNum, sq, i))
+		}
+		m.attacks[i], filled[i] = attacks, true
+		occupied = (occupied - m.mask) & m.mask
+		if occupied == 0 {
+			break
+		}
+	}
+}
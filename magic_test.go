@@ -0,0 +1,44 @@
+package chess
+
+import (
+	"testing"
+)
+
+func TestMagicAttacks(t *testing.T) {
+	tables := []struct {
+		name     string
+		table    *[64]magic
+		lookup   func(bitboard, Square) bitboard
+		expected func(bitboard, Square) bitboard
+	}{
+		{"rook", &rookTable, hvAttack, hvRayAttack},
+		{"bishop", &bishopTable, diaAttack, diaRayAttack},
+	}
+	for _, table := range tables {
+		for sq := Square(0); sq < numOfSquaresInBoard; sq++ {
+			mask := table.table[sq].mask
+			// every subset of the relevant squares, found with the carry
+			// rippler trick
+			var occupied bitboard
+			for {
+				if actual, expected := table.lookup(occupied, sq), table.expected(occupied, sq); actual != expected {
+					t.Fatalf("expected %s attacks from %s with occupancy %s to be %s but got %s", table.name, sq, occupied, expected, actual)
+				}
+				occupied = (occupied - mask) & mask
+				if occupied == 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestInitMagicCollision(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a magic number mapping different attacks to one entry to panic")
+		}
+	}()
+	var m magic
+	initMagic(&m, D4, 1, hvRayAttack)
+}
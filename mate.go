@@ -0,0 +1,67 @@
+package chess
+
+// FindMate searches for a forced mate of at most maxDepth moves by the
+// side to move and returns the principal line of the shortest one.  The
+// line alternates the mating side's moves with the replies delaying mate
+// the longest, so it ends in checkmate and has 2n-1 moves for a mate in
+// n.  False is returned if there is no forced mate within maxDepth.  The
+// search is exhaustive and meant for puzzles with short mates rather
+// than for deep searches.
+func FindMate(pos *Position, maxDepth int) ([]*Move, bool) {
+	return pos.Clone().shortestMate(maxDepth)
+}
+
+// shortestMate deepens the mate search one move at a time so the first
+// mate found is the shortest.
+func (pos *Position) shortestMate(maxDepth int) ([]*Move, bool) {
+	for n := 1; n <= maxDepth; n++ {
+		if line, ok := pos.mateIn(n); ok {
+			return line, true
+		}
+	}
+	return nil, false
+}
+
+// mateIn returns a line mating in at most n moves.  The moves are played
+// in place on the position and undone.
+func (pos *Position) mateIn(n int) ([]*Move, bool) {
+	for _, m := range pos.ValidMoves() {
+		u := pos.Do(m)
+		line, ok := pos.mateAfter(m, n)
+		pos.Undo(u)
+		if ok {
+			return line, true
+		}
+	}
+	return nil, false
+}
+
+// mateAfter returns the line starting with m if every reply to it can be
+// mated in fewer than n moves.
+func (pos *Position) mateAfter(m *Move, n int) ([]*Move, bool) {
+	mv := *m
+	switch pos.Status() {
+	case Checkmate:
+		return []*Move{&mv}, true
+	case NoMethod:
+	default:
+		return nil, false
+	}
+	if n == 1 {
+		return nil, false
+	}
+	var longest []*Move
+	for _, r := range pos.ValidMoves() {
+		u := pos.Do(r)
+		line, ok := pos.shortestMate(n - 1)
+		pos.Undo(u)
+		if !ok {
+			return nil, false
+		}
+		if len(line)+1 > len(longest) {
+			reply := *r
+			longest = append([]*Move{&reply}, line...)
+		}
+	}
+	return append([]*Move{&mv}, longest...), true
+}
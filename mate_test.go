@@ -0,0 +1,62 @@
+package chess
+
+import (
+	"testing"
+)
+
+func TestFindMate(t *testing.T) {
+	tables := []struct {
+		fen      string
+		maxDepth int
+		first    string
+		length   int
+	}{
+		// back rank mate
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, "a1a8", 1},
+		// the rook sacrifice mates in two whatever black replies
+		{"kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1", 2, "a1a6", 3},
+		// black mates after the fool's mate opening
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", 1, "d8h4", 1},
+	}
+	for _, table := range tables {
+		line, ok := FindMate(unsafeFEN(table.fen), table.maxDepth)
+		if !ok {
+			t.Fatalf("%s expected a mate within %d moves", table.fen, table.maxDepth)
+		}
+		if len(line) != table.length || line[0].String() != table.first {
+			t.Fatalf("%s expected a line of %d moves starting with %s but got %v", table.fen, table.length, table.first, line)
+		}
+		pos := unsafeFEN(table.fen)
+		for _, m := range line {
+			if !pos.IsLegal(m) {
+				t.Fatalf("%s expected the mating line to be legal but %s isn't", table.fen, m)
+			}
+			pos = pos.Update(m)
+		}
+		if pos.Status() != Checkmate {
+			t.Fatalf("%s expected the mating line to end in checkmate but got %s", table.fen, pos.Status())
+		}
+	}
+}
+
+func TestFindMateNone(t *testing.T) {
+	tables := []struct {
+		fen      string
+		maxDepth int
+	}{
+		{startFEN, 2},
+		// the mate in two isn't found with a depth of one
+		{"kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1", 1},
+		// the only checkmating try is a stalemate
+		{"k7/8/1Q6/8/8/8/8/7K b - - 0 1", 3},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if line, ok := FindMate(pos, table.maxDepth); ok {
+			t.Fatalf("%s expected no mate within %d moves but got %v", table.fen, table.maxDepth, line)
+		}
+		if pos.String() != table.fen {
+			t.Fatalf("expected the position to be unchanged but got %s", pos)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package chess
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // A MoveTag represents a notable consequence of a move.
 type MoveTag uint16
 
@@ -14,22 +19,66 @@ const (
 	EnPassant
 	// Check indicates that the move puts the opposing player in check.
 	Check
+	// DoubleCheck indicates that the move puts the opposing player in
+	// check by two pieces at once so only a king move can answer it.
+	DoubleCheck
 	// inCheck indicates that the move puts the moving player in check and
 	// is therefore invalid.
 	inCheck
 )
 
-// A Move is the movement of a piece from one square to another.
+// A Move is the movement of a piece from one square to another.  In
+// variants with pockets such as Crazyhouse a move may instead drop a
+// piece from the mover's pocket on an empty square in which case S1
+// and S2 are both the square dropped on.
 type Move struct {
 	S1    Square
 	S2    Square
 	promo PieceType
+	drop  PieceType
 	tags  MoveTag
 }
 
+// DropMove returns the move dropping a piece of the given type from the
+// mover's pocket on the square.
+func DropMove(pt PieceType, sq Square) *Move {
+	return &Move{S1: sq, S2: sq, drop: pt}
+}
+
+// NewNullMove returns the null move which passes the turn without
+// moving.  It is written as 0000 in UCI notation and Position's Update
+// plays it like Position's NullMove method.
+func NewNullMove() *Move {
+	return &Move{S1: NoSquare, S2: NoSquare}
+}
+
+// castleSide returns the side the move castles on or 0 if it isn't a
+// castle.
+func (m *Move) castleSide() Side {
+	switch {
+	case m.HasTag(KingSideCastle):
+		return KingSide
+	case m.HasTag(QueenSideCastle):
+		return QueenSide
+	}
+	return 0
+}
+
+// IsNull returns true if the move is the null move.
+func (m *Move) IsNull() bool {
+	return m.S1 == NoSquare && m.S2 == NoSquare
+}
+
 // String returns a string useful for debugging.  String doesn't return
-// algebraic notation.
+// algebraic notation.  Drops are written with the piece and an @ sign
+// such as P@e4 and the null move as 0000.
 func (m *Move) String() string {
+	if m.IsNull() {
+		return "0000"
+	}
+	if m.drop != NoPieceType {
+		return strings.ToUpper(m.drop.String()) + "@" + m.S2.String()
+	}
 	return m.S1.String() + m.S2.String() + m.promo.String()
 }
 
@@ -48,6 +97,12 @@ func (m *Move) Promo() PieceType {
 	return m.promo
 }
 
+// Drop returns the type of the piece dropped by the move or NoPieceType
+// if the move isn't a drop.
+func (m *Move) Drop() PieceType {
+	return m.drop
+}
+
 // HasTag returns true if the move contains the MoveTag given.
 func (m *Move) HasTag(tag MoveTag) bool {
 	return (tag & m.tags) > 0
@@ -57,16 +112,60 @@ func (m *Move) addTag(tag MoveTag) {
 	m.tags = m.tags | tag
 }
 
+// MarshalText implements the encoding.TextMarshaler interface and
+// encodes the move in UCI notation.
+func (m *Move) MarshalText() (text []byte, err error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface and
+// decodes a move in UCI notation.  Without a position the move has no
+// tags, so it should be matched against the valid moves or normalized
+// with the position's NormalizeTags method before it is played.
+func (m *Move) UnmarshalText(text []byte) error {
+	mv, err := UCINotation{}.Decode(nil, string(text))
+	if err != nil {
+		return err
+	}
+	*m = *mv
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface and encodes the
+// move in UCI notation as a JSON string.
+func (m *Move) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and decodes a
+// JSON string in UCI notation.
+func (m *Move) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return m.UnmarshalText([]byte(s))
+}
+
 type moveSlice []*Move
 
 func (a moveSlice) find(m *Move) *Move {
 	if m == nil {
 		return nil
 	}
+	// in Chess960 a castle and a king move may share their squares so
+	// the move with the same castling tags is preferred
+	var found *Move
 	for _, move := range a {
-		if move.String() == m.String() {
+		if move.String() != m.String() {
+			continue
+		}
+		if move.castleSide() == m.castleSide() {
 			return move
 		}
+		if found == nil {
+			found = move
+		}
 	}
-	return nil
+	return found
 }
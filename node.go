@@ -0,0 +1,348 @@
+package chess
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Node is a move in the game tree along with the position it
+// resulted in.  The root node of a game has no move and holds the
+// starting position.  The first child of a node is its main line
+// continuation and any other children are alternative variations.
+type Node struct {
+	parent   *Node
+	children []*Node
+	move     *Move
+	pos      *Position
+	comment  string
+	nags     []int
+}
+
+// SingularMoveNAG is the numeric annotation glyph ($8) marking a move
+// as the only reasonable move.  It is used to mark forced moves.
+const SingularMoveNAG = 8
+
+// Parent returns the node that the node's move was played from
+// or nil for the root node.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the continuations of the node starting with
+// the main line followed by the variations.
+func (n *Node) Children() []*Node {
+	return append([]*Node(nil), n.children...)
+}
+
+// MainLine returns the main line continuation of the node
+// or nil if there is none.
+func (n *Node) MainLine() *Node {
+	if len(n.children) == 0 {
+		return nil
+	}
+	return n.children[0]
+}
+
+// Variations returns the continuations of the node that
+// aren't the main line.
+func (n *Node) Variations() []*Node {
+	if len(n.children) < 2 {
+		return []*Node{}
+	}
+	return append([]*Node(nil), n.children[1:]...)
+}
+
+// Move returns the move that led to the node or nil for the root node.
+func (n *Node) Move() *Move {
+	return n.move
+}
+
+// Position returns the position after the node's move.
+func (n *Node) Position() *Position {
+	return n.pos
+}
+
+// Comment returns the comment attached to the node.
+func (n *Node) Comment() string {
+	return n.comment
+}
+
+// SetComment sets the comment attached to the node.
+func (n *Node) SetComment(comment string) {
+	n.comment = comment
+}
+
+// NAGs returns the numeric annotation glyphs attached to the node
+// in the order they were added.
+func (n *Node) NAGs() []int {
+	return append([]int(nil), n.nags...)
+}
+
+// AddNAG attaches the numeric annotation glyph to the node.  A glyph
+// that is already attached isn't added again.
+func (n *Node) AddNAG(nag int) {
+	for _, existing := range n.nags {
+		if existing == nag {
+			return
+		}
+	}
+	n.nags = append(n.nags, nag)
+}
+
+// RemoveNAG removes the numeric annotation glyph from the node.
+func (n *Node) RemoveNAG(nag int) {
+	nags := []int{}
+	for _, existing := range n.nags {
+		if existing != nag {
+			nags = append(nags, existing)
+		}
+	}
+	n.nags = nags
+}
+
+// MoveAnnotation is a move suffix annotation such as ! or ?!.  It is
+// kept on a node as the numeric annotation glyph of the same value.
+type MoveAnnotation int
+
+const (
+	// NoAnnotation is a move without a suffix annotation.
+	NoAnnotation MoveAnnotation = iota
+	// GoodMove is annotated with !
+	GoodMove
+	// Mistake is annotated with ?
+	Mistake
+	// BrilliantMove is annotated with !!
+	BrilliantMove
+	// Blunder is annotated with ??
+	Blunder
+	// SpeculativeMove is annotated with !?
+	SpeculativeMove
+	// DubiousMove is annotated with ?!
+	DubiousMove
+)
+
+var annotationSuffixes = [...]string{"", "!", "?", "!!", "??", "!?", "?!"}
+
+// String returns the suffix of the annotation.
+func (a MoveAnnotation) String() string {
+	if a < NoAnnotation || int(a) >= len(annotationSuffixes) {
+		return ""
+	}
+	return annotationSuffixes[a]
+}
+
+// Annotation returns the move suffix annotation of the node, which is
+// the first glyph from $1 to $6 attached to it.
+func (n *Node) Annotation() MoveAnnotation {
+	for _, nag := range n.nags {
+		if a := MoveAnnotation(nag); a > NoAnnotation && a <= DubiousMove {
+			return a
+		}
+	}
+	return NoAnnotation
+}
+
+// SetAnnotation replaces the move suffix annotation of the node.
+// NoAnnotation removes it.
+func (n *Node) SetAnnotation(a MoveAnnotation) {
+	nags := []int{}
+	for _, nag := range n.nags {
+		if nag < int(GoodMove) || nag > int(DubiousMove) {
+			nags = append(nags, nag)
+		}
+	}
+	n.nags = nags
+	if a != NoAnnotation {
+		n.nags = append([]int{int(a)}, n.nags...)
+	}
+}
+
+// Evaluation is an engine evaluation from white's perspective.  Mate
+// is the number of moves until mate, negative if black is mating, and
+// zero if no mate was found in which case Centipawns is the evaluation.
+type Evaluation struct {
+	Centipawns int
+	Mate       int
+}
+
+// String returns the evaluation as written in a PGN %eval command such
+// as 0.25 or #-3.
+func (e Evaluation) String() string {
+	if e.Mate != 0 {
+		return fmt.Sprintf("#%d", e.Mate)
+	}
+	return fmt.Sprintf("%.2f", float64(e.Centipawns)/100)
+}
+
+var (
+	evalCommandRegex  = regexp.MustCompile(`\s*\[%eval\s+([^\]\s]*)[^\]]*\]`)
+	clockCommandRegex = regexp.MustCompile(`\s*\[%clk\s+([^\]\s]*)[^\]]*\]`)
+)
+
+// Eval returns the evaluation stored in the node's comment as a
+// [%eval ...] command like the ones exported by lichess.  False is
+// returned if the comment has no valid evaluation.
+func (n *Node) Eval() (Evaluation, bool) {
+	arg, ok := n.command(evalCommandRegex)
+	if !ok {
+		return Evaluation{}, false
+	}
+	// some tools append the search depth such as 0.17,23
+	if i := strings.Index(arg, ","); i != -1 {
+		arg = arg[:i]
+	}
+	if strings.HasPrefix(arg, "#") {
+		mate, err := strconv.Atoi(arg[1:])
+		if err != nil || mate == 0 {
+			return Evaluation{}, false
+		}
+		return Evaluation{Mate: mate}, true
+	}
+	pawns, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return Evaluation{}, false
+	}
+	return Evaluation{Centipawns: int(math.Round(pawns * 100))}, true
+}
+
+// SetEval stores the evaluation in the node's comment replacing any
+// evaluation it already has.
+func (n *Node) SetEval(e Evaluation) {
+	n.setCommand(evalCommandRegex, "[%eval "+e.String()+"]")
+}
+
+// RemoveEval removes the evaluation from the node's comment.
+func (n *Node) RemoveEval() {
+	n.comment = strings.TrimSpace(evalCommandRegex.ReplaceAllString(n.comment, ""))
+}
+
+// Clock returns the mover's remaining time after the node's move
+// stored in the node's comment as a [%clk h:mm:ss] command.  False is
+// returned if the comment has no valid clock time.
+func (n *Node) Clock() (time.Duration, bool) {
+	arg, ok := n.command(clockCommandRegex)
+	if !ok {
+		return 0, false
+	}
+	parts := strings.Split(arg, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0.0
+	for i, part := range parts {
+		// only the seconds may have a fraction
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 || (i < len(parts)-1 && strings.Contains(part, ".")) {
+			return 0, false
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond, true
+}
+
+// SetClock stores the mover's remaining time in the node's comment as
+// a [%clk h:mm:ss] command replacing any clock time it already has.
+// Fractions of a second are kept to a tenth and otherwise left out.
+func (n *Node) SetClock(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second / 10)
+	s := fmt.Sprintf("%d:%02d:%02d", int(d/time.Hour), int(d/time.Minute)%60, int(d/time.Second)%60)
+	if tenths := int(d/(time.Second/10)) % 10; tenths != 0 {
+		s += fmt.Sprintf(".%d", tenths)
+	}
+	n.setCommand(clockCommandRegex, "[%clk "+s+"]")
+}
+
+// RemoveClock removes the clock time from the node's comment.
+func (n *Node) RemoveClock() {
+	n.comment = strings.TrimSpace(clockCommandRegex.ReplaceAllString(n.comment, ""))
+}
+
+// command returns the argument of the first command in the node's
+// comment matched by the regex.
+func (n *Node) command(re *regexp.Regexp) (string, bool) {
+	match := re.FindStringSubmatch(n.comment)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// setCommand removes the commands matched by the regex from the node's
+// comment and puts the command in front of it.
+func (n *Node) setCommand(re *regexp.Regexp, cmd string) {
+	n.comment = strings.TrimSpace(re.ReplaceAllString(n.comment, ""))
+	if n.comment == "" {
+		n.comment = cmd
+		return
+	}
+	n.comment = cmd + " " + n.comment
+}
+
+// AddVariation adds the move as a continuation of the node and returns
+// the resulting node.  If the node has no continuation yet the move
+// becomes its main line.  If the move is already a continuation then
+// the existing node is returned.  An error is returned if the move
+// is invalid in the node's position.
+func (n *Node) AddVariation(m *Move) (*Node, error) {
+	valid := moveSlice(n.pos.ValidMoves()).find(m)
+	if valid == nil {
+		return nil, fmt.Errorf("chess: invalid move %s", m)
+	}
+	if child := n.child(valid); child != nil {
+		return child, nil
+	}
+	child := &Node{parent: n, move: valid, pos: n.pos.Update(valid)}
+	n.children = append(n.children, child)
+	return child, nil
+}
+
+// addMainLine makes the already validated move the main line
+// continuation of the node and returns the resulting node.
+func (n *Node) addMainLine(m *Move, pos *Position) *Node {
+	child := n.child(m)
+	cp := []*Node{}
+	for _, c := range n.children {
+		if c != child {
+			cp = append(cp, c)
+		}
+	}
+	if child == nil {
+		child = &Node{parent: n, move: m}
+	}
+	child.pos = pos
+	n.children = append([]*Node{child}, cp...)
+	return child
+}
+
+func (n *Node) child(m *Move) *Node {
+	for _, c := range n.children {
+		if c.move.String() == m.String() {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *Node) addComment(comment string) {
+	if n.comment != "" {
+		n.comment += " "
+	}
+	n.comment += comment
+}
+
+func (n *Node) copyTree(parent *Node) *Node {
+	// positions are copied since they cache their moves when read
+	pos := *n.pos
+	cp := &Node{parent: parent, move: n.move, pos: &pos, comment: n.comment, nags: n.NAGs()}
+	for _, c := range n.children {
+		cp.children = append(cp.children, c.copyTree(cp))
+	}
+	return cp
+}
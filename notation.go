@@ -2,6 +2,7 @@ package chess
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -29,7 +30,10 @@ type Notation interface {
 
 // UCINotation is a more computer friendly alternative to algebraic
 // notation.  This notation uses the same format as the UCI (Universal Chess
-// Interface).  Examples: e2e4, e7e5, e1g1 (white short castling), e7e8q (for promotion)
+// Interface).  Examples: e2e4, e7e5, e1g1 (white short castling), e7e8q (for promotion),
+// 0000 (null move).  Castling in Chess960 is written as the king capturing
+// its own rook such as e1h1 for white's short castling from the standard
+// starting position.
 type UCINotation struct{}
 
 // String implements the fmt.Stringer interface and returns
@@ -38,9 +42,17 @@ func (UCINotation) String() string {
 	return "UCI Notation"
 }
 
-// Encode implements the Encoder interface.
+// Encode implements the Encoder interface.  The null move is encoded
+// as 0000.
 func (UCINotation) Encode(pos *Position, m *Move) string {
-	return m.GetS1().String() + m.GetS2().String() + m.Promo().String()
+	if m.drop != NoPieceType || m.IsNull() {
+		return m.String()
+	}
+	s2 := m.S2
+	if pos != nil {
+		s2 = pos.castleTarget(m)
+	}
+	return m.GetS1().String() + s2.String() + m.Promo().String()
 }
 
 // Decode implements the Decoder interface.
@@ -50,6 +62,18 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 	if l < 4 || l > 5 {
 		return nil, err
 	}
+	if s == "0000" {
+		return NewNullMove(), nil
+	}
+	// drops are written with the piece and an @ sign such as P@e4
+	if l == 4 && s[1] == '@' {
+		pt, ok := dropPieceTypeFromChar(s[0:1])
+		sq, sqOK := strToSquareMap[s[2:4]]
+		if !ok || !sqOK {
+			return nil, err
+		}
+		return DropMove(pt, sq), nil
+	}
 	S1, ok := strToSquareMap[s[0:2]]
 	if !ok {
 		return nil, err
@@ -61,6 +85,9 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 	promo := NoPieceType
 	if l == 5 {
 		promo = pieceTypeFromChar(s[4:5])
+		if promo == NoPieceType && strings.ToLower(s[4:5]) == "k" && promotesToKing(pos) {
+			promo = King
+		}
 		if promo == NoPieceType {
 			return nil, err
 		}
@@ -70,7 +97,11 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 		return m, nil
 	}
 	p := pos.Board().Piece(S1)
-	if p.Type() == King {
+	if p.Type() == King && pos.isChess960() {
+		if castle := pos.kingTakesRook(S1, S2); castle != nil {
+			return castle, nil
+		}
+	} else if p.Type() == King {
 		if (S1 == E1 && S2 == G1) || (S1 == E8 && S2 == G8) {
 			m.addTag(KingSideCastle)
 		} else if (S1 == E1 && S2 == C1) || (S1 == E8 && S2 == C8) {
@@ -88,6 +119,171 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 	return m, nil
 }
 
+// SmithNotation is a coordinate notation that extends UCI notation
+// with the captured piece and special move indicators.  The captured
+// piece is appended in lower case, followed by c for short castling,
+// C for long castling, E for en passant or the upper case promotion
+// piece.  Examples: e2e4, e1g1c (white short castling), d5e6pE (en
+// passant), b7a8rQ (capturing a rook and promoting to a queen)
+type SmithNotation struct{}
+
+// String implements the fmt.Stringer interface and returns
+// the notation's name.
+func (SmithNotation) String() string {
+	return "Smith Notation"
+}
+
+// Encode implements the Encoder interface.
+func (SmithNotation) Encode(pos *Position, m *Move) string {
+	s := m.S1.String() + m.S2.String()
+	if m.HasTag(EnPassant) {
+		return s + "pE"
+	}
+	if m.HasTag(Capture) {
+		s += smithCapturedChar(pos.Board().Piece(m.S2).Type())
+	}
+	switch {
+	case m.HasTag(KingSideCastle):
+		s += "c"
+	case m.HasTag(QueenSideCastle):
+		s += "C"
+	case m.promo != NoPieceType:
+		s += charFromPieceType(m.promo)
+	}
+	return s
+}
+
+// Decode implements the Decoder interface.  The en passant indicator
+// is also accepted as EP.
+func (SmithNotation) Decode(pos *Position, s string) (*Move, error) {
+	err := fmt.Errorf(`chess: failed to decode smith notation text "%s" for position %s`, s, pos)
+	if len(s) < 4 {
+		return nil, err
+	}
+	S1, ok := strToSquareMap[s[0:2]]
+	if !ok {
+		return nil, err
+	}
+	S2, ok := strToSquareMap[s[2:4]]
+	if !ok {
+		return nil, err
+	}
+	m := &Move{S1: S1, S2: S2}
+	rest := s[4:]
+	captured := NoPieceType
+	if len(rest) > 0 && strings.Contains("pnbrqk", rest[0:1]) {
+		captured = smithCapturedPieceType(rest[0:1])
+		m.addTag(Capture)
+		rest = rest[1:]
+	}
+	switch rest {
+	case "":
+	case "c":
+		m.addTag(KingSideCastle)
+	case "C":
+		m.addTag(QueenSideCastle)
+	case "E", "EP":
+		if captured != Pawn {
+			return nil, err
+		}
+		m.addTag(EnPassant)
+	default:
+		m.promo = pieceTypeFromChar(rest)
+		if len(rest) != 1 || m.promo == NoPieceType {
+			return nil, err
+		}
+	}
+	if pos == nil {
+		return m, nil
+	}
+	// the captured piece must match the board
+	if !m.HasTag(EnPassant) && pos.Board().Piece(S2).Type() != captured {
+		return nil, err
+	}
+	return m, nil
+}
+
+func smithCapturedChar(p PieceType) string {
+	if p == Pawn {
+		return "p"
+	}
+	return strings.ToLower(charFromPieceType(p))
+}
+
+func smithCapturedPieceType(c string) PieceType {
+	switch c {
+	case "k":
+		return King
+	case "p":
+		return Pawn
+	}
+	return pieceTypeFromChar(c)
+}
+
+// ICCFNotation is the numeric notation used in correspondence chess
+// by the ICCF.  Files and ranks are both written as digits from 1 to
+// 8 and promotions append 1 for a queen, 2 for a rook, 3 for a bishop
+// or 4 for a knight.  Examples: 5254 (e2e4), 5171 (white short
+// castling), 27281 (b7b8=Q)
+type ICCFNotation struct{}
+
+// String implements the fmt.Stringer interface and returns
+// the notation's name.
+func (ICCFNotation) String() string {
+	return "ICCF Notation"
+}
+
+// Encode implements the Encoder interface.
+func (ICCFNotation) Encode(pos *Position, m *Move) string {
+	s := iccfSquare(m.S1) + iccfSquare(m.S2)
+	switch m.promo {
+	case Queen:
+		s += "1"
+	case Rook:
+		s += "2"
+	case Bishop:
+		s += "3"
+	case Knight:
+		s += "4"
+	}
+	return s
+}
+
+// Decode implements the Decoder interface.
+func (ICCFNotation) Decode(pos *Position, s string) (*Move, error) {
+	err := fmt.Errorf(`chess: failed to decode iccf notation text "%s" for position %s`, s, pos)
+	if len(s) < 4 || len(s) > 5 {
+		return nil, err
+	}
+	uci := ""
+	for i := 0; i < 4; i++ {
+		if s[i] < '1' || s[i] > '8' {
+			return nil, err
+		}
+		if i%2 == 0 {
+			uci += File(s[i] - '1').String()
+		} else {
+			uci += s[i : i+1]
+		}
+	}
+	if len(s) == 5 {
+		i := strings.IndexByte("1234", s[4])
+		if i == -1 {
+			return nil, err
+		}
+		uci += "qrbn"[i : i+1]
+	}
+	m, decodeErr := UCINotation{}.Decode(pos, uci)
+	if decodeErr != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func iccfSquare(sq Square) string {
+	return fmt.Sprintf("%d%d", sq.File()+1, sq.Rank()+1)
+}
+
 // AlgebraicNotation (or Standard Algebraic Notation) is the
 // official chess notation used by FIDE. Examples: e4, e5,
 // O-O (short castling), e8=Q (promotion)
@@ -101,37 +297,187 @@ func (AlgebraicNotation) String() string {
 
 // Encode implements the Encoder interface.
 func (AlgebraicNotation) Encode(pos *Position, m *Move) string {
-	checkChar := getCheckChar(pos, m)
+	return algebraicText(pos, m) + getCheckChar(pos, m)
+}
+
+var (
+	sanRegex     = regexp.MustCompile(`^([NBRQK]?)([a-h]?)([1-8]?)(x?)([a-h][1-8])(=[NBRQK])?$`)
+	sanDropRegex = regexp.MustCompile(`^([PNBRQ]?)@([a-h][1-8])$`)
+)
+
+// Decode implements the Decoder interface.  The text is parsed into
+// the piece, disambiguation, destination and promotion which are
+// matched against the valid moves.  The text must be in the exact
+// form produced by Encode apart from the variants accepted by
+// normalizeSAN and the check, mate and annotation suffixes.
+func (AlgebraicNotation) Decode(pos *Position, s string) (*Move, error) {
+	s = normalizeSAN(removeSubstrings(s, "?", "!", "+", "#", "e.p."))
+	// the error is only formatted on failure since encoding the
+	// position is slower than decoding the move
+	err := func() error {
+		return fmt.Errorf("chess: could not decode algebraic notation %s for position %s", s, pos.String())
+	}
+	var tag MoveTag
+	switch s {
+	case "O-O":
+		tag = KingSideCastle
+	case "O-O-O":
+		tag = QueenSideCastle
+	}
+	if drop := sanDropRegex.FindStringSubmatch(s); drop != nil {
+		// a pawn drop may leave out the piece
+		pt, _ := dropPieceTypeFromChar(drop[1])
+		for _, m := range pos.ValidMoves() {
+			if m.drop == pt && m.S2.String() == drop[2] {
+				return m, nil
+			}
+		}
+		return nil, err()
+	}
+	match := sanRegex.FindStringSubmatch(s)
+	if tag == 0 && match == nil {
+		return nil, err()
+	}
+	var found *Move
+	for _, m := range pos.ValidMoves() {
+		if tag != 0 {
+			if m.HasTag(tag) {
+				return m, nil
+			}
+			continue
+		}
+		p := pos.Board().Piece(m.S1)
+		switch {
+		case m.drop != NoPieceType, m.castleSide() != 0,
+			charFromPieceType(p.Type()) != match[1] && (p.Type() != Pawn || match[1] != ""),
+			m.S2.String() != match[5],
+			charForPromo(m.promo) != match[6],
+			match[2] != "" && m.S1.File().String() != match[2],
+			match[3] != "" && m.S1.Rank().String() != match[3]:
+			continue
+		}
+		if found != nil {
+			return nil, err()
+		}
+		found = m
+	}
+	// the disambiguation and capture must be exactly as encoded
+	if found == nil || algebraicText(pos, found) != s {
+		return nil, err()
+	}
+	return found, nil
+}
+
+// algebraicText returns the move in algebraic notation without the
+// check or mate indicator.
+func algebraicText(pos *Position, m *Move) string {
+	if m.drop != NoPieceType {
+		return m.String()
+	}
 	if m.HasTag(KingSideCastle) {
-		return "O-O" + checkChar
+		return "O-O"
 	} else if m.HasTag(QueenSideCastle) {
-		return "O-O-O" + checkChar
+		return "O-O-O"
 	}
 	p := pos.Board().Piece(m.GetS1())
 	pChar := charFromPieceType(p.Type())
 	S1Str := formS1(pos, m)
 	capChar := ""
-	if m.HasTag(Capture) || m.HasTag(EnPassant) {
+	if m.HasTag(Capture) || m.HasTag(EnPassant) || (p.Type() == Pawn && S1Str != "") {
 		capChar = "x"
-		if p.Type() == Pawn && S1Str == "" {
-			capChar = m.S1.File().String() + "x"
-		}
 	}
-	promoText := charForPromo(m.promo)
-	return pChar + S1Str + capChar + m.S2.String() + promoText + checkChar
+	return pChar + S1Str + capChar + m.S2.String() + charForPromo(m.promo)
 }
 
-// Decode implements the Decoder interface.
-func (AlgebraicNotation) Decode(pos *Position, s string) (*Move, error) {
-	s = removeSubstrings(s, "?", "!", "+", "#", "e.p.")
+// RelaxedAlgebraicNotation encodes moves like AlgebraicNotation but
+// decodes the sloppy algebraic notation common in real world PGN files.
+// Annotations, check and mate indicators, missing or extra capture
+// indicators, unneeded disambiguation, lower case piece letters,
+// castling with zeros and promotions without an equals sign are all
+// accepted.  Examples: Nf3!?, 0-0, nxe5, Ngf3, e8Q
+type RelaxedAlgebraicNotation struct{}
+
+// String implements the fmt.Stringer interface and returns
+// the notation's name.
+func (RelaxedAlgebraicNotation) String() string {
+	return "Relaxed Algebraic Notation"
+}
+
+// Encode implements the Encoder interface.
+func (RelaxedAlgebraicNotation) Encode(pos *Position, m *Move) string {
+	return AlgebraicNotation{}.Encode(pos, m)
+}
+
+var sanRelaxedRegex = regexp.MustCompile(`^([NBRQKnrqk]?)([a-h]?)([1-8]?)[x:-]?([a-h][1-8])=?([NBRQnbrq]?)$`)
+
+// Decode implements the Decoder interface.  An error is returned if
+// the text doesn't match exactly one valid move.  A lower case b is
+// read as a pawn on the b file unless only a bishop move fits.
+func (RelaxedAlgebraicNotation) Decode(pos *Position, s string) (*Move, error) {
+	if m, err := (AlgebraicNotation{}).Decode(pos, s); err == nil {
+		return m, nil
+	}
+	err := fmt.Errorf("chess: could not decode relaxed algebraic notation %s for position %s", s, pos)
+	s = removeSubstrings(s, "?", "!", "+", "#", "e.p.", " ")
+	switch strings.ToUpper(strings.Replace(s, "0", "O", -1)) {
+	case "O-O":
+		return relaxedCastle(pos, KingSideCastle, err)
+	case "O-O-O":
+		return relaxedCastle(pos, QueenSideCastle, err)
+	}
+	m := relaxedSANMove(pos, s)
+	if m == nil && strings.HasPrefix(s, "b") {
+		m = relaxedSANMove(pos, "B"+s[1:])
+	}
+	if m == nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func relaxedCastle(pos *Position, tag MoveTag, err error) (*Move, error) {
 	for _, m := range pos.ValidMoves() {
-		str := AlgebraicNotation{}.Encode(pos, m)
-		str = removeSubstrings(str, "?", "!", "+", "#", "e.p.")
-		if str == s {
+		if m.HasTag(tag) {
 			return m, nil
 		}
 	}
-	return nil, fmt.Errorf("chess: could not decode algebraic notation %s for position %s", s, pos.String())
+	return nil, err
+}
+
+// relaxedSANMove returns the only valid move fitting the text or nil.
+func relaxedSANMove(pos *Position, s string) *Move {
+	match := sanRelaxedRegex.FindStringSubmatch(s)
+	if match == nil {
+		return nil
+	}
+	pt := Pawn
+	if match[1] != "" {
+		pt = pieceTypeFromChar(match[1])
+		if strings.ToUpper(match[1]) == "K" {
+			pt = King
+		}
+	}
+	promo := pieceTypeFromChar(match[5])
+	var found *Move
+	for _, m := range pos.ValidMoves() {
+		switch {
+		case pos.Board().Piece(m.S1).Type() != pt,
+			m.S2.String() != match[4],
+			m.promo != promo,
+			match[2] != "" && m.S1.File().String() != match[2],
+			match[3] != "" && m.S1.Rank().String() != match[3]:
+			continue
+		}
+		// a king move is preferred over a Chess960 castle to its square
+		switch {
+		case found == nil, found.castleSide() != 0:
+			found = m
+		case m.castleSide() != 0:
+		default:
+			return nil
+		}
+	}
+	return found
 }
 
 // LongAlgebraicNotation is a fully expanded version of
@@ -149,7 +495,9 @@ func (LongAlgebraicNotation) String() string {
 // Encode implements the Encoder interface.
 func (LongAlgebraicNotation) Encode(pos *Position, m *Move) string {
 	checkChar := getCheckChar(pos, m)
-	if m.HasTag(KingSideCastle) {
+	if m.drop != NoPieceType {
+		return m.String() + checkChar
+	} else if m.HasTag(KingSideCastle) {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
 		return "O-O-O" + checkChar
@@ -182,50 +530,64 @@ func (LongAlgebraicNotation) Decode(pos *Position, s string) (*Move, error) {
 }
 
 func getCheckChar(pos *Position, move *Move) string {
-	if !move.HasTag(Check) {
+	// variants decide themselves what is check and mate
+	if pos.variant != nil {
+		switch nextPos := pos.Update(move); {
+		case nextPos.Status() == Checkmate:
+			return "#"
+		case nextPos.inCheck:
+			return "+"
+		}
+		return ""
+	}
+	if !move.HasTag(Check) && !pos.GivesCheck(move) {
 		return ""
 	}
+	// only a check needs the opponent's moves to tell it from mate
 	nextPos := pos.Update(move)
-	if nextPos.Status() == Checkmate {
+	if !nextPos.HasLegalMoves() {
 		return "#"
 	}
 	return "+"
 }
 
+// formS1 returns the origin square disambiguation of the move following
+// the FIDE rules: pawn captures always give the origin file, otherwise
+// the file is used if it's unique among the pieces of the same type
+// that can legally reach the destination, then the rank and finally
+// both.
 func formS1(pos *Position, m *Move) string {
 	p := pos.board.Piece(m.S1)
 	if p.Type() == Pawn {
+		if m.S1.File() != m.S2.File() {
+			return m.S1.File().String()
+		}
 		return ""
 	}
 
-	var req, fileReq, rankReq bool
-	moves := pos.ValidMoves()
-
-	for _, mv := range moves {
-		if mv.S1 != m.S1 && mv.S2 == m.S2 && p == pos.board.Piece(mv.S1) {
-			req = true
-
-			if mv.S1.File() == m.S1.File() {
-				rankReq = true
-			}
-
-			if mv.S1.Rank() == m.S1.Rank() {
-				fileReq = true
-			}
+	var req, sameFile, sameRank bool
+	for _, mv := range pos.ValidMoves() {
+		if mv.S1 == m.S1 || mv.S2 != m.S2 || pos.board.Piece(mv.S1) != p {
+			continue
+		}
+		req = true
+		if mv.S1.File() == m.S1.File() {
+			sameFile = true
+		}
+		if mv.S1.Rank() == m.S1.Rank() {
+			sameRank = true
 		}
 	}
 
-	var S1 = ""
-
-	if fileReq || !rankReq && req {
-		S1 = m.S1.File().String()
-	}
-
-	if rankReq {
-		S1 += m.S1.Rank().String()
+	switch {
+	case !req:
+		return ""
+	case !sameFile:
+		return m.S1.File().String()
+	case !sameRank:
+		return m.S1.Rank().String()
 	}
-
-	return S1
+	return m.S1.String()
 }
 
 func charForPromo(p PieceType) string {
@@ -252,8 +614,10 @@ func charFromPieceType(p PieceType) string {
 	return ""
 }
 
+// pieceTypeFromChar returns the promotable piece type for the
+// given character.  The character is matched case insensitively.
 func pieceTypeFromChar(c string) PieceType {
-	switch c {
+	switch strings.ToLower(c) {
 	case "q":
 		return Queen
 	case "r":
@@ -266,6 +630,49 @@ func pieceTypeFromChar(c string) PieceType {
 	return NoPieceType
 }
 
+// promotesToKing returns true if a valid move of the position promotes
+// to a king as in Antichess.
+func promotesToKing(pos *Position) bool {
+	if pos == nil || pos.variant == nil {
+		return false
+	}
+	for _, m := range pos.calcMoves() {
+		if m.promo == King {
+			return true
+		}
+	}
+	return false
+}
+
+// dropPieceTypeFromChar returns the type of a dropped piece written as
+// an upper case letter.  An empty string is a pawn.
+func dropPieceTypeFromChar(c string) (PieceType, bool) {
+	switch c {
+	case "", "P":
+		return Pawn, true
+	case "N", "B", "R", "Q":
+		return pieceTypeFromChar(c), true
+	}
+	return NoPieceType, false
+}
+
+var (
+	sanPawnCaptureRegex = regexp.MustCompile(`^([a-h])([a-h][1-8])`)
+	sanPromoRegex       = regexp.MustCompile(`([a-h][18])([QRBNqrbn])$`)
+)
+
+// normalizeSAN rewrites common variants of algebraic notation into the
+// form produced by AlgebraicNotation's Encode method.  Castling written
+// with zeros, pawn captures without an x and promotions without an
+// equals sign are accepted.
+func normalizeSAN(s string) string {
+	s = strings.Replace(s, "0", "O", -1)
+	s = sanPawnCaptureRegex.ReplaceAllString(s, "${1}x$2")
+	return sanPromoRegex.ReplaceAllStringFunc(s, func(str string) string {
+		return str[:2] + "=" + strings.ToUpper(str[2:])
+	})
+}
+
 func removeSubstrings(s string, subs ...string) string {
 	for _, sub := range subs {
 		s = strings.Replace(s, sub, "", -1)
@@ -0,0 +1,279 @@
+package chess
+
+import "testing"
+
+func TestUCINotationPromotion(t *testing.T) {
+	pos := unsafeFEN("8/4P3/8/8/8/8/8/k3K3 w - - 0 1")
+	tables := []struct {
+		s     string
+		promo PieceType
+	}{
+		{"e7e8q", Queen}, {"e7e8Q", Queen},
+		{"e7e8r", Rook}, {"e7e8R", Rook},
+		{"e7e8b", Bishop}, {"e7e8B", Bishop},
+		{"e7e8n", Knight}, {"e7e8N", Knight},
+	}
+	for _, table := range tables {
+		m, err := UCINotation{}.Decode(pos, table.s)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		if m.Promo() != table.promo {
+			t.Fatalf("expected %s to promote to %s but got %s", table.s, table.promo, m.Promo())
+		}
+		expected := "e7e8" + table.promo.String()
+		if s := (UCINotation{}).Encode(pos, m); s != expected {
+			t.Fatalf("expected %s to encode as %s but got %s", table.s, expected, s)
+		}
+	}
+	if _, err := (UCINotation{}).Decode(pos, "e7e8K"); err == nil {
+		t.Fatal("expected king promotion to fail decoding")
+	}
+}
+
+func TestUCINotationNullMove(t *testing.T) {
+	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	m, err := UCINotation{}.Decode(pos, "0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsNull() {
+		t.Fatalf("expected 0000 to decode to the null move but got %s", m)
+	}
+	if s := (UCINotation{}).Encode(pos, m); s != "0000" {
+		t.Fatalf("expected the null move to encode as 0000 but got %s", s)
+	}
+	expected := pos.NullMove().String()
+	if next := pos.Update(m); next.String() != expected {
+		t.Fatalf("expected the null move to result in %s but got %s", expected, next)
+	}
+}
+
+func TestAlgebraicNotationVariants(t *testing.T) {
+	tables := []struct {
+		fen string
+		s   string
+		uci string
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0+", "e1c1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "exd5", "e4d5"},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "ed5", "e4d5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "ed6", "e5d6"},
+		{"8/4P3/8/8/8/8/8/k3K3 w - - 0 1", "e8Q", "e7e8q"},
+		{"8/4P3/8/8/8/8/8/k3K3 w - - 0 1", "e8=N", "e7e8n"},
+		{"8/4P3/8/8/8/8/8/k3K3 w - - 0 1", "e8r", "e7e8r"},
+		{"3r4/4P3/8/8/8/8/8/k3K3 w - - 0 1", "ed8Q+", "e7d8q"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := AlgebraicNotation{}.Decode(pos, table.s)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		if s := (UCINotation{}).Encode(pos, m); s != table.uci {
+			t.Fatalf("expected %s to decode as %s but got %s", table.s, table.uci, s)
+		}
+	}
+	pos := unsafeFEN("4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1")
+	for _, s := range []string{"fd5", "ed4", "e5Q", "0-0"} {
+		if _, err := (AlgebraicNotation{}).Decode(pos, s); err == nil {
+			t.Fatalf("expected %s to fail decoding", s)
+		}
+	}
+}
+
+func TestSmithNotation(t *testing.T) {
+	tables := []struct {
+		fen  string
+		s    string
+		tags []MoveTag
+	}{
+		{startFEN, "e2e4", nil},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5p", []MoveTag{Capture}},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6pE", []MoveTag{EnPassant}},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1c", []MoveTag{KingSideCastle}},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8C", []MoveTag{QueenSideCastle}},
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8rQ", []MoveTag{Capture}},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8N", nil},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := SmithNotation{}.Decode(pos, table.s)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		for _, tag := range table.tags {
+			if !m.HasTag(tag) {
+				t.Fatalf("expected %s to have tag %d", table.s, tag)
+			}
+		}
+		// the decoded move must be one of the valid moves
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			t.Fatalf("expected %s to decode to a valid move", table.s)
+		}
+		if s := (SmithNotation{}).Encode(pos, valid); s != table.s {
+			t.Fatalf("expected %s to encode as %s but got %s", m, table.s, s)
+		}
+	}
+	pos := unsafeFEN("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1")
+	if _, err := (SmithNotation{}).Decode(pos, "e5d6pEP"); err != nil {
+		t.Fatalf("expected EP indicator to decode but got error %s", err)
+	}
+	for _, s := range []string{"e5d6nE", "e1e2p", "e5e6X", "e5"} {
+		if _, err := (SmithNotation{}).Decode(pos, s); err == nil {
+			t.Fatalf("expected %s to fail decoding", s)
+		}
+	}
+}
+
+func TestAlgebraicNotationDisambiguation(t *testing.T) {
+	tables := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		// knights on different files use the file
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "b1d2", "Nbd2"},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "f3d2", "Nfd2"},
+		// rooks on the same file use the rank
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a5a3", "R5a3"},
+		// rooks on the same rank use the file
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "h1d1", "Rhd1"},
+		// three queens reaching e1
+		{"2K5/k7/8/8/4Q2Q/8/8/7Q w - - 0 1", "h4e1", "Qh4e1"},
+		{"2K5/k7/8/8/4Q2Q/8/8/7Q w - - 0 1", "e4e1", "Qee1"},
+		{"2K5/k7/8/8/4Q2Q/8/8/7Q w - - 0 1", "h1e1", "Q1e1"},
+		// a pinned knight doesn't count
+		{"4k3/8/8/7b/8/5N2/8/1N1K4 w - - 0 1", "b1d2", "Nd2"},
+		// pawn captures always include the file
+		{"4k3/8/8/3p4/2P1P3/8/8/4K3 w - - 0 1", "c4d5", "cxd5"},
+		{"4k3/8/8/3p4/2P1P3/8/8/4K3 w - - 0 1", "e4d5", "exd5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"n3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8n", "bxa8=N"},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "e2e4", "e4"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		// a hand built move without tags is encoded the same way
+		m := &Move{S1: strToSquareMap[table.move[0:2]], S2: strToSquareMap[table.move[2:4]]}
+		if len(table.move) == 5 {
+			m.promo = pieceTypeFromChar(table.move[4:5])
+		}
+		if san := (AlgebraicNotation{}).Encode(pos, m); san != table.san {
+			t.Fatalf("%s expected untagged %s to encode as %s but got %s", table.fen, table.move, table.san, san)
+		}
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			t.Fatalf("%s expected %s to be valid", table.fen, table.move)
+		}
+		if san := (AlgebraicNotation{}).Encode(pos, valid); san != table.san {
+			t.Fatalf("%s expected %s to encode as %s but got %s", table.fen, table.move, table.san, san)
+		}
+		decoded, err := AlgebraicNotation{}.Decode(pos, table.san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.String() != valid.String() {
+			t.Fatalf("%s expected %s to decode as %s but got %s", table.fen, table.san, valid, decoded)
+		}
+	}
+}
+
+func TestICCFNotation(t *testing.T) {
+	tables := []struct {
+		fen  string
+		s    string
+		uci  string
+		tags []MoveTag
+	}{
+		{startFEN, "5254", "e2e4", nil},
+		{startFEN, "7163", "g1f3", nil},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "5445", "e4d5", []MoveTag{Capture}},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "5546", "e5d6", []MoveTag{EnPassant}},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "5171", "e1g1", []MoveTag{KingSideCastle}},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "5838", "e8c8", []MoveTag{QueenSideCastle}},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "27281", "b7b8q", nil},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "27284", "b7b8n", nil},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := ICCFNotation{}.Decode(pos, table.s)
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		if m.String() != table.uci {
+			t.Fatalf("expected %s to decode to %s but got %s", table.s, table.uci, m)
+		}
+		for _, tag := range table.tags {
+			if !m.HasTag(tag) {
+				t.Fatalf("expected %s to have tag %d", table.s, tag)
+			}
+		}
+		if s := (ICCFNotation{}).Encode(pos, m); s != table.s {
+			t.Fatalf("expected %s to encode as %s but got %s", m, table.s, s)
+		}
+	}
+	for _, s := range []string{"525", "5294", "0254", "27285", "e2e4", "525411"} {
+		if _, err := (ICCFNotation{}).Decode(nil, s); err == nil {
+			t.Fatalf("expected %s to fail decoding", s)
+		}
+	}
+}
+
+func TestRelaxedAlgebraicNotation(t *testing.T) {
+	tables := []struct {
+		fen string
+		s   string
+		uci string
+	}{
+		{startFEN, "Nf3!?", "g1f3"},
+		{startFEN, "nf3", "g1f3"},
+		{startFEN, "Ngf3", "g1f3"},
+		{startFEN, "Ng1-f3", "g1f3"},
+		{startFEN, "b4", "b2b4"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 2", "Ne5", "f3e5"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 2", "Nxe5+", "f3e5"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 2", "bb5", "f1b5"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 2", "Nc5", ""},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "o-o-o", "e8c8"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8Q", "b7b8q"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=n", "b7b8n"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8", ""},
+		// both rooks can reach d1
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rd1", ""},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "rad1", "a1d1"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := RelaxedAlgebraicNotation{}.Decode(pos, table.s)
+		if table.uci == "" {
+			if err == nil {
+				t.Fatalf("expected %s to fail decoding but got %s", table.s, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected %s to decode but got error %s", table.s, err)
+		}
+		if m.String() != table.uci {
+			t.Fatalf("expected %s to decode to %s but got %s", table.s, table.uci, m)
+		}
+	}
+}
+
+func BenchmarkAlgebraicNotationDecode(b *testing.B) {
+	pos := unsafeFEN("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	for n := 0; n < b.N; n++ {
+		for _, s := range []string{"Ng5", "O-O", "Bxf7+", "d4"} {
+			if _, err := (AlgebraicNotation{}).Decode(pos, s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
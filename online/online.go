@@ -0,0 +1,209 @@
+// Package online is a client for the Lichess tablebase and cloud
+// evaluation APIs for programs that have network access.  The library
+// doesn't probe local Syzygy files, so the tablebase API is the way to
+// look up win, draw or loss and distance to zeroing results.
+package online
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+const (
+	// DefaultTablebaseURL is the Lichess tablebase endpoint for standard chess.
+	DefaultTablebaseURL = "https://tablebase.lichess.ovh/standard"
+	// DefaultCloudEvalURL is the Lichess cloud evaluation endpoint.
+	DefaultCloudEvalURL = "https://lichess.org/api/cloud-eval"
+)
+
+// ErrNotFound is returned when the service has no result for the position.
+var ErrNotFound = errors.New("online: no result for the position")
+
+// Client sends requests to the Lichess APIs.  The zero value uses
+// http.DefaultClient and the default endpoints.
+type Client struct {
+	HTTPClient   *http.Client
+	TablebaseURL string
+	CloudEvalURL string
+}
+
+// Category is the tablebase result of a position from the perspective
+// of the side to move.  Cursed wins and blessed losses are wins and
+// losses that are drawn by the fifty move rule.
+type Category string
+
+// The tablebase categories from best to worst.
+const (
+	Win         Category = "win"
+	CursedWin   Category = "cursed-win"
+	MaybeWin    Category = "maybe-win"
+	Draw        Category = "draw"
+	MaybeLoss   Category = "maybe-loss"
+	BlessedLoss Category = "blessed-loss"
+	Loss        Category = "loss"
+	Unknown     Category = "unknown"
+)
+
+// TablebaseResult is the tablebase entry of a position.  DTZ and DTM
+// are nil if they aren't known.  Moves are sorted from best to worst
+// for the side to move.
+type TablebaseResult struct {
+	Category             Category
+	DTZ                  *int
+	DTM                  *int
+	Checkmate            bool
+	Stalemate            bool
+	InsufficientMaterial bool
+	Moves                []TablebaseMove
+}
+
+// TablebaseMove is a move with the tablebase entry of the resulting
+// position from the perspective of the opponent.
+type TablebaseMove struct {
+	Move     *chess.Move
+	Category Category
+	DTZ      *int
+	DTM      *int
+	Zeroing  bool
+}
+
+type tablebaseJSON struct {
+	Category             Category `json:"category"`
+	DTZ                  *int     `json:"dtz"`
+	DTM                  *int     `json:"dtm"`
+	Checkmate            bool     `json:"checkmate"`
+	Stalemate            bool     `json:"stalemate"`
+	InsufficientMaterial bool     `json:"insufficient_material"`
+	Moves                []struct {
+		UCI      string   `json:"uci"`
+		Category Category `json:"category"`
+		DTZ      *int     `json:"dtz"`
+		DTM      *int     `json:"dtm"`
+		Zeroing  bool     `json:"zeroing"`
+	} `json:"moves"`
+}
+
+// Tablebase looks up the position in the tablebase.  An error is
+// returned if the request fails or the response can't be decoded.
+func (c *Client) Tablebase(ctx context.Context, pos *chess.Position) (*TablebaseResult, error) {
+	u := c.TablebaseURL
+	if u == "" {
+		u = DefaultTablebaseURL
+	}
+	data := tablebaseJSON{}
+	if err := c.get(ctx, u, url.Values{"fen": {pos.String()}}, &data); err != nil {
+		return nil, err
+	}
+	result := &TablebaseResult{
+		Category:             data.Category,
+		DTZ:                  data.DTZ,
+		DTM:                  data.DTM,
+		Checkmate:            data.Checkmate,
+		Stalemate:            data.Stalemate,
+		InsufficientMaterial: data.InsufficientMaterial,
+	}
+	for _, m := range data.Moves {
+		move, err := pos.DecodeUCI(m.UCI)
+		if err != nil {
+			return nil, fmt.Errorf("online: invalid move in response: %w", err)
+		}
+		result.Moves = append(result.Moves, TablebaseMove{Move: move, Category: m.Category, DTZ: m.DTZ, DTM: m.DTM, Zeroing: m.Zeroing})
+	}
+	return result, nil
+}
+
+// CloudEval is a cached engine evaluation of a position.
+type CloudEval struct {
+	Depth  int
+	KNodes int
+	PVs    []PV
+}
+
+// PV is a principal variation with its evaluation from white's
+// perspective.  Mate is the number of moves until mate, negative if
+// black is mating, and zero if no mate was found in which case CP is
+// the evaluation in centipawns.
+type PV struct {
+	Moves []*chess.Move
+	CP    int
+	Mate  int
+}
+
+type cloudEvalJSON struct {
+	Depth  int `json:"depth"`
+	KNodes int `json:"knodes"`
+	PVs    []struct {
+		Moves string `json:"moves"`
+		CP    int    `json:"cp"`
+		Mate  int    `json:"mate"`
+	} `json:"pvs"`
+}
+
+// CloudEval looks up the cached evaluation of the position with up to
+// multiPV principal variations.  ErrNotFound is returned if the
+// position hasn't been evaluated.  An error is also returned if the
+// request fails or the response can't be decoded.
+func (c *Client) CloudEval(ctx context.Context, pos *chess.Position, multiPV int) (*CloudEval, error) {
+	u := c.CloudEvalURL
+	if u == "" {
+		u = DefaultCloudEvalURL
+	}
+	if multiPV < 1 {
+		multiPV = 1
+	}
+	data := cloudEvalJSON{}
+	params := url.Values{"fen": {pos.String()}, "multiPv": {strconv.Itoa(multiPV)}}
+	if err := c.get(ctx, u, params, &data); err != nil {
+		return nil, err
+	}
+	eval := &CloudEval{Depth: data.Depth, KNodes: data.KNodes}
+	for _, pv := range data.PVs {
+		p := PV{CP: pv.CP, Mate: pv.Mate}
+		cur := pos
+		for _, s := range strings.Fields(pv.Moves) {
+			m, err := cur.DecodeUCI(s)
+			if err != nil {
+				return nil, fmt.Errorf("online: invalid move in response: %w", err)
+			}
+			p.Moves = append(p.Moves, m)
+			cur = cur.Update(m)
+		}
+		eval.PVs = append(eval.PVs, p)
+	}
+	return eval, nil
+}
+
+func (c *Client) get(ctx context.Context, u string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("online: %s returned status %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("online: invalid response from %s: %w", u, err)
+	}
+	return nil
+}
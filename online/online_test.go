@@ -0,0 +1,84 @@
+package online
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+func TestTablebase(t *testing.T) {
+	fen := "4k3/6KP/8/8/8/8/7p/8 w - - 0 1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fen") != fen {
+			t.Errorf("expected fen %s but got %s", fen, r.URL.Query().Get("fen"))
+		}
+		w.Write([]byte(`{"checkmate":false,"stalemate":false,"insufficient_material":false,"dtz":1,"dtm":17,"category":"win",
+			"moves":[{"uci":"h7h8q","san":"h8=Q+","zeroing":true,"dtz":-2,"dtm":-16,"category":"loss"},
+			{"uci":"g7g6","san":"Kg6","zeroing":false,"dtz":null,"dtm":null,"category":"draw"}]}`))
+	}))
+	defer server.Close()
+	c := &Client{TablebaseURL: server.URL}
+	pos, err := chess.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Tablebase(context.Background(), pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Category != Win || *result.DTZ != 1 || *result.DTM != 17 || len(result.Moves) != 2 {
+		t.Fatalf("expected a win with dtz 1 and two moves but got %+v", result)
+	}
+	best := result.Moves[0]
+	if best.Move.String() != "h7h8q" || best.Category != Loss || *best.DTZ != -2 || !best.Zeroing {
+		t.Fatalf("expected h7h8q to be a zeroing loss for black but got %+v", best)
+	}
+	if draw := result.Moves[1]; draw.Category != Draw || draw.DTZ != nil {
+		t.Fatalf("expected g7g6 to be a draw without dtz but got %+v", draw)
+	}
+}
+
+func TestCloudEval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("multiPv") != "2" {
+			http.Error(w, "bad multiPv", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("fen") != chess.StartingPosition().String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1","knodes":100,"depth":40,
+			"pvs":[{"moves":"e2e4 e7e5 g1f3","cp":18},{"moves":"d2d4","mate":-12}]}`))
+	}))
+	defer server.Close()
+	c := &Client{CloudEvalURL: server.URL}
+	eval, err := c.CloudEval(context.Background(), chess.StartingPosition(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Depth != 40 || eval.KNodes != 100 || len(eval.PVs) != 2 {
+		t.Fatalf("expected depth 40 with two pvs but got %+v", eval)
+	}
+	if pv := eval.PVs[0]; pv.CP != 18 || len(pv.Moves) != 3 || pv.Moves[2].String() != "g1f3" {
+		t.Fatalf("expected pv e2e4 e7e5 g1f3 with 18 centipawns but got %+v", pv)
+	}
+	if pv := eval.PVs[1]; pv.Mate != -12 {
+		t.Fatalf("expected a mate in -12 but got %+v", pv)
+	}
+	pos := chess.StartingPosition().Update(eval.PVs[0].Moves[0])
+	if _, err := c.CloudEval(context.Background(), pos, 2); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+	if _, err := c.CloudEval(context.Background(), pos, 1); err == nil {
+		t.Fatal("expected an error status to return an error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CloudEval(ctx, chess.StartingPosition(), 2); err == nil {
+		t.Fatal("expected a canceled context to return an error")
+	}
+}
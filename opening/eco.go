@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"strings"
+	"sync"
 
 	chess "github.com/Yoshi-Exeler/chesslib"
 )
@@ -48,6 +49,24 @@ func NewBookECO() *BookECO {
 	return b
 }
 
+var (
+	ecoOnce sync.Once
+	eco     *BookECO
+)
+
+// Classify returns the most specific ECO opening reached by the moves
+// of the game or nil if the game doesn't start with a known opening or
+// from the standard starting position.  The book is loaded on the first call and shared by later calls.
+func Classify(g *chess.Game) *Opening {
+	ecoOnce.Do(func() {
+		eco = NewBookECO()
+	})
+	if !g.Positions()[0].IsStandardStart() {
+		return nil
+	}
+	return eco.Find(g.Moves())
+}
+
 // Find implements the Book interface
 func (b *BookECO) Find(moves []*chess.Move) *Opening {
 	for n := b.followPath(b.root, moves); n != nil; n = n.parent {
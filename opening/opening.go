@@ -3,6 +3,7 @@ package opening
 
 import (
 	"bytes"
+	"strings"
 
 	chess "github.com/Yoshi-Exeler/chesslib"
 )
@@ -25,6 +26,33 @@ func (o *Opening) Title() string {
 	return o.title
 }
 
+// Name returns the name of the opening without its variation, such as
+// "Sicilian" for the title "Najdorf, Sicilian".
+func (o *Opening) Name() string {
+	name, _ := o.split()
+	return name
+}
+
+// Variation returns the variation of the opening, such as "Najdorf"
+// for the title "Najdorf, Sicilian", or an empty string if the title
+// names the opening itself.
+func (o *Opening) Variation() string {
+	_, variation := o.split()
+	return variation
+}
+
+// split separates the ECO title, which is written either as the opening
+// or as the variation followed by the opening, optionally ending with
+// the code.
+func (o *Opening) split() (name, variation string) {
+	title := strings.TrimSuffix(o.title, "; "+o.code)
+	i := strings.LastIndex(title, ", ")
+	if i == -1 {
+		return title, ""
+	}
+	return title[i+2:], title[:i]
+}
+
 // PGN returns the opening in PGN format.
 func (o *Opening) PGN() string {
 	return o.pgn
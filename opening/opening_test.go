@@ -8,7 +8,7 @@ import (
 	opening "github.com/Yoshi-Exeler/chesslib/opening"
 )
 
-func ExampleFind() {
+func ExampleBookECO_Find() {
 	g := chess.NewGame()
 	g.MoveStr("e4")
 	g.MoveStr("e6")
@@ -19,7 +19,7 @@ func ExampleFind() {
 	fmt.Println(o.Title())
 }
 
-func ExamplePossible() {
+func ExampleBookECO_Possible() {
 	g := chess.NewGame()
 	g.MoveStr("e4")
 	g.MoveStr("d5")
@@ -60,6 +60,49 @@ func TestPossible(t *testing.T) {
 	}
 }
 
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		moves     []string
+		code      string
+		name      string
+		variation string
+	}{
+		{[]string{"e4", "c5", "b4"}, "B20", "Sicilian", "Wing Gambit"},
+		{[]string{"e4", "c5", "b4", "d5"}, "B20", "Sicilian", "Wing Gambit"},
+		{[]string{"e4", "d5"}, "B01", "Center Counter; Scandanavian", ""},
+		{[]string{"a3"}, "A00", "Anderssen's Opening", ""},
+	}
+	for _, test := range tests {
+		g := chess.NewGame()
+		for _, m := range test.moves {
+			if err := g.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		o := opening.Classify(g)
+		if o == nil {
+			t.Fatalf("expected to classify %v but got nil", test.moves)
+		}
+		if o.Code() != test.code || o.Name() != test.name || o.Variation() != test.variation {
+			t.Fatalf("expected %s %q %q for %v but got %s %q %q", test.code, test.name, test.variation, test.moves, o.Code(), o.Name(), o.Variation())
+		}
+	}
+}
+
+func TestClassifyFromPosition(t *testing.T) {
+	fen, err := chess.FEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chess.NewGame(fen)
+	if err := g.MoveStr("Nf3"); err != nil {
+		t.Fatal(err)
+	}
+	if o := opening.Classify(g); o != nil {
+		t.Fatalf("expected no opening for a game set up from a position but got %s", o.Title())
+	}
+}
+
 func BenchmarkNewBookECO(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		opening.NewBookECO()
@@ -0,0 +1,180 @@
+// Package openingdb aggregates the results of games into an opening
+// explorer.  Each position reached in the opening is keyed by its
+// Zobrist hash and records how the games through it ended and which
+// moves were played from it, so transpositions share their statistics.
+package openingdb
+
+import (
+	"sort"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// DefaultMaxPly is the number of half moves of each game added to a DB
+// unless the MaxPly option is given.
+const DefaultMaxPly = 30
+
+// Games is a stream of games such as a *chess.Scanner reading a PGN
+// database.
+type Games interface {
+	Scan() bool
+	Next() *chess.Game
+	Err() error
+}
+
+// Stats counts the results of the games reaching a position or playing
+// a move.
+type Stats struct {
+	WhiteWins int
+	Draws     int
+	BlackWins int
+}
+
+// Total returns the number of games counted.
+func (s Stats) Total() int {
+	return s.WhiteWins + s.Draws + s.BlackWins
+}
+
+// Score returns white's score in the games counted, counting a draw as
+// half a win, or zero if no games were counted.
+func (s Stats) Score() float64 {
+	if s.Total() == 0 {
+		return 0
+	}
+	return (float64(s.WhiteWins) + float64(s.Draws)/2) / float64(s.Total())
+}
+
+func (s *Stats) add(o chess.Outcome) {
+	switch o {
+	case chess.WhiteWon:
+		s.WhiteWins++
+	case chess.Draw:
+		s.Draws++
+	case chess.BlackWon:
+		s.BlackWins++
+	}
+}
+
+// MoveStats is a move played from a position and the results of the
+// games that played it.
+type MoveStats struct {
+	Move *chess.Move
+	Stats
+}
+
+// DB is an opening explorer built from games.  After it is built a DB is
+// safe for concurrent reads but Add must not be called concurrently with
+// other methods.
+type DB struct {
+	maxPly int
+	nodes  map[uint64]*node
+}
+
+type node struct {
+	stats Stats
+	moves map[string]*MoveStats
+}
+
+// New returns an empty DB.
+func New(options ...func(*DB)) *DB {
+	db := &DB{maxPly: DefaultMaxPly, nodes: map[uint64]*node{}}
+	for _, f := range options {
+		f(db)
+	}
+	return db
+}
+
+// MaxPly is an option for New and Build that adds only the first n half
+// moves of each game.
+func MaxPly(n int) func(*DB) {
+	return func(db *DB) {
+		db.maxPly = n
+	}
+}
+
+// Build returns a DB of the games read until the stream ends.  The
+// stream's error, if any, is returned with the games added before it.
+func Build(games Games, options ...func(*DB)) (*DB, error) {
+	db := New(options...)
+	for games.Scan() {
+		db.Add(games.Next())
+	}
+	return db, games.Err()
+}
+
+// Add adds the game to the DB.  Games without a result and games set up
+// from another position than the standard starting position are
+// ignored.  A position repeated within a game is counted once.
+func (db *DB) Add(g *chess.Game) {
+	if g == nil || g.Outcome() == chess.NoOutcome {
+		return
+	}
+	positions := g.Positions()
+	if !positions[0].IsStandardStart() {
+		return
+	}
+	moves := g.Moves()
+	seen := map[uint64]bool{}
+	for i, pos := range positions {
+		if i > db.maxPly {
+			break
+		}
+		hash := pos.Hash()
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		n, ok := db.nodes[hash]
+		if !ok {
+			n = &node{moves: map[string]*MoveStats{}}
+			db.nodes[hash] = n
+		}
+		n.stats.add(g.Outcome())
+		if i == len(moves) || i == db.maxPly {
+			continue
+		}
+		key := moves[i].String()
+		ms, ok := n.moves[key]
+		if !ok {
+			m := *moves[i]
+			ms = &MoveStats{Move: &m}
+			n.moves[key] = ms
+		}
+		ms.add(g.Outcome())
+	}
+}
+
+// Stats returns the results of the games reaching the position with the
+// hash and false if no game reached it.
+func (db *DB) Stats(hash uint64) (Stats, bool) {
+	n, ok := db.nodes[hash]
+	if !ok {
+		return Stats{}, false
+	}
+	return n.stats, true
+}
+
+// Moves returns the moves played from the position with the hash, most
+// played first.
+func (db *DB) Moves(hash uint64) []MoveStats {
+	n, ok := db.nodes[hash]
+	if !ok {
+		return nil
+	}
+	moves := make([]MoveStats, 0, len(n.moves))
+	for _, ms := range n.moves {
+		moves = append(moves, *ms)
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Total() != moves[j].Total() {
+			return moves[i].Total() > moves[j].Total()
+		}
+		return moves[i].Move.String() < moves[j].Move.String()
+	})
+	return moves
+}
+
+// Len returns the number of positions in the DB.
+func (db *DB) Len() int {
+	return len(db.nodes)
+}
@@ -0,0 +1,103 @@
+package openingdb
+
+import (
+	"strings"
+	"testing"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+const testGames = `[Result "1-0"]
+
+1. e4 e5 2. Nf3 1-0
+
+[Result "0-1"]
+
+1. e4 c5 0-1
+
+[Result "1/2-1/2"]
+
+1. d4 d5 2. Nf3 Nf6 1/2-1/2
+
+[Result "1-0"]
+
+1. Nf3 d5 2. d4 Nf6 1-0
+
+[Result "*"]
+
+1. e4 *
+`
+
+func buildTestDB(t *testing.T, options ...func(*DB)) *DB {
+	db, err := Build(chess.NewScanner(strings.NewReader(testGames)), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func positionAfter(t *testing.T, moves ...string) *chess.Position {
+	g := chess.NewGame()
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g.Position()
+}
+
+func TestBuild(t *testing.T) {
+	db := buildTestDB(t)
+	tests := []struct {
+		moves []string
+		stats Stats
+	}{
+		{nil, Stats{WhiteWins: 2, Draws: 1, BlackWins: 1}},
+		{[]string{"e4"}, Stats{WhiteWins: 1, BlackWins: 1}},
+		{[]string{"e4", "c5"}, Stats{BlackWins: 1}},
+		// the position is reached by both queen's pawn games
+		{[]string{"d4", "d5", "Nf3", "Nf6"}, Stats{WhiteWins: 1, Draws: 1}},
+	}
+	for _, test := range tests {
+		stats, ok := db.Stats(positionAfter(t, test.moves...).Hash())
+		if !ok || stats != test.stats {
+			t.Fatalf("expected stats %+v after %v but got %+v", test.stats, test.moves, stats)
+		}
+	}
+	if _, ok := db.Stats(positionAfter(t, "c4").Hash()); ok {
+		t.Fatalf("expected no stats for a position no game reached")
+	}
+}
+
+func TestMoves(t *testing.T) {
+	db := buildTestDB(t)
+	moves := db.Moves(positionAfter(t).Hash())
+	expected := []struct {
+		move  string
+		total int
+	}{{"e2e4", 2}, {"d2d4", 1}, {"g1f3", 1}}
+	if len(moves) != len(expected) {
+		t.Fatalf("expected %d moves but got %d", len(expected), len(moves))
+	}
+	for i, e := range expected {
+		if moves[i].Move.String() != e.move || moves[i].Total() != e.total {
+			t.Fatalf("expected move %d to be %s with %d games but got %s with %d", i, e.move, e.total, moves[i].Move, moves[i].Total())
+		}
+	}
+	if score := moves[0].Score(); score != 0.5 {
+		t.Fatalf("expected a score of 0.5 for e4 but got %f", score)
+	}
+}
+
+func TestMaxPly(t *testing.T) {
+	db := buildTestDB(t, MaxPly(1))
+	if _, ok := db.Stats(positionAfter(t, "e4").Hash()); !ok {
+		t.Fatalf("expected stats after the first ply")
+	}
+	if _, ok := db.Stats(positionAfter(t, "e4", "e5").Hash()); ok {
+		t.Fatalf("expected no stats past the maximum ply")
+	}
+	if moves := db.Moves(positionAfter(t, "e4").Hash()); len(moves) != 0 {
+		t.Fatalf("expected no moves past the maximum ply but got %d", len(moves))
+	}
+}
@@ -0,0 +1,41 @@
+package chess
+
+// Perft returns the number of leaf nodes of the legal move tree of the
+// position to the given depth.  Comparing the counts against published
+// values validates move generation.
+func Perft(pos *Position, depth int) uint64 {
+	return perft(pos.Clone(), depth)
+}
+
+// perft plays the moves in place on the position and undoes them.
+func perft(pos *Position, depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	moves := engine{}.CalcMoves(pos, false)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, m := range moves {
+		u := pos.Do(m)
+		nodes += perft(pos, depth-1)
+		pos.Undo(u)
+	}
+	return nodes
+}
+
+// Divide returns the perft count below each valid move of the position
+// for the given depth.  The move counts sum to the perft count and help
+// find the move whose subtree differs from a reference engine's.  The
+// keys are the moves returned by ValidMoves including their tags.
+func Divide(pos *Position, depth int) map[Move]uint64 {
+	counts := map[Move]uint64{}
+	if depth <= 0 {
+		return counts
+	}
+	for _, m := range pos.ValidMoves() {
+		counts[*m] = Perft(pos.Update(m), depth-1)
+	}
+	return counts
+}
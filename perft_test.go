@@ -0,0 +1,48 @@
+package chess
+
+import "testing"
+
+func TestPerft(t *testing.T) {
+	tables := []struct {
+		fen   string
+		nodes []uint64
+	}{
+		{startFEN, []uint64{20, 400, 8902, 197281}},
+		// castling, promotions and pins
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862}},
+		// en passant captures exposing the king along the rank
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624}},
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467}},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379}},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		for i, expected := range table.nodes {
+			depth := i + 1
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := Perft(pos, depth); nodes != expected {
+				t.Fatalf("%s expected perft %d to be %d but got %d", table.fen, depth, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	pos := StartingPosition()
+	counts := Divide(pos, 3)
+	if len(counts) != 20 {
+		t.Fatalf("expected 20 moves but got %d", len(counts))
+	}
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	if total != Perft(pos, 3) {
+		t.Fatalf("expected divide counts to sum to %d but got %d", Perft(pos, 3), total)
+	}
+	if n := counts[Move{S1: E2, S2: E4}]; n != 600 {
+		t.Fatalf("expected 600 nodes after e2e4 but got %d", n)
+	}
+}
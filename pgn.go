@@ -2,10 +2,13 @@ package chess
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -14,62 +17,157 @@ import (
 // from concatenated PGN files.  It is designed to
 // replace GamesFromPGN in order to handle very large
 // PGN database files such as https://database.lichess.org/.
+// Only the game being read is held in memory.
 type Scanner struct {
-	scanr *bufio.Scanner
+	scanr *PGNScanner
 	game  *Game
 	err   error
 }
 
 // NewScanner returns a new scanner.
 func NewScanner(r io.Reader) *Scanner {
-	scanr := bufio.NewScanner(r)
-	return &Scanner{scanr: scanr}
+	return &Scanner{scanr: NewPGNScanner(r)}
 }
 
 // Scan returns false if there was an error parsing
 // a game or EOF was reached.  Running scan populates
-// data for Next() and Err().
+// data for Next() and Err().  Games are split at the
+// first tag pair following a game's move text and a
+// blank line so the last game doesn't need to end
+// with blank lines.
 func (s *Scanner) Scan() bool {
+	s.game = nil
+	if !s.scanr.Scan() {
+		s.err = s.scanr.Err()
+		return false
+	}
+	if s.err = s.scanr.Err(); s.err != nil {
+		return false
+	}
+	s.game = s.scanr.Game()
+	return true
+}
+
+// Next returns the game from the most recent Scan.
+func (s *Scanner) Next() *Game {
+	return s.game
+}
+
+// Err returns an error encountered during scanning.
+// Typically this will be a PGN parsing error or nil
+// if the end of the input was reached.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// PGNScanner is modeled on the bufio.Scanner type and reads
+// games one at a time from concatenated PGN data.  A new game
+// begins at the first tag pair following a game's move text and a
+// blank line, outside of any comment.
+// Unlike Scanner, a game that fails to parse doesn't end the
+// scan; the parse error is reported by Err and the following
+// games can still be read.
+type PGNScanner struct {
+	scanr   *bufio.Scanner
+	game    *Game
+	err     error
+	pending string
+}
+
+// NewPGNScanner returns a new PGNScanner reading from r.
+func NewPGNScanner(r io.Reader) *PGNScanner {
+	scanr := bufio.NewScanner(r)
+	scanr.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &PGNScanner{scanr: scanr}
+}
+
+// Scan advances to the next game and returns true if one was
+// read, even if it couldn't be parsed.  It returns false when
+// the end of the input is reached or reading fails.
+func (s *PGNScanner) Scan() bool {
+	s.game = nil
 	s.err = nil
 	var sb strings.Builder
-	count := 0
-	for {
-		scan := s.scanr.Scan()
-		if scan == false {
-			s.err = s.scanr.Err()
-			return false
-		}
+	sb.WriteString(s.pending)
+	s.pending = ""
+	inMoves := false
+	afterBlank := false
+	depth := 0
+	for s.scanr.Scan() {
 		line := s.scanr.Text() + "\n"
-		if strings.TrimSpace(line) == "" {
-			count++
-		} else {
-			sb.WriteString(line)
-		}
-		if count == 2 {
-			game, err := decodePGN(sb.String())
-			if err != nil {
-				s.err = err
-				return false
-			}
-			s.game = game
+		trimmed := strings.TrimSpace(line)
+		// lines of a comment such as [%clk 0:01:00] aren't tag pairs
+		isTag := depth == 0 && strings.HasPrefix(trimmed, "[")
+		if isTag && inMoves && afterBlank {
+			s.pending = line
 			break
 		}
+		if trimmed != "" && !isTag {
+			inMoves = true
+			depth = commentDepth(trimmed, depth)
+		}
+		afterBlank = trimmed == ""
+		sb.WriteString(line)
+	}
+	if err := s.scanr.Err(); err != nil {
+		s.err = err
+		return false
 	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return false
+	}
+	s.game, s.err = decodePGN(sb.String())
 	return true
 }
 
-// Next returns the game from the most recent Scan.
-func (s *Scanner) Next() *Game {
+// commentDepth returns the brace comment depth after the line of move
+// text given the depth before it.  Braces in a rest of line comment
+// starting with a semicolon are ignored.
+func commentDepth(line string, depth int) int {
+	for _, r := range line {
+		switch {
+		case r == ';' && depth == 0:
+			return depth
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		}
+	}
+	return depth
+}
+
+// Game returns the game from the most recent Scan or nil
+// if the game couldn't be parsed.
+func (s *PGNScanner) Game() *Game {
 	return s.game
 }
 
-// Err returns an error encountered during scanning.
-// Typically this will be a PGN parsing error or an
-// io.EOF.
-func (s *Scanner) Err() error {
+// Err returns the error from the most recent Scan.  After
+// Scan returns true this is the game's parse error, if any.
+// After Scan returns false this is the read error or nil
+// if the end of the input was reached.
+func (s *PGNScanner) Err() error {
 	return s.err
 }
 
+// DecodePGN reads a single game in PGN format from the reader.  Tag
+// pairs, comments, numeric annotation glyphs and variations are kept.
+// An error is returned if the PGN can't be read or parsed.
+func DecodePGN(r io.Reader) (*Game, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodePGN(string(b))
+}
+
+// EncodePGN writes the game in PGN format to the writer.
+func (g *Game) EncodePGN(w io.Writer) error {
+	_, err := io.WriteString(w, encodePGN(g))
+	return err
+}
+
 // GamesFromPGN returns all PGN decoding games from the
 // reader.  It is designed to be used decoding multiple PGNs
 // in the same file.  An error is returned if there is an
@@ -120,31 +218,98 @@ func (a multiDecoder) Decode(pos *Position, s string) (*Move, error) {
 	return nil, fmt.Errorf(`chess: failed to decode notation text "%s" for position %s`, s, pos)
 }
 
+// startingPosition returns the function setting the game's starting
+// position to the FEN played by the variant of the Variant tag pair.  A
+// variant game without a FEN starts from the variant's starting
+// position, such as the empty pockets of Crazyhouse, while nil is
+// returned for a standard game without a FEN.
+func startingPosition(tagPairs []*TagPair, fen string) (func(*Game), error) {
+	var variant Variant
+	for _, tp := range tagPairs {
+		if v, ok := VariantByName(tp.Value); ok && tp.Key == "Variant" {
+			variant = v
+		}
+	}
+	switch {
+	case variant != nil && fen == "":
+		return UseVariant(variant)
+	case variant != nil:
+		return VariantFEN(variant, fen)
+	case fen != "":
+		return FEN(fen)
+	}
+	return nil, nil
+}
+
 func decodePGN(pgn string) (*Game, error) {
 	tagPairs := getTagPairs(pgn)
-	moveStrs, outcome := moveList(pgn)
 	gameFuncs := []func(*Game){}
+	fen := ""
 	for _, tp := range tagPairs {
 		if strings.ToLower(tp.Key) == "fen" {
-			fenFunc, err := FEN(tp.Value)
-			if err != nil {
-				return nil, fmt.Errorf("chess: pgn decode error %s on tag %s", err.Error(), tp.Key)
-			}
-			gameFuncs = append(gameFuncs, fenFunc)
+			fen = tp.Value
 			break
 		}
 	}
+	startFunc, err := startingPosition(tagPairs, fen)
+	if err != nil {
+		return nil, fmt.Errorf("chess: pgn decode error %s on tag FEN", err.Error())
+	}
+	if startFunc != nil {
+		gameFuncs = append(gameFuncs, startFunc)
+	}
 	gameFuncs = append(gameFuncs, TagPairs(tagPairs))
 	g := NewGame(gameFuncs...)
 	g.ignoreAutomaticDraws = true
-	decoder := multiDecoder([]Decoder{AlgebraicNotation{}, LongAlgebraicNotation{}, UCINotation{}})
-	for _, alg := range moveStrs {
-		m, err := decoder.Decode(g.Position(), alg)
-		if err != nil {
-			return nil, fmt.Errorf("chess: pgn decode error %s on move %d", err.Error(), g.Position().moveCount)
-		}
-		if err := g.Move(m); err != nil {
-			return nil, fmt.Errorf("chess: pgn invalid move error %s on move %d", err.Error(), g.Position().moveCount)
+	decoder := multiDecoder([]Decoder{AlgebraicNotation{}, LongAlgebraicNotation{}, UCINotation{}, RelaxedAlgebraicNotation{}})
+	var outcome Outcome
+	// node is the node the next move is played from and variations
+	// holds the nodes to return to when the open variations close
+	node := g.root
+	variations := []*Node{}
+	for _, tok := range moveTextTokens(pgn) {
+		switch tok.typ {
+		case pgnCommentToken:
+			node.addComment(tok.text)
+		case pgnVariationStartToken:
+			if node.parent == nil {
+				return nil, errors.New("chess: pgn decode error variation doesn't follow a move")
+			}
+			variations = append(variations, node)
+			node = node.parent
+		case pgnVariationEndToken:
+			if len(variations) == 0 {
+				return nil, errors.New("chess: pgn decode error unbalanced variation")
+			}
+			node = variations[len(variations)-1]
+			variations = variations[:len(variations)-1]
+		case pgnResultToken:
+			if len(variations) == 0 {
+				outcome = Outcome(tok.text)
+			}
+		case pgnNAGToken:
+			node.AddNAG(nagFromText(tok.text))
+		case pgnMoveToken:
+			text, glyph := splitMoveGlyph(tok.text)
+			m, err := decoder.Decode(node.pos, text)
+			if err != nil {
+				return nil, fmt.Errorf("chess: pgn decode error %s on move %d", err.Error(), node.pos.moveCount)
+			}
+			if len(variations) == 0 {
+				if err := g.Move(m); err != nil {
+					return nil, fmt.Errorf("chess: pgn invalid move error %s on move %d", err.Error(), node.pos.moveCount)
+				}
+				node = g.tail
+			} else {
+				next, err := node.AddVariation(m)
+				if err != nil {
+					return nil, fmt.Errorf("chess: pgn invalid move error %s on move %d", err.Error(), node.pos.moveCount)
+				}
+				node = next
+			}
+			if glyph != "" {
+				node.AddNAG(nagFromText(glyph))
+			}
 		}
 	}
 	g.outcome = outcome
@@ -154,82 +319,270 @@ func decodePGN(pgn string) (*Game, error) {
 func encodePGN(g *Game) string {
 	s := ""
 	for _, tag := range g.tagPairs {
-		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, tag.Value)
+		// the set up tags are derived from the starting position
+		if tag.Key == "SetUp" || tag.Key == "FEN" {
+			continue
+		}
+		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, tagValueEscaper.Replace(tag.Value))
+	}
+	if start := g.positions[0]; !start.IsStandardStart() {
+		s += fmt.Sprintf("[SetUp \"1\"]\n[FEN \"%s\"]\n", start)
 	}
 	s += "\n"
-	for i, move := range g.moves {
+	if g.root.comment != "" {
+		s += "{" + g.root.comment + "} "
+	}
+	annotated := false
+	for i, node := range g.mainLine() {
 		pos := g.positions[i]
-		txt := g.Notation.Encode(pos, move)
-		if i%2 == 0 {
-			s += fmt.Sprintf("%d.%s", (i/2)+1, txt)
+		txt := g.Notation.Encode(pos, node.move) + node.Annotation().String()
+		if pos.turn == White {
+			s += fmt.Sprintf("%d.%s", pos.moveCount, txt)
+		} else if i == 0 {
+			s += fmt.Sprintf("%d...%s ", pos.moveCount, txt)
+		} else if annotated {
+			s += fmt.Sprintf(" %d...%s ", pos.moveCount, txt)
 		} else {
 			s += fmt.Sprintf(" %s ", txt)
 		}
+		annotations := nodeAnnotations(g.Notation, node)
+		for _, a := range annotations {
+			if pos.turn == White {
+				s += " " + a
+			} else {
+				s += a + " "
+			}
+		}
+		annotated = len(annotations) > 0
 	}
 	s += " " + string(g.outcome)
 	return s
 }
 
+// nodeAnnotations returns the glyphs, the comment and the variations
+// that follow the node's move in PGN move text.
+func nodeAnnotations(n Notation, node *Node) []string {
+	annotations := nodeNotes(node)
+	for _, v := range node.parent.Variations() {
+		annotations = append(annotations, "("+encodeVariation(n, v)+")")
+	}
+	return annotations
+}
+
+// nodeNotes returns the glyphs and the comment attached to the node
+// leaving out the glyph written as the move's suffix annotation.
+func nodeNotes(node *Node) []string {
+	notes := []string{}
+	annotation := node.Annotation()
+	for _, nag := range node.nags {
+		if nag == int(annotation) {
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("$%d", nag))
+	}
+	if node.comment != "" {
+		notes = append(notes, "{"+node.comment+"}")
+	}
+	return notes
+}
+
+// encodeVariation returns the PGN move text of the line
+// starting with the node and following its main line.
+func encodeVariation(n Notation, node *Node) string {
+	tokens := []string{}
+	numbered := false
+	for cur := node; cur != nil; cur = cur.MainLine() {
+		pos := cur.parent.pos
+		txt := n.Encode(pos, cur.move) + cur.Annotation().String()
+		if pos.turn == White {
+			tokens = append(tokens, fmt.Sprintf("%d.%s", pos.moveCount, txt))
+		} else if !numbered {
+			tokens = append(tokens, fmt.Sprintf("%d...%s", pos.moveCount, txt))
+		} else {
+			tokens = append(tokens, txt)
+		}
+		numbered = true
+		var annotations []string
+		if cur == node {
+			// the siblings of the first move are encoded by the enclosing line
+			annotations = nodeNotes(cur)
+		} else {
+			annotations = nodeAnnotations(n, cur)
+		}
+		if len(annotations) > 0 {
+			tokens = append(tokens, annotations...)
+			numbered = false
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
 var (
-	tagPairRegex = regexp.MustCompile(`\[(.*)\s\"(.*)\"\]`)
+	tagPairRegex = regexp.MustCompile(`\[(\w+)\s+"((?:[^"\\]|\\.)*)"\]`)
+
+	// tag pair values escape quotes and backslashes with a backslash
+	tagValueEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	tagValueUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
 )
 
+// getTagPairs returns the tag pairs of the header, which ends at the
+// first move text token, so brackets in comments aren't read as tags.
+// Line comments and escaped lines between the tags are skipped.
 func getTagPairs(pgn string) []*TagPair {
 	tagPairs := []*TagPair{}
-	matches := tagPairRegex.FindAllString(pgn, -1)
-	for _, m := range matches {
-		results := tagPairRegex.FindStringSubmatch(m)
+	for i := 0; i < len(pgn); {
+		switch pgn[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+			continue
+		case ';', '%':
+			i = sectionEnd(pgn, i, '\n') + 1
+			continue
+		case '[':
+		default:
+			return tagPairs
+		}
+		end := tagPairEnd(pgn, i)
+		if end < len(pgn) {
+			end++
+		}
+		results := tagPairRegex.FindStringSubmatch(pgn[i:end])
 		if len(results) == 3 {
 			pair := &TagPair{
 				Key:   results[1],
-				Value: results[2],
+				Value: tagValueUnescaper.Replace(results[2]),
 			}
 			tagPairs = append(tagPairs, pair)
 		}
+		i = end
 	}
 	return tagPairs
 }
 
+type pgnTokenType int
+
+const (
+	pgnMoveToken pgnTokenType = iota
+	pgnCommentToken
+	pgnNAGToken
+	pgnVariationStartToken
+	pgnVariationEndToken
+	pgnResultToken
+)
+
+type pgnToken struct {
+	typ  pgnTokenType
+	text string
+}
+
 var (
 	moveNumRegex = regexp.MustCompile(`(?:\d+\.+)?(.*)`)
+	nagRegex     = regexp.MustCompile(`^\$\d+$`)
 )
 
-func moveList(pgn string) ([]string, Outcome) {
-	// remove comments
-	text := removeSection("{", "}", pgn)
-	// remove variations
-	text = removeSection(`\(`, `\)`, text)
-	// remove tag pairs
-	text = removeSection(`\[`, `\]`, text)
-	// remove line breaks
-	text = strings.Replace(text, "\n", " ", -1)
-
-	list := strings.Split(text, " ")
-	filtered := []string{}
-	var outcome Outcome
-	for _, move := range list {
-		move = strings.TrimSpace(move)
-		switch move {
-		case string(NoOutcome), string(WhiteWon), string(BlackWon), string(Draw):
-			outcome = Outcome(move)
-		case "":
-		default:
-			results := moveNumRegex.FindStringSubmatch(move)
-			if len(results) == 2 && results[1] != "" {
-				filtered = append(filtered, results[1])
-			}
+// glyphNAGs maps the move suffix annotations to their numeric
+// annotation glyphs.
+var glyphNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// nagFromText returns the numeric annotation glyph for a $n
+// token or a move suffix annotation such as !?.
+func nagFromText(text string) int {
+	if nag, ok := glyphNAGs[text]; ok {
+		return nag
+	}
+	nag, _ := strconv.Atoi(strings.TrimPrefix(text, "$"))
+	return nag
+}
+
+// splitMoveGlyph splits a move suffix annotation such as !? from
+// the move text.
+func splitMoveGlyph(text string) (string, string) {
+	move := strings.TrimRight(text, "!?")
+	if glyph := text[len(move):]; glyphNAGs[glyph] != 0 {
+		return move, glyph
+	}
+	return text, ""
+}
+
+const pgnDelimiters = " \t\r\n{}()[];"
+
+// sectionEnd returns the index of the closing character for the
+// section opened at index i or the length of s if it isn't closed.
+func sectionEnd(s string, i int, closer byte) int {
+	end := strings.IndexByte(s[i+1:], closer)
+	if end == -1 {
+		return len(s)
+	}
+	return i + 1 + end
+}
+
+// tagPairEnd returns the index of the bracket closing the tag pair
+// starting at i, skipping brackets inside the quoted value.
+func tagPairEnd(s string, i int) int {
+	quoted := false
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case quoted && s[j] == '\\':
+			j++
+		case s[j] == '"':
+			quoted = !quoted
+		case !quoted && s[j] == ']':
+			return j
 		}
 	}
-	return filtered, outcome
+	return len(s)
 }
 
-func removeSection(leftChar, rightChar, s string) string {
-	r := regexp.MustCompile(leftChar + ".*?" + rightChar)
-	for {
-		i := r.FindStringIndex(s)
-		if i == nil {
-			return s
+// moveTextTokens splits the PGN into move text tokens.  Tag pairs
+// and move numbers are skipped.
+func moveTextTokens(pgn string) []pgnToken {
+	tokens := []pgnToken{}
+	for i := 0; i < len(pgn); {
+		switch c := pgn[i]; c {
+		case '{', ';':
+			closer := byte('}')
+			if c == ';' {
+				closer = '\n'
+			}
+			end := sectionEnd(pgn, i, closer)
+			tokens = append(tokens, pgnToken{typ: pgnCommentToken, text: strings.TrimSpace(pgn[i+1 : end])})
+			i = end + 1
+		case '[':
+			i = tagPairEnd(pgn, i) + 1
+		case '(':
+			tokens = append(tokens, pgnToken{typ: pgnVariationStartToken})
+			i++
+		case ')':
+			tokens = append(tokens, pgnToken{typ: pgnVariationEndToken})
+			i++
+		case ' ', '\t', '\r', '\n', '}', ']':
+			i++
+		default:
+			j := i
+			for j < len(pgn) && !strings.ContainsRune(pgnDelimiters, rune(pgn[j])) {
+				j++
+			}
+			word := pgn[i:j]
+			i = j
+			switch {
+			case word == string(NoOutcome), word == string(WhiteWon), word == string(BlackWon), word == string(Draw):
+				tokens = append(tokens, pgnToken{typ: pgnResultToken, text: word})
+			case nagRegex.MatchString(word), glyphNAGs[word] != 0:
+				tokens = append(tokens, pgnToken{typ: pgnNAGToken, text: word})
+			default:
+				results := moveNumRegex.FindStringSubmatch(word)
+				if len(results) == 2 && results[1] != "" {
+					tokens = append(tokens, pgnToken{typ: pgnMoveToken, text: results[1]})
+				}
+			}
 		}
-		s = s[0:i[0]] + s[i[1]:]
 	}
+	return tokens
 }
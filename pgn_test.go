@@ -0,0 +1,658 @@
+package chess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const multiGamePGN = `[Event "First"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+
+[Event "Corrupt"]
+[Result "*"]
+
+1. e4 e4 *
+
+[Event "Third"]
+[Result "1/2-1/2"]
+
+1. d4 d5 2. c4 e6
+3. Nc3 Nf6 1/2-1/2
+`
+
+func TestPGNScanner(t *testing.T) {
+	scanner := NewPGNScanner(strings.NewReader(multiGamePGN))
+	events := []string{}
+	errCount := 0
+	for scanner.Scan() {
+		if scanner.Err() != nil {
+			errCount++
+			continue
+		}
+		events = append(events, scanner.Game().GetTagPair("Event").Value)
+	}
+	if scanner.Err() != nil {
+		t.Fatal(scanner.Err())
+	}
+	if errCount != 1 {
+		t.Fatalf("expected 1 corrupt game but got %d", errCount)
+	}
+	if strings.Join(events, ",") != "First,Third" {
+		t.Fatalf("expected games First,Third but got %s", strings.Join(events, ","))
+	}
+}
+
+func TestPGNScannerMultiLineComment(t *testing.T) {
+	pgn := `[Event "First"]
+
+1. e4 {White thinks
+[%clk 0:04:58]
+} e5 {
+[%eval 0.17]} 2. Nf3 ; a {brace in a line comment
+Nc6 1-0
+
+[Event "Second"]
+
+1. d4 d5 *
+`
+	scanner := NewPGNScanner(strings.NewReader(pgn))
+	events := []string{}
+	for scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, scanner.Game().GetTagPair("Event").Value)
+		if scanner.Game().GetTagPair("Event").Value == "First" && len(scanner.Game().Moves()) != 4 {
+			t.Fatalf("expected 4 moves in the first game but got %d", len(scanner.Game().Moves()))
+		}
+	}
+	if strings.Join(events, ",") != "First,Second" {
+		t.Fatalf("expected games First,Second but got %s", strings.Join(events, ","))
+	}
+}
+
+const variationPGN = `[Event "Variations"]
+
+1. e4 {King's pawn} e5 (1... c5 2. Nf3 (2. c3 d5) 2... d6) (1... e6)
+2. Nf3 Nc6 {developing} 3. Bb5 *`
+
+func TestPGNVariations(t *testing.T) {
+	pgn, err := PGN(strings.NewReader(variationPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(pgn)
+	if len(g.Moves()) != 5 {
+		t.Fatalf("expected 5 main line moves but got %d", len(g.Moves()))
+	}
+	e4 := g.Root().MainLine()
+	if e4.Comment() != "King's pawn" {
+		t.Fatalf("expected comment on e4 but got %q", e4.Comment())
+	}
+	children := e4.Children()
+	if len(children) != 3 {
+		t.Fatalf("expected 3 replies to e4 but got %d", len(children))
+	}
+	replies := []string{}
+	for _, c := range children {
+		replies = append(replies, c.Move().String())
+	}
+	if strings.Join(replies, ",") != "e7e5,c7c5,e7e6" {
+		t.Fatalf("expected replies e7e5,c7c5,e7e6 but got %s", strings.Join(replies, ","))
+	}
+	sicilian := children[1].MainLine()
+	if len(sicilian.Variations()) != 0 || len(children[1].Variations()) != 1 {
+		t.Fatal("expected 2. c3 to be a variation of 2. Nf3 in the sicilian")
+	}
+	if sicilian.MainLine().Move().String() != "d7d6" {
+		t.Fatalf("expected sicilian to continue with d7d6 but got %s", sicilian.MainLine().Move())
+	}
+	expected := "1.e4 {King's pawn} 1...e5 (1...c5 2.Nf3 (2.c3 d5) 2...d6) (1...e6) 2.Nf3 Nc6 {developing} 3.Bb5 *"
+	actual := strings.TrimSpace(strings.TrimPrefix(g.String(), "[Event \"Variations\"]\n"))
+	if actual != expected {
+		t.Fatalf("expected pgn move text %s but got %s", expected, actual)
+	}
+	if err := g.UnmarshalText([]byte(g.String())); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Root().MainLine().Children()) != 3 {
+		t.Fatal("expected variations to survive a round trip")
+	}
+}
+
+func TestNodeAddVariation(t *testing.T) {
+	g := NewGame()
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := UCINotation{}.Decode(g.Root().Position(), "d2d4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := g.Root().AddVariation(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Parent() != g.Root() || len(g.Root().Variations()) != 1 || g.Root().MainLine().Move().String() != "e2e4" {
+		t.Fatal("expected d4 to be added as a variation of e4")
+	}
+	if _, err := v.AddVariation(&Move{S1: E2, S2: E5}); err == nil {
+		t.Fatal("expected invalid variation move to return an error")
+	}
+	clone := g.Clone()
+	if err := clone.MoveStr("e5"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Root().MainLine().MainLine() != nil {
+		t.Fatal("expected moves on a clone not to change the original game tree")
+	}
+}
+
+func TestGamePromoteVariation(t *testing.T) {
+	pgn, err := PGN(strings.NewReader("1. e4 e5 (1... c5 2. Nf3 d6) 2. Nf3 *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(pgn)
+	c5 := g.Root().MainLine().Variations()[0]
+	if err := g.PromoteVariation(c5); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(g.String()); s != "1.e4 c5 (1...e5 2.Nf3) 2.Nf3 d6  *" {
+		t.Fatalf("expected the game to follow the promoted line but got %s", s)
+	}
+	if len(g.Moves()) != 4 || g.Position() != c5.MainLine().MainLine().Position() {
+		t.Fatalf("expected the game's moves to end with d6 but got %v", g.Moves())
+	}
+	if err := g.PromoteVariation(g.Root()); err == nil {
+		t.Fatal("expected promoting the root to return an error")
+	}
+	if err := g.PromoteVariation(NewGame().Root()); err == nil {
+		t.Fatal("expected promoting a node of another game to return an error")
+	}
+}
+
+func TestGameDeleteVariation(t *testing.T) {
+	pgn, err := PGN(strings.NewReader("1. e4 e5 (1... c5 2. Nf3) 2. Nf3 Nc6 *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(pgn)
+	e4 := g.Root().MainLine()
+	if err := g.DeleteVariation(e4.Variations()[0]); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(g.String()); s != "1.e4 e5 2.Nf3 Nc6  *" {
+		t.Fatalf("expected the variation to be removed but got %s", s)
+	}
+	// deleting one of the game's moves takes the game back
+	if err := g.DeleteVariation(e4.MainLine().MainLine()); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(g.String()); s != "1.e4 e5  *" {
+		t.Fatalf("expected the game to be taken back to e5 but got %s", s)
+	}
+	if len(g.Positions()) != 3 || g.Position() != e4.MainLine().Position() {
+		t.Fatalf("expected the game's position to be after e5 but got %s", g.Position())
+	}
+	if err := g.DeleteVariation(g.Root()); err == nil {
+		t.Fatal("expected deleting the root to return an error")
+	}
+}
+
+func TestPGNTagPairsInHeader(t *testing.T) {
+	pgn := "[Event \"Casual\"] [Site \"?\"]\n; a comment\n[White \"A\"]\n\n" +
+		"1. e4 {like [White \"B\"] in another game} e5 ; [FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n2. Nf3 *"
+	g, err := DecodePGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*TagPair{{Key: "Event", Value: "Casual"}, {Key: "Site", Value: "?"}, {Key: "White", Value: "A"}}
+	if !reflect.DeepEqual(g.TagPairs(), expected) {
+		t.Fatalf("expected the tag pairs of the header but got %v", g.TagPairs())
+	}
+	if len(g.Moves()) != 3 || g.Root().MainLine().Comment() != `like [White "B"] in another game` {
+		t.Fatalf("expected the moves and comments of %s but got %s", pgn, g)
+	}
+}
+
+func TestPGNSetUpTags(t *testing.T) {
+	g := NewGame(TagPairs([]*TagPair{{Key: "FEN", Value: startFEN}, {Key: "Event", Value: "Standard"}}))
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if s := g.String(); strings.Contains(s, "FEN") || strings.Contains(s, "SetUp") {
+		t.Fatalf("expected standard start pgn without set up tags but got %s", s)
+	}
+	fen := "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"
+	opt, err := FEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(opt)
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	s := g.String()
+	if !strings.Contains(s, "[SetUp \"1\"]\n[FEN \""+fen+"\"]\n") {
+		t.Fatalf("expected custom start pgn to include set up tags but got %s", s)
+	}
+	// the set up tags survive a round trip
+	read, err := PGN(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(read)
+	if g.Positions()[0].String() != fen {
+		t.Fatalf("expected game to start from %s but got %s", fen, g.Positions()[0])
+	}
+	if strings.Count(g.String(), "[FEN ") != 1 {
+		t.Fatalf("expected a single fen tag but got %s", g.String())
+	}
+}
+
+func TestPGNMoveNumbersFromPosition(t *testing.T) {
+	opt, err := FEN("4k3/7p/8/8/8/8/4p3/4K3 b - - 3 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	for _, move := range []string{"Kd7", "Kxe2", "Ke6"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := g.String()
+	if !strings.HasSuffix(s, "\n42...Kd7 43.Kxe2 Ke6  *") {
+		t.Fatalf("expected move numbers from the starting position but got %s", s)
+	}
+	read, err := PGN(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := NewGame(read).FEN(); fen != g.FEN() {
+		t.Fatalf("expected pgn to decode to %s but got %s", g.FEN(), fen)
+	}
+}
+
+func TestPGNAnnotations(t *testing.T) {
+	pgn := `[Event "Annotated"]
+
+1. e4! {best by test} e5 $2 2. Nf3 Nc6?! 3. Bb5 $1 $14 {the Spanish} a6 *`
+	read, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(read)
+	tables := []struct {
+		ply     int
+		nags    []int
+		comment string
+	}{
+		{0, []int{1}, "best by test"},
+		{1, []int{2}, ""},
+		{2, nil, ""},
+		{3, []int{6}, ""},
+		{4, []int{1, 14}, "the Spanish"},
+		{5, nil, ""},
+		{6, nil, ""},
+	}
+	for _, table := range tables {
+		nags, comment := g.Annotations(table.ply)
+		if !reflect.DeepEqual(nags, table.nags) || comment != table.comment {
+			t.Fatalf("expected ply %d annotations %v %q but got %v %q", table.ply, table.nags, table.comment, nags, comment)
+		}
+	}
+	expected := "1.e4! {best by test} 1...e5? 2.Nf3 Nc6?! 3.Bb5! $14 {the Spanish} 3...a6  *"
+	if s := g.String(); !strings.HasSuffix(s, expected) {
+		t.Fatalf("expected pgn to end with %s but got %s", expected, s)
+	}
+	// the encoded annotations read back the same
+	read, err = PGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := NewGame(read).String(); s != g.String() {
+		t.Fatalf("expected round trip to keep annotations but got %s", s)
+	}
+}
+
+func TestNodeEval(t *testing.T) {
+	pgn := "1. e4 { [%eval 0.17] } 1... e5 { [%eval -0.2] [%clk 0:03:00] } 2. Qh5 { [%eval #-3] } *"
+	read, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(read)
+	e4 := g.Root().MainLine()
+	e5 := e4.MainLine()
+	qh5 := e5.MainLine()
+	tables := []struct {
+		node *Node
+		e    Evaluation
+	}{
+		{e4, Evaluation{Centipawns: 17}},
+		{e5, Evaluation{Centipawns: -20}},
+		{qh5, Evaluation{Mate: -3}},
+	}
+	for _, table := range tables {
+		if e, ok := table.node.Eval(); !ok || e != table.e {
+			t.Fatalf("expected %s to have evaluation %s but got %s", table.node.Move(), table.e, e)
+		}
+	}
+	e5.SetEval(Evaluation{Mate: 2})
+	if c := e5.Comment(); c != "[%eval #2] [%clk 0:03:00]" {
+		t.Fatalf("expected the evaluation to be replaced but got %q", c)
+	}
+	e4.RemoveEval()
+	if _, ok := e4.Eval(); ok || e4.Comment() != "" {
+		t.Fatalf("expected the evaluation to be removed but got %q", e4.Comment())
+	}
+	e4.SetEval(Evaluation{Centipawns: 35})
+	e4.AddNAG(1)
+	e4.AddNAG(14)
+	e4.RemoveNAG(1)
+	// the evaluations and glyphs read back the same
+	read, err = PGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e4 = NewGame(read).Root().MainLine()
+	if e, ok := e4.Eval(); !ok || e.Centipawns != 35 || !reflect.DeepEqual(e4.NAGs(), []int{14}) {
+		t.Fatalf("expected round trip to keep the evaluation and glyphs but got %s and %v", e, e4.NAGs())
+	}
+}
+
+func TestPGNMoveClockAndEval(t *testing.T) {
+	pgn := `[Event "Rated Blitz game"]
+[Site "https://lichess.org/8jb5kiqw"]
+[TimeControl "180+0"]
+
+1. e4 { [%eval 0.17] [%clk 0:03:00] } 1... c5 { [%eval 0.19,24] [%clk 0:02:58.3] } 2. Nf3 { [%clk 1:02:03] } 2... d6 { [%eval #-4] } 1-0`
+	tables := []struct {
+		clock   time.Duration
+		clockOK bool
+		eval    Evaluation
+		evalOK  bool
+	}{
+		{3 * time.Minute, true, Evaluation{Centipawns: 17}, true},
+		{2*time.Minute + 58300*time.Millisecond, true, Evaluation{Centipawns: 19}, true},
+		{time.Hour + 2*time.Minute + 3*time.Second, true, Evaluation{}, false},
+		{0, false, Evaluation{Mate: -4}, true},
+	}
+	encoded := pgn
+	for round := 0; round < 2; round++ {
+		read, err := PGN(strings.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(read)
+		for i, table := range tables {
+			if d, ok := g.MoveClock(i); d != table.clock || ok != table.clockOK {
+				t.Fatalf("expected move %d to have clock %s %t but got %s %t", i, table.clock, table.clockOK, d, ok)
+			}
+			if e, ok := g.MoveEval(i); e != table.eval || ok != table.evalOK {
+				t.Fatalf("expected move %d to have evaluation %s %t but got %s %t", i, table.eval, table.evalOK, e, ok)
+			}
+		}
+		if _, ok := g.MoveClock(len(tables)); ok {
+			t.Fatal("expected no clock for an index out of range")
+		}
+		// the encoded game reads back the same
+		encoded = g.String()
+	}
+}
+
+func TestMoveAnnotations(t *testing.T) {
+	g := NewGame()
+	for _, s := range []string{"e4!!", "e5", "Qh5?!"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e4 := g.Root().MainLine()
+	qh5 := e4.MainLine().MainLine()
+	if e4.Annotation() != BrilliantMove || e4.MainLine().Annotation() != NoAnnotation || qh5.Annotation() != DubiousMove {
+		t.Fatalf("expected the annotations !!, none and ?! but got %s, %s and %s",
+			e4.Annotation(), e4.MainLine().Annotation(), qh5.Annotation())
+	}
+	qh5.AddNAG(14)
+	qh5.SetAnnotation(Mistake)
+	if !reflect.DeepEqual(qh5.NAGs(), []int{2, 14}) {
+		t.Fatalf("expected setting the annotation to replace the glyph but got %v", qh5.NAGs())
+	}
+	if expected := "1.e4!! e5 2.Qh5? $14 *"; !strings.HasSuffix(g.String(), expected) {
+		t.Fatalf("expected pgn %s but got %s", expected, g.String())
+	}
+}
+
+func TestPGNEncodeDecode(t *testing.T) {
+	pgn := `[Event "Casual \"Blitz\" [rated]"]
+[Site "C:\\games"]
+[Date "2024.01.02"]
+[Round "1"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[Opening "Scholar's Mate"]
+
+{a short game} 1.e4 e5 2.Qh5?! {risky} 2...Nc6 3.Bc4 Nf6?? 4.Qxf7# 1-0`
+	g, err := DecodePGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []struct {
+		key   string
+		value string
+	}{
+		{"Event", `Casual "Blitz" [rated]`},
+		{"Site", `C:\games`},
+		{"White", "Alice"},
+		{"Opening", "Scholar's Mate"},
+	}
+	for _, table := range tables {
+		if tp := g.GetTagPair(table.key); tp == nil || tp.Value != table.value {
+			t.Fatalf("expected tag %s to be %s but got %v", table.key, table.value, tp)
+		}
+	}
+	if len(g.TagPairs()) != 8 || len(g.Moves()) != 7 || g.Outcome() != WhiteWon {
+		t.Fatalf("expected 8 tag pairs and 7 moves with a white win but got %d, %d and %s",
+			len(g.TagPairs()), len(g.Moves()), g.Outcome())
+	}
+	buf := &strings.Builder{}
+	if err := g.EncodePGN(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != pgn {
+		t.Fatalf("expected pgn to round trip as\n%s\nbut got\n%s", pgn, buf)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	// the last game has no trailing blank lines and a single blank
+	// line separates the games
+	pgn := `[Event "First"]
+
+1. e4 e5 1-0
+
+[Event "Second"]
+
+1. d4 d5 *`
+	scanner := NewScanner(strings.NewReader(pgn))
+	events := []string{}
+	for scanner.Scan() {
+		events = append(events, scanner.Next().GetTagPair("Event").Value)
+	}
+	if scanner.Err() != nil {
+		t.Fatal(scanner.Err())
+	}
+	if strings.Join(events, ",") != "First,Second" {
+		t.Fatalf("expected games First,Second but got %s", strings.Join(events, ","))
+	}
+	// a corrupt game stops the scan
+	scanner = NewScanner(strings.NewReader(multiGamePGN))
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+	if count != 1 || scanner.Err() == nil {
+		t.Fatalf("expected the scan to stop with an error after 1 game but got %d games and %v", count, scanner.Err())
+	}
+}
+
+func TestPGNEncoder(t *testing.T) {
+	pgn := `[Black "Kasparov"]
+[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Result "*"]
+
+{Opening} 1. e4 $1 {the best move} e5 (1... c5 2. Nf3 d6) 2. Nf3 Nc6 3. Bb5 a6 *`
+	read, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(read)
+	tables := []struct {
+		options  []func(*PGNEncoder)
+		expected string
+	}{
+		{nil, `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Black "Kasparov"]
+[Result "*"]
+
+{Opening} 1. e4 $1 {the best move} 1... e5 (1... c5 2. Nf3 d6) 2. Nf3 Nc6 3. Bb5
+a6 *
+
+`},
+		{[]func(*PGNEncoder){PGNLineWidth(30), PGNWithoutComments()}, `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Black "Kasparov"]
+[Result "*"]
+
+1. e4 e5 (1... c5 2. Nf3 d6)
+2. Nf3 Nc6 3. Bb5 a6 *
+
+`},
+		{[]func(*PGNEncoder){PGNReduced(), PGNLineWidth(0)}, `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Black "Kasparov"]
+[Result "*"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 *
+
+`},
+		{[]func(*PGNEncoder){PGNTagOrder("Black", "White"), PGNWithoutVariations()}, `[Black "Kasparov"]
+[White "Deep Blue"]
+[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[Result "*"]
+
+{Opening} 1. e4 $1 {the best move} 1... e5 2. Nf3 Nc6 3. Bb5 a6 *
+
+`},
+		{[]func(*PGNEncoder){PGNReduced(), PGNCompactMoveNumbers(), PGNResultOnOwnLine()}, `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Black "Kasparov"]
+[Result "*"]
+
+1.e4 e5 2.Nf3 Nc6 3.Bb5 a6
+*
+
+`},
+		{[]func(*PGNEncoder){PGNWithoutResumedMoveNumbers(), PGNCompactMoveNumbers(), PGNLineWidth(0)}, `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Deep Blue"]
+[Black "Kasparov"]
+[Result "*"]
+
+{Opening} 1.e4 $1 {the best move} e5 (1...c5 2.Nf3 d6) 2.Nf3 Nc6 3.Bb5 a6 *
+
+`},
+	}
+	for _, table := range tables {
+		var sb strings.Builder
+		if err := NewPGNEncoder(&sb, table.options...).Encode(g); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != table.expected {
+			t.Fatalf("expected pgn\n%s\nbut got\n%s", table.expected, sb.String())
+		}
+		decoded, err := DecodePGN(strings.NewReader(sb.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded.Moves(), g.Moves()) {
+			t.Fatalf("expected the encoded pgn to decode to the moves %v but got %v", g.Moves(), decoded.Moves())
+		}
+	}
+}
+
+func TestPGNEncoderUnknownTags(t *testing.T) {
+	tables := []struct {
+		moves    []string
+		expected string
+	}{
+		{nil, `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+*
+
+`},
+		{[]string{"f3", "e5", "g4", "Qh4#"}, `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4#
+0-1
+
+`},
+	}
+	for _, table := range tables {
+		g := NewGame()
+		for _, s := range table.moves {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var sb strings.Builder
+		if err := NewPGNEncoder(&sb, PGNResultOnOwnLine()).Encode(g); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != table.expected {
+			t.Fatalf("expected pgn\n%s\nbut got\n%s", table.expected, sb.String())
+		}
+	}
+}
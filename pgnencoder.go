@@ -0,0 +1,285 @@
+package chess
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sevenTagRoster is the order of the tags every PGN game should have.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// unknownTagValues are the values of the Seven Tag Roster written for
+// games without the tag, apart from the result taken from the game.
+var unknownTagValues = map[string]string{
+	"Event": "?",
+	"Site":  "?",
+	"Date":  "????.??.??",
+	"Round": "?",
+	"White": "?",
+	"Black": "?",
+}
+
+// PGNEncoder writes games in the PGN export format.  Tags are written
+// in the tag order, followed by the Seven Tag Roster tags not in it and
+// the remaining tags in the order of the game.  Roster tags the game
+// lacks are written as unknown, such as [Site "?"], with the result
+// taken from the game.  The move text is wrapped at the line width with
+// a space after each move number, as in "1. e4 e5", and ends with the
+// result.  A blank line follows each game so an encoder can write a
+// database of games.
+type PGNEncoder struct {
+	w             io.Writer
+	lineWidth     int
+	comments      bool
+	variations    bool
+	tagOrder      []string
+	reduced       bool
+	compactNumber bool
+	resumeNumber  bool
+	resultLine    bool
+}
+
+// NewPGNEncoder returns an encoder writing to w.  By default lines are
+// at most 80 characters, comments, glyphs and variations are included
+// and the Seven Tag Roster comes first.
+func NewPGNEncoder(w io.Writer, options ...func(*PGNEncoder)) *PGNEncoder {
+	e := &PGNEncoder{
+		w:            w,
+		lineWidth:    80,
+		comments:     true,
+		variations:   true,
+		tagOrder:     sevenTagRoster,
+		resumeNumber: true,
+	}
+	for _, f := range options {
+		f(e)
+	}
+	return e
+}
+
+// PGNLineWidth is an option for NewPGNEncoder that wraps the move text
+// at the given number of characters.  A width of zero writes the move
+// text on a single line.
+func PGNLineWidth(n int) func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.lineWidth = n
+	}
+}
+
+// PGNWithoutComments is an option for NewPGNEncoder that leaves out
+// comments and numeric annotation glyphs.
+func PGNWithoutComments() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.comments = false
+	}
+}
+
+// PGNWithoutVariations is an option for NewPGNEncoder that only writes
+// the main line.
+func PGNWithoutVariations() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.variations = false
+	}
+}
+
+// PGNTagOrder is an option for NewPGNEncoder that writes the tags with
+// the given keys first in the given order.
+func PGNTagOrder(keys ...string) func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.tagOrder = keys
+	}
+}
+
+// PGNCompactMoveNumbers is an option for NewPGNEncoder that writes the
+// move numbers without a space before the move, as in "1.e4 e5 2.Nf3".
+func PGNCompactMoveNumbers() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.compactNumber = true
+	}
+}
+
+// PGNWithoutResumedMoveNumbers is an option for NewPGNEncoder that
+// leaves out the move number of a black move following a comment,
+// glyph or variation, as in "1. e4 {best by test} e5".  Lines starting
+// with a black move still begin with its number.
+func PGNWithoutResumedMoveNumbers() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.resumeNumber = false
+	}
+}
+
+// PGNResultOnOwnLine is an option for NewPGNEncoder that writes the
+// result on a line of its own after the move text.
+func PGNResultOnOwnLine() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.resultLine = true
+	}
+}
+
+// PGNReduced is an option for NewPGNEncoder that writes the reduced
+// export format: only the Seven Tag Roster and the set up tags are
+// written and the move text is the main line without comments, glyphs
+// or variations.
+func PGNReduced() func(*PGNEncoder) {
+	return func(e *PGNEncoder) {
+		e.reduced = true
+		e.comments = false
+		e.variations = false
+		e.tagOrder = sevenTagRoster
+	}
+}
+
+// Encode writes the game to the encoder's writer.
+func (e *PGNEncoder) Encode(g *Game) error {
+	var sb strings.Builder
+	for _, tag := range e.tags(g) {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", tag.Key, tagValueEscaper.Replace(tag.Value))
+	}
+	if start := g.positions[0]; !start.IsStandardStart() {
+		fmt.Fprintf(&sb, "[SetUp \"1\"]\n[FEN \"%s\"]\n", start)
+	}
+	sb.WriteString("\n")
+	tokens := []string{}
+	if e.comments && g.root.comment != "" {
+		tokens = append(tokens, commentTokens(g.root.comment)...)
+	}
+	tokens = append(tokens, e.lineTokens(g.mainLine(), true)...)
+	if e.resultLine && len(tokens) > 0 {
+		sb.WriteString(wrapTokens(tokens, e.lineWidth))
+		sb.WriteString("\n")
+		tokens = nil
+	}
+	tokens = append(tokens, string(g.outcome))
+	sb.WriteString(wrapTokens(tokens, e.lineWidth))
+	sb.WriteString("\n\n")
+	_, err := io.WriteString(e.w, sb.String())
+	return err
+}
+
+// tags returns the game's tags in the encoder's order leaving out the
+// set up tags, which are derived from the starting position.
+func (e *PGNEncoder) tags(g *Game) []*TagPair {
+	tags := []*TagPair{}
+	written := map[string]bool{"SetUp": true, "FEN": true}
+	keys := append(append([]string{}, e.tagOrder...), sevenTagRoster...)
+	for _, key := range keys {
+		tag := g.GetTagPair(key)
+		if tag == nil && rosterTag(key) {
+			tag = &TagPair{Key: key, Value: unknownTagValues[key]}
+			if key == "Result" {
+				tag.Value = string(g.outcome)
+			}
+		}
+		if tag != nil && !written[key] {
+			tags = append(tags, tag)
+			written[key] = true
+		}
+	}
+	if e.reduced {
+		return tags
+	}
+	for _, tag := range g.tagPairs {
+		if !written[tag.Key] {
+			tags = append(tags, tag)
+			written[tag.Key] = true
+		}
+	}
+	return tags
+}
+
+// rosterTag returns true if the key belongs to the Seven Tag Roster.
+func rosterTag(key string) bool {
+	for _, k := range sevenTagRoster {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// lineTokens returns the move text tokens of the nodes, which follow
+// each other from a node's parent.  The variations of the first node
+// are only included for the main line since a variation's siblings are
+// written by the enclosing line.
+func (e *PGNEncoder) lineTokens(nodes []*Node, mainLine bool) []string {
+	tokens := []string{}
+	numbered := false
+	for i, node := range nodes {
+		pos := node.parent.pos
+		number := ""
+		switch {
+		case pos.turn == White:
+			number = fmt.Sprintf("%d.", pos.moveCount)
+		case !numbered && (i == 0 || e.resumeNumber):
+			number = fmt.Sprintf("%d...", pos.moveCount)
+		}
+		move := AlgebraicNotation{}.Encode(pos, node.move)
+		switch {
+		case number == "":
+			tokens = append(tokens, move)
+		case e.compactNumber:
+			tokens = append(tokens, number+move)
+		default:
+			tokens = append(tokens, number, move)
+		}
+		numbered = true
+		if e.comments {
+			for _, nag := range node.nags {
+				tokens = append(tokens, fmt.Sprintf("$%d", nag))
+				numbered = false
+			}
+			if node.comment != "" {
+				tokens = append(tokens, commentTokens(node.comment)...)
+				numbered = false
+			}
+		}
+		if !e.variations || (i == 0 && !mainLine) {
+			continue
+		}
+		for _, v := range node.parent.Variations() {
+			line := []*Node{}
+			for cur := v; cur != nil; cur = cur.MainLine() {
+				line = append(line, cur)
+			}
+			vt := e.lineTokens(line, false)
+			vt[0] = "(" + vt[0]
+			vt[len(vt)-1] += ")"
+			tokens = append(tokens, vt...)
+			numbered = false
+		}
+	}
+	return tokens
+}
+
+// commentTokens splits the comment into words so it can be wrapped.
+func commentTokens(comment string) []string {
+	words := strings.Fields(comment)
+	if len(words) == 0 {
+		return []string{"{}"}
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	return words
+}
+
+// wrapTokens joins the tokens with spaces starting a new line before a
+// token that would make the line longer than width.
+func wrapTokens(tokens []string, width int) string {
+	var sb strings.Builder
+	n := 0
+	for _, t := range tokens {
+		switch {
+		case n == 0:
+		case width > 0 && n+1+len(t) > width:
+			sb.WriteString("\n")
+			n = 0
+		default:
+			sb.WriteString(" ")
+			n++
+		}
+		sb.WriteString(t)
+		n += len(t)
+	}
+	return sb.String()
+}
@@ -3,8 +3,12 @@ package chess
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
 	"strings"
 )
 
@@ -24,14 +28,19 @@ type CastleRights string
 // CanCastle returns true if the given color and side combination
 // can castle, otherwise returns false.
 func (cr CastleRights) CanCastle(c Color, side Side) bool {
-	char := "k"
-	if side == QueenSide {
-		char = "q"
-	}
-	if c == White {
-		char = strings.ToUpper(char)
+	return strings.Contains(string(cr), castleChar(c, side))
+}
+
+func castleChar(c Color, side Side) string {
+	switch {
+	case c == White && side == QueenSide:
+		return "Q"
+	case c == White:
+		return "K"
+	case side == QueenSide:
+		return "q"
 	}
-	return strings.Contains(string(cr), char)
+	return "k"
 }
 
 // String implements the fmt.Stringer interface and returns
@@ -40,6 +49,54 @@ func (cr CastleRights) String() string {
 	return string(cr)
 }
 
+// castleSides are the sides castled on in the order of the rights.
+var castleSides = []Side{KingSide, QueenSide}
+
+// castling holds the home files of the king and the rooks each color
+// castles with, indexed by color and side.
+type castling struct {
+	king  [3]File
+	rooks [3][3]File
+}
+
+// standardCastling is the castling of positions without their own.
+var standardCastling = castling{
+	king: [3]File{White: FileE, Black: FileE},
+	rooks: [3][3]File{
+		White: {KingSide: FileH, QueenSide: FileA},
+		Black: {KingSide: FileH, QueenSide: FileA},
+	},
+}
+
+// castleSquares returns the home squares of the king and of the rook
+// the color castles with on the side.
+func (pos *Position) castleSquares(c Color, side Side) (king, rook Square) {
+	cs := pos.castling
+	if cs == nil {
+		cs = &standardCastling
+	}
+	rank := backRank(c)
+	return getSquare(cs.king[c], rank), getSquare(cs.rooks[c][side], rank)
+}
+
+// castleDestinations returns the squares the king and rook land on
+// when the color castles on the side, which are the same in standard
+// chess and Chess960.
+func castleDestinations(c Color, side Side) (king, rook Square) {
+	rank := backRank(c)
+	if side == KingSide {
+		return getSquare(FileG, rank), getSquare(FileF, rank)
+	}
+	return getSquare(FileC, rank), getSquare(FileD, rank)
+}
+
+func backRank(c Color) Rank {
+	if c == Black {
+		return Rank8
+	}
+	return Rank1
+}
+
 // Position represents the state of the game without reguard
 // to its outcome.  Position is translatable to FEN notation.
 type Position struct {
@@ -52,6 +109,15 @@ type Position struct {
 	inCheck         bool
 	validMoves      []*Move
 	hash            uint64
+	// pockets is nil unless the position has pockets as in Crazyhouse
+	pockets *pockets
+	// checks is nil unless the checks given are counted as in Three-check
+	checks *[3]int
+	// castling is nil unless the king and rooks castle from other files
+	// than in standard chess as in Chess960
+	castling *castling
+	// variant is nil for standard chess
+	variant Variant
 }
 
 const (
@@ -65,67 +131,468 @@ func StartingPosition() *Position {
 	return pos
 }
 
+// IsStandardStart returns true if the position is the standard
+// starting position including castling rights and move counters.
+func (pos *Position) IsStandardStart() bool {
+	return pos.String() == startFEN
+}
+
 // Update returns a new position resulting from the given move.
 // The move itself isn't validated, if validation is needed use
 // Game's Move method.  This method is more performant for bots that
-// rely on the ValidMoves because it skips redundant validation.
+// rely on the ValidMoves because it skips redundant validation.  The
+// move is played by the rules of the position's variant.  A nil move
+// and the null move both pass the turn as in NullMove.
 func (pos *Position) Update(m *Move) *Position {
-	// if a null move was made we only change the active turn
-	if m == nil {
-		return &Position{
-			board:           pos.board,
-			turn:            pos.turn.Other(),
-			castleRights:    pos.castleRights,
-			enPassantSquare: pos.enPassantSquare,
-			halfMoveClock:   pos.halfMoveClock,
-			moveCount:       pos.moveCount,
-			inCheck:         pos.inCheck,
-		}
+	if m == nil || m.IsNull() {
+		return pos.passTurn()
+	}
+	if pos.variant != nil && m != nil {
+		return pos.variant.Update(pos, m)
+	}
+	return pos.update(m)
+}
+
+// update plays the move by the rules of standard chess.
+func (pos *Position) update(m *Move) *Position {
+	next := pos.play(m, pos.board.copy())
+	return &next
+}
+
+// play returns the position after the move by the rules of standard
+// chess with the board updated to b, which is either a copy of the
+// position's board or the board itself when the move is played in
+// place.  The position's board is only read before b is updated.
+func (pos *Position) play(m *Move, b *Board) Position {
+	// hand built moves may lack the tags needed to update the board
+	untagged := m.tags == 0
+	if untagged {
+		cp := *m
+		m = &cp
+		pos.addMoveTags(m)
 	}
 	moveCount := pos.moveCount
 	if pos.turn == Black {
 		moveCount++
 	}
-	cr := pos.CastleRights()
 	ncr := pos.updateCastleRights(m)
 	p := pos.board.Piece(m.S1)
 	halfMove := pos.halfMoveClock
-	if p.Type() == Pawn || m.HasTag(Capture) || cr != ncr {
+	// only pawn moves, pawn drops and captures reset the half move clock
+	if p.Type() == Pawn || m.drop == Pawn || m.HasTag(Capture) {
 		halfMove = 0
 	} else {
 		halfMove++
 	}
-	b := pos.board.copy()
-	b.update(m)
-	hash := uint64(0)
-	if pos.hash != 0 {
-		hash = UpdateZobristHash(pos, m)
+	ep := pos.updateEnPassantSquare(m)
+	// the hash is updated incrementally, only unhashed positions such as
+	// those decoded from FEN are hashed from scratch
+	hash := pos.hash
+	if hash == 0 {
+		hash = generateZobristHash(pos)
 	}
-	return &Position{
+	hash = updateZobristHash(hash, pos, m, ncr, ep)
+	pockets := pos.updatePockets(m)
+	pos.updateBoard(b, m)
+	next := Position{
 		board:           b,
 		turn:            pos.turn.Other(),
 		castleRights:    ncr,
-		enPassantSquare: pos.updateEnPassantSquare(m),
+		enPassantSquare: ep,
 		halfMoveClock:   halfMove,
 		moveCount:       moveCount,
 		inCheck:         m.HasTag(Check),
 		hash:            hash,
+		pockets:         pockets,
+		castling:        pos.castling,
+		variant:         pos.variant,
+	}
+	if untagged {
+		// isInCheck is given a copy so next can stay on the stack
+		if pos.variant == nil {
+			next.inCheck = isKingAttacked(&Position{board: b, turn: next.turn})
+		} else {
+			cp := next
+			next.inCheck = isInCheck(&cp)
+		}
+	}
+	next.checks = pos.updateChecks(next.inCheck)
+	return next
+}
+
+// NormalizeTags adds the Capture, EnPassant, castling and check tags to
+// the move based on the position so that a hand built move behaves like
+// a move returned by ValidMoves or a notation's Decode method.  The move
+// itself isn't validated.
+func (pos *Position) NormalizeTags(m *Move) {
+	pos.addMoveTags(m)
+	switch checkers := pos.checkersAfter(m); {
+	case checkers.count() > 1:
+		m.addTag(Check | DoubleCheck)
+	case checkers != 0:
+		m.addTag(Check)
+	}
+}
+
+// addMoveTags adds the tags that depend only on the board to the move.
+func (pos *Position) addMoveTags(m *Move) {
+	p := pos.board.Piece(m.S1)
+	if p.Type() == King {
+		switch pos.castleSide(p.Color(), m) {
+		case KingSide:
+			m.addTag(KingSideCastle)
+			return
+		case QueenSide:
+			m.addTag(QueenSideCastle)
+			return
+		}
+	}
+	switch {
+	case pos.board.isOccupied(m.S2):
+		m.addTag(Capture)
+	case p.Type() == Pawn && m.S2 == pos.enPassantSquare && m.S1.File() != m.S2.File():
+		m.addTag(EnPassant)
+	}
+}
+
+// castleSide returns the side the color's king move castles on or 0 if
+// it doesn't castle.  A king move from its home square to a castling
+// destination castles unless it is a step to the next file, which is
+// an ordinary king move in Chess960.
+func (pos *Position) castleSide(c Color, m *Move) Side {
+	for _, side := range castleSides {
+		king, _ := pos.castleSquares(c, side)
+		kingTo, _ := castleDestinations(c, side)
+		if m.S1 == king && m.S2 == kingTo && abs(int(m.S2.File())-int(m.S1.File())) != 1 {
+			return side
+		}
+	}
+	return 0
+}
+
+// NullMove returns a new position in which the side to move passes
+// the turn to the opponent without moving.  The en passant square is
+// cleared and the move counters advance as for a quiet move.  Null
+// moves are only legal outside of check, so nil is returned if the
+// side to move is in check.
+func (pos *Position) NullMove() *Position {
+	if isInCheck(pos) {
+		return nil
+	}
+	return pos.passTurn()
+}
+
+// passTurn returns the position after a null move without checking that
+// the side to move is out of check.
+func (pos *Position) passTurn() *Position {
+	moveCount := pos.moveCount
+	if pos.turn == Black {
+		moveCount++
+	}
+	return &Position{
+		board:           pos.board.copy(),
+		turn:            pos.turn.Other(),
+		castleRights:    pos.castleRights,
+		enPassantSquare: NoSquare,
+		halfMoveClock:   pos.halfMoveClock + 1,
+		moveCount:       moveCount,
+		pockets:         pos.pockets,
+		checks:          pos.checks,
+		castling:        pos.castling,
+		variant:         pos.variant,
 	}
 }
 
-// ValidMoves returns a list of valid moves for the position.
+// Mirror returns the position as seen from the other side of the board.
+// The board is flipped vertically, the piece colors, castling rights and
+// side to move are swapped, and the en passant square is flipped.
+func (pos *Position) Mirror() *Position {
+	m := map[Square]Piece{}
+	for sq, p := range pos.board.SquareMap() {
+		m[sq.mirror()] = getPiece(p.Type(), p.Color().Other())
+	}
+	cr := ""
+	for _, c := range []Color{White, Black} {
+		if pos.castleRights.CanCastle(c.Other(), KingSide) {
+			cr += castleChar(c, KingSide)
+		}
+		if pos.castleRights.CanCastle(c.Other(), QueenSide) {
+			cr += castleChar(c, QueenSide)
+		}
+	}
+	if cr == "" {
+		cr = "-"
+	}
+	enPassant := NoSquare
+	if pos.enPassantSquare != NoSquare {
+		enPassant = pos.enPassantSquare.mirror()
+	}
+	var pk *pockets
+	if pos.pockets != nil {
+		pk = &pockets{}
+		pk.counts[White], pk.counts[Black] = pos.pockets.counts[Black], pos.pockets.counts[White]
+		for sq := range m {
+			if pos.IsPromoted(sq.mirror()) {
+				pk.promoted |= bbForSquare(sq)
+			}
+		}
+	}
+	var checks *[3]int
+	if pos.checks != nil {
+		checks = &[3]int{}
+		checks[White], checks[Black] = pos.checks[Black], pos.checks[White]
+	}
+	var cs *castling
+	if pos.castling != nil {
+		cs = &castling{}
+		cs.king[White], cs.king[Black] = pos.castling.king[Black], pos.castling.king[White]
+		cs.rooks[White], cs.rooks[Black] = pos.castling.rooks[Black], pos.castling.rooks[White]
+	}
+	return &Position{
+		board:           NewBoard(m),
+		turn:            pos.turn.Other(),
+		castleRights:    CastleRights(cr),
+		enPassantSquare: enPassant,
+		halfMoveClock:   pos.halfMoveClock,
+		moveCount:       pos.moveCount,
+		inCheck:         pos.inCheck,
+		pockets:         pk,
+		checks:          checks,
+		castling:        cs,
+		variant:         pos.variant,
+	}
+}
+
+// ValidMoves returns a list of valid moves for the position by the
+// rules of its variant.
 func (pos *Position) ValidMoves() []*Move {
-	if pos.validMoves != nil {
-		return append([]*Move(nil), pos.validMoves...)
+	return append([]*Move(nil), pos.calcMoves()...)
+}
+
+// ValidMovesInto appends the valid moves for the position to buf and
+// returns the extended slice.  Search code can reuse buf between nodes
+// to generate the moves of standard positions without allocating.
+// Unlike ValidMoves the moves aren't cached by the position.
+func (pos *Position) ValidMovesInto(buf []Move) []Move {
+	if pos.validMoves != nil || pos.variant != nil {
+		for _, m := range pos.calcMoves() {
+			buf = append(buf, *m)
+		}
+		return buf
 	}
-	pos.validMoves = engine{}.CalcMoves(pos, false)
-	return append([]*Move(nil), pos.validMoves...)
+	return engine{}.AppendMoves(pos, buf)
+}
+
+// calcMoves returns the cached valid moves, generating them on first use.
+func (pos *Position) calcMoves() []*Move {
+	if pos.validMoves == nil {
+		if pos.variant != nil {
+			pos.validMoves = pos.variant.ValidMoves(pos)
+		} else {
+			pos.validMoves = engine{}.CalcMoves(pos, false)
+		}
+	}
+	return pos.validMoves
+}
+
+// RandomMove returns a uniformly random valid move chosen with the
+// given source of randomness.  False is returned if there are no valid
+// moves.
+func (pos *Position) RandomMove(rng *rand.Rand) (*Move, bool) {
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return nil, false
+	}
+	return moves[rng.Intn(len(moves))], true
+}
+
+// MovesFrom returns the valid moves of the piece on the given square.
+// An empty slice is returned if the square is empty or occupied by a
+// piece of the side not to move.
+func (pos *Position) MovesFrom(sq Square) []*Move {
+	moves := []*Move{}
+	for _, m := range pos.ValidMoves() {
+		if m.S1 == sq && m.drop == NoPieceType {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// MovesTo returns the valid moves that end on the given square.
+func (pos *Position) MovesTo(sq Square) []*Move {
+	moves := []*Move{}
+	for _, m := range pos.ValidMoves() {
+		if m.S2 == sq {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// HasLegalMoves returns true if the side to move has at least one
+// legal move.  Move generation stops at the first legal move found.
+func (pos *Position) HasLegalMoves() bool {
+	if pos.validMoves != nil || pos.variant != nil {
+		return len(pos.calcMoves()) > 0
+	}
+	return len(engine{}.CalcMoves(pos, true)) > 0
+}
+
+// IsForced returns true if the side to move has exactly one legal move.
+func (pos *Position) IsForced() bool {
+	return pos.LegalMoveCount() == 1
+}
+
+// LegalMoveCount returns the number of legal moves in the position.
+func (pos *Position) LegalMoveCount() int {
+	return len(pos.calcMoves())
+}
+
+// CaptureMoves returns the valid moves for the position that capture
+// a piece, capture en passant or promote a pawn.  Quiet moves are not
+// generated which makes this cheaper than filtering ValidMoves.
+func (pos *Position) CaptureMoves() []*Move {
+	if pos.validMoves != nil || pos.variant != nil {
+		pos.calcMoves()
+		moves := []*Move{}
+		for _, m := range pos.validMoves {
+			if m.HasTag(Capture) || m.HasTag(EnPassant) || m.promo != NoPieceType {
+				moves = append(moves, m)
+			}
+		}
+		return moves
+	}
+	return engine{}.CalcCaptures(pos)
+}
+
+// QuietMoves returns the valid moves for the position that don't
+// capture, capture en passant or promote, which are the moves left out
+// by CaptureMoves.  Together they allow search to order the moves in
+// stages without generating them all up front.
+func (pos *Position) QuietMoves() []*Move {
+	if pos.validMoves != nil || pos.variant != nil {
+		moves := []*Move{}
+		for _, m := range pos.calcMoves() {
+			if !m.HasTag(Capture) && !m.HasTag(EnPassant) && m.promo == NoPieceType {
+				moves = append(moves, m)
+			}
+		}
+		return moves
+	}
+	return engine{}.CalcQuiets(pos)
+}
+
+// PseudoLegalMoves returns the moves of the position's pieces without
+// checking if they leave the king in check.  IsLegal can filter the
+// moves when they are searched, which saves the cost of checking moves
+// that are pruned.  Castles and drops are only returned if they are
+// legal and variants return their valid moves.
+func (pos *Position) PseudoLegalMoves() []*Move {
+	if pos.validMoves != nil || pos.variant != nil {
+		return pos.ValidMoves()
+	}
+	return engine{}.CalcPseudoLegal(pos)
+}
+
+// PromotionMoves returns the valid promotion moves for the position
+// grouped by their destination square.
+func (pos *Position) PromotionMoves() map[Square][]*Move {
+	m := map[Square][]*Move{}
+	var moves []*Move
+	if pos.variant != nil {
+		moves = pos.calcMoves()
+	} else {
+		moves = engine{}.CalcPromotions(pos)
+	}
+	for _, move := range moves {
+		if move.promo == NoPieceType {
+			continue
+		}
+		m[move.S2] = append(m[move.S2], move)
+	}
+	return m
+}
+
+// DragConstraints returns the legal destination squares of each of the
+// side to move's pieces keyed by the square the piece stands on.  Pieces
+// without a legal move are omitted and promotions to different piece
+// types share a single destination square.
+func (pos *Position) DragConstraints() map[Square][]Square {
+	m := map[Square][]Square{}
+	for _, move := range pos.ValidMoves() {
+		if move.drop != NoPieceType {
+			continue
+		}
+		sqs := m[move.S1]
+		if len(sqs) > 0 && sqs[len(sqs)-1] == move.S2 {
+			continue
+		}
+		m[move.S1] = append(sqs, move.S2)
+	}
+	return m
+}
+
+// KingDestinations returns the squares the king of the side to move can
+// legally move to including the destination squares of castling.
+func (pos *Position) KingDestinations() []Square {
+	sqs := []Square{}
+	kingSq := pos.board.kingSquare(pos.turn)
+	if kingSq == NoSquare {
+		return sqs
+	}
+	for _, m := range pos.MovesFrom(kingSq) {
+		sqs = append(sqs, m.S2)
+	}
+	return sqs
+}
+
+// EvasionMoves returns the valid moves for the position when the side
+// to move is in check.  Only king moves, captures of the checking piece
+// and interpositions are generated, and in double check only king moves.
+// If the side to move isn't in check an empty slice is returned.
+func (pos *Position) EvasionMoves() []*Move {
+	if pos.variant != nil {
+		if !pos.inCheck {
+			return []*Move{}
+		}
+		return pos.ValidMoves()
+	}
+	return engine{}.CalcEvasions(pos)
 }
 
 // Status returns the position's status as one of the outcome methods.
-// Possible returns values include Checkmate, Stalemate, and NoMethod.
+// Possible returns values include Checkmate, Stalemate,
+// InsufficientMaterial, VariantEnd and NoMethod.
 func (pos *Position) Status() Method {
-	return engine{}.Status(pos)
+	_, method := pos.status()
+	return method
+}
+
+// status returns the outcome and method of the position by the rules
+// of its variant.
+func (pos *Position) status() (Outcome, Method) {
+	if pos.variant != nil {
+		return pos.variant.Status(pos)
+	}
+	return Standard{}.Status(pos)
+}
+
+// InsufficientMaterial returns true if neither side has the material to
+// checkmate: king versus king, king and a minor piece versus king, or
+// kings and bishops that are all on squares of the same color.  It is
+// always false for positions with pockets where captured pieces return
+// to play.  Variants decide themselves if material is insufficient.
+func (pos *Position) InsufficientMaterial() bool {
+	if pos.variant != nil {
+		_, method := pos.variant.Status(pos)
+		return method == InsufficientMaterial
+	}
+	return pos.standardInsufficientMaterial()
+}
+
+func (pos *Position) standardInsufficientMaterial() bool {
+	return pos.pockets == nil && !pos.board.hasSufficientMaterial()
 }
 
 // Board returns the position's board.
@@ -143,19 +610,68 @@ func (pos *Position) CastleRights() CastleRights {
 	return pos.castleRights
 }
 
+// HalfMoveClock returns the number of half moves since the last
+// capture or pawn move.
+func (pos *Position) HalfMoveClock() int {
+	return pos.halfMoveClock
+}
+
+// FullMoveNumber returns the number of the full move, which starts
+// at one and is incremented after each of black's moves.
+func (pos *Position) FullMoveNumber() int {
+	return pos.moveCount
+}
+
 // String implements the fmt.Stringer interface and returns a
 // string with the FEN format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+// Chess960 positions write their castling rights in X-FEN.
 func (pos *Position) String() string {
+	return pos.fen(false)
+}
+
+// fen returns the FEN of the position with the castling rights in
+// Shredder-FEN if shredder is true and otherwise in X-FEN.
+func (pos *Position) fen(shredder bool) string {
 	b := pos.board.String()
+	if pos.pockets != nil {
+		b = pos.pockets.fen(b)
+	}
 	t := pos.turn.String()
-	c := pos.castleRights.String()
+	c := pos.fenCastleRights(shredder)
 	sq := "-"
 	if pos.enPassantSquare != NoSquare {
 		sq = pos.enPassantSquare.String()
 	}
+	if pos.checks != nil {
+		sq += " " + pos.checksFEN()
+	}
 	return fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
 }
 
+// FEN returns the FEN of the position.  It's the same as String.
+func (pos *Position) FEN() string {
+	return pos.String()
+}
+
+// ShredderFEN returns the FEN of the position with the castling rights
+// written as the files of the rooks such as HAha for the starting
+// position, which is common for Chess960.  FromFEN decodes it into a
+// Chess960 position.
+func (pos *Position) ShredderFEN() string {
+	return pos.fen(true)
+}
+
+// CompactFEN returns the shortest FEN of the position.  The half move
+// clock and move count are omitted when they have their default values
+// of 0 and 1.  The result can be decoded like any other FEN.
+func (pos *Position) CompactFEN() string {
+	fen := pos.String()
+	if pos.halfMoveClock == 0 && pos.moveCount == 1 {
+		return strings.TrimSuffix(fen, " 0 1")
+	}
+	return fen
+}
+
 // Hash returns a unique hash of the position
 func (pos *Position) Hash() uint64 {
 	if pos.hash == 0 {
@@ -170,7 +686,7 @@ func (pos *Position) MarshalText() (text []byte, err error) {
 	return []byte(pos.String()), nil
 }
 
-// UnmarshalText implements the encoding.TextUnarshaler interface and
+// UnmarshalText implements the encoding.TextUnmarshaler interface and
 // assumes the data is in the FEN format.
 func (pos *Position) UnmarshalText(text []byte) error {
 	cp, err := decodeFEN(string(text))
@@ -183,10 +699,32 @@ func (pos *Position) UnmarshalText(text []byte) error {
 	pos.enPassantSquare = cp.enPassantSquare
 	pos.halfMoveClock = cp.halfMoveClock
 	pos.moveCount = cp.moveCount
+	pos.pockets = cp.pockets
+	pos.checks = cp.checks
+	pos.castling = cp.castling
+	pos.variant = cp.variant
 	pos.inCheck = isInCheck(cp)
+	pos.validMoves = nil
+	pos.hash = 0
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface and encodes the
+// position's FEN as a JSON string.
+func (pos *Position) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pos.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and decodes a
+// JSON string in the FEN format.
+func (pos *Position) UnmarshalJSON(b []byte) error {
+	var fen string
+	if err := json.Unmarshal(b, &fen); err != nil {
+		return err
+	}
+	return pos.UnmarshalText([]byte(fen))
+}
+
 const (
 	bitsCastleWhiteKing uint8 = 1 << iota
 	bitsCastleWhiteQueen
@@ -196,7 +734,11 @@ const (
 	bitsHasEnPassant
 )
 
-// MarshalBinary implements the encoding.BinaryMarshaler interface
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// Standard chess positions take 101 bytes.  Positions of a built in
+// variant are followed by the variant's name and the pockets, checks
+// and Chess960 castling files the position has.  An error is returned
+// for positions of other variants.
 func (pos *Position) MarshalBinary() (data []byte, err error) {
 	boardBytes, err := pos.board.MarshalBinary()
 	if err != nil {
@@ -234,14 +776,38 @@ func (pos *Position) MarshalBinary() (data []byte, err error) {
 	if err := binary.Write(buf, binary.BigEndian, b); err != nil {
 		return nil, err
 	}
+	if pos.variant != nil {
+		if err := pos.writeVariantBinary(buf); err != nil {
+			return nil, err
+		}
+	}
 	return buf.Bytes(), err
 }
 
-// UnmarshalBinary implements the encoding.BinaryMarshaler interface
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// and decodes the data written by MarshalBinary.  All state of the
+// position is replaced, including its variant.
 func (pos *Position) UnmarshalBinary(data []byte) error {
-	if len(data) != 101 {
-		return errors.New("chess: position binary data should consist of 101 bytes")
+	if len(data) < 101 {
+		return errors.New("chess: position binary data should consist of at least 101 bytes")
+	}
+	decoded := &Position{}
+	if err := decoded.unmarshalStandardBinary(data[:101]); err != nil {
+		return err
 	}
+	if len(data) > 101 {
+		if err := decoded.readVariantBinary(bytes.NewReader(data[101:])); err != nil {
+			return err
+		}
+	}
+	decoded.inCheck = isInCheck(decoded)
+	*pos = *decoded
+	return nil
+}
+
+// unmarshalStandardBinary decodes the board, counters, castling rights,
+// turn and en passant square written for every position.
+func (pos *Position) unmarshalStandardBinary(data []byte) error {
 	board := &Board{}
 	if err := board.UnmarshalBinary(data[:96]); err != nil {
 		return err
@@ -288,7 +854,117 @@ func (pos *Position) UnmarshalBinary(data []byte) error {
 	if b&bitsHasEnPassant == 0 {
 		pos.enPassantSquare = NoSquare
 	}
-	pos.inCheck = isInCheck(pos)
+	return nil
+}
+
+const (
+	bitsHasPockets uint8 = 1 << iota
+	bitsHasChecks
+	bitsHasCastling
+)
+
+// writeVariantBinary writes the variant's name followed by the state
+// only variant positions have.
+func (pos *Position) writeVariantBinary(buf *bytes.Buffer) error {
+	if v, ok := VariantByName(pos.variant.Name()); !ok || reflect.TypeOf(v) != reflect.TypeOf(pos.variant) {
+		return fmt.Errorf("chess: position of variant %s can't be encoded in binary", pos.variant.Name())
+	}
+	writeString(buf, pos.variant.Name())
+	var b uint8
+	if pos.pockets != nil {
+		b |= bitsHasPockets
+	}
+	if pos.checks != nil {
+		b |= bitsHasChecks
+	}
+	if pos.castling != nil {
+		b |= bitsHasCastling
+	}
+	buf.WriteByte(b)
+	if pos.pockets != nil {
+		for _, c := range []Color{White, Black} {
+			for _, pt := range dropPieceTypes {
+				buf.WriteByte(byte(pos.pockets.count(c, pt)))
+			}
+		}
+		if err := binary.Write(buf, binary.BigEndian, uint64(pos.pockets.promoted)); err != nil {
+			return err
+		}
+	}
+	if pos.checks != nil {
+		buf.Write([]byte{byte(pos.checks[White]), byte(pos.checks[Black])})
+	}
+	if cs := pos.castling; cs != nil {
+		for _, c := range []Color{White, Black} {
+			buf.Write([]byte{byte(cs.king[c]), byte(cs.rooks[c][KingSide]), byte(cs.rooks[c][QueenSide])})
+		}
+	}
+	return nil
+}
+
+// readVariantBinary reads the variant state written by
+// writeVariantBinary.
+func (pos *Position) readVariantBinary(r *bytes.Reader) error {
+	err := errors.New("chess: position binary data has an invalid variant")
+	name, nerr := readString(r)
+	if nerr != nil {
+		return err
+	}
+	v, ok := VariantByName(name)
+	if !ok {
+		return fmt.Errorf("chess: position binary data has unknown variant %s", name)
+	}
+	pos.variant = v
+	b, berr := r.ReadByte()
+	if berr != nil {
+		return err
+	}
+	if b&bitsHasPockets != 0 {
+		pos.pockets = &pockets{}
+		for _, c := range []Color{White, Black} {
+			for _, pt := range dropPieceTypes {
+				n, nerr := r.ReadByte()
+				if nerr != nil {
+					return err
+				}
+				pos.pockets.counts[c][pt] = int(n)
+			}
+		}
+		var promoted uint64
+		if binary.Read(r, binary.BigEndian, &promoted) != nil {
+			return err
+		}
+		pos.pockets.promoted = bitboard(promoted)
+	}
+	if b&bitsHasChecks != 0 {
+		pos.checks = &[3]int{}
+		for _, c := range []Color{White, Black} {
+			n, nerr := r.ReadByte()
+			if nerr != nil {
+				return err
+			}
+			pos.checks[c] = int(n)
+		}
+	}
+	if b&bitsHasCastling != 0 {
+		cs := &castling{}
+		for _, c := range []Color{White, Black} {
+			files := make([]byte, 3)
+			if _, ferr := io.ReadFull(r, files); ferr != nil {
+				return err
+			}
+			for _, f := range files {
+				if f >= numOfSquaresInRow {
+					return err
+				}
+			}
+			cs.king[c], cs.rooks[c][KingSide], cs.rooks[c][QueenSide] = File(files[0]), File(files[1]), File(files[2])
+		}
+		pos.castling = cs
+	}
+	if r.Len() != 0 {
+		return err
+	}
 	return nil
 }
 
@@ -301,23 +977,27 @@ func (pos *Position) copy() *Position {
 		halfMoveClock:   pos.halfMoveClock,
 		moveCount:       pos.moveCount,
 		inCheck:         pos.inCheck,
+		pockets:         pos.pockets,
+		checks:          pos.checks,
+		castling:        pos.castling,
+		variant:         pos.variant,
 	}
 }
 
+// updateCastleRights returns the castling rights after the move, which
+// loses a right by moving the king or the rook or capturing the rook.
 func (pos *Position) updateCastleRights(m *Move) CastleRights {
 	cr := string(pos.castleRights)
-	p := pos.board.Piece(m.S1)
-	if p == WhiteKing || m.S1 == H1 || m.S2 == H1 {
-		cr = strings.Replace(cr, "K", "", -1)
-	}
-	if p == WhiteKing || m.S1 == A1 || m.S2 == A1 {
-		cr = strings.Replace(cr, "Q", "", -1)
-	}
-	if p == BlackKing || m.S1 == H8 || m.S2 == H8 {
-		cr = strings.Replace(cr, "k", "", -1)
+	if cr == "-" {
+		return pos.castleRights
 	}
-	if p == BlackKing || m.S1 == A8 || m.S2 == A8 {
-		cr = strings.Replace(cr, "q", "", -1)
+	for _, c := range []Color{White, Black} {
+		for _, side := range castleSides {
+			king, rook := pos.castleSquares(c, side)
+			if m.S1 == king || m.S1 == rook || m.S2 == rook {
+				cr = strings.Replace(cr, castleChar(c, side), "", -1)
+			}
+		}
 	}
 	if cr == "" {
 		cr = "-"
@@ -330,21 +1010,68 @@ func (pos *Position) updateEnPassantSquare(m *Move) Square {
 	if p.Type() != Pawn {
 		return NoSquare
 	}
+	sq := NoSquare
 	if pos.turn == White &&
 		(bbForSquare(m.S1)&bbRank2) != 0 &&
 		(bbForSquare(m.S2)&bbRank4) != 0 {
-		return Square(m.S2 - 8)
+		sq = Square(m.S2 - 8)
 	} else if pos.turn == Black &&
 		(bbForSquare(m.S1)&bbRank7) != 0 &&
 		(bbForSquare(m.S2)&bbRank5) != 0 {
-		return Square(m.S2 + 8)
+		sq = Square(m.S2 + 8)
+	}
+	if sq == NoSquare {
+		return NoSquare
 	}
-	return NoSquare
+	// the resulting position is only built if an enemy pawn is beside
+	// the pushed pawn
+	enemy := getPiece(Pawn, pos.turn.Other())
+	beside := false
+	for _, df := range []int{-1, 1} {
+		if s1, ok := m.S2.Offset(df, 0); ok && pos.board.Piece(s1) == enemy {
+			beside = true
+		}
+	}
+	if !beside {
+		return NoSquare
+	}
+	b := pos.board.copy()
+	b.update(m)
+	next := &Position{board: b, turn: pos.turn.Other(), enPassantSquare: sq}
+	if !next.hasLegalEnPassant() {
+		return NoSquare
+	}
+	return sq
+}
+
+// Equal returns true if the positions are identical including the
+// en passant square and the move counters.
+func (pos *Position) Equal(o *Position) bool {
+	return pos.SamePosition(o) &&
+		pos.enPassantSquare == o.enPassantSquare &&
+		pos.halfMoveClock == o.halfMoveClock &&
+		pos.moveCount == o.moveCount
 }
 
-func (pos *Position) samePosition(poS2 *Position) bool {
-	return pos.board.String() == poS2.board.String() &&
-		pos.turn == poS2.turn &&
-		pos.castleRights.String() == poS2.castleRights.String() &&
-		pos.enPassantSquare == poS2.enPassantSquare
+// SamePosition returns true if the positions are the same for the
+// purpose of repetition: the piece placement, side to move, castling
+// rights, en passant square, pockets and checks given match.  An en
+// passant square only counts if a legal en passant capture exists and
+// the move counters are ignored.
+func (pos *Position) SamePosition(o *Position) bool {
+	return pos.board.String() == o.board.String() &&
+		pos.pockets.equal(o.pockets) &&
+		pos.Checks(White) == o.Checks(White) && pos.Checks(Black) == o.Checks(Black) &&
+		pos.turn == o.turn &&
+		pos.castleRights.String() == o.castleRights.String() &&
+		pos.legalEnPassantSquare() == o.legalEnPassantSquare()
+}
+
+// legalEnPassantSquare returns the en passant square if a legal en
+// passant capture exists and NoSquare otherwise.
+func (pos *Position) legalEnPassantSquare() Square {
+	if pos.enPassantSquare == NoSquare || !pos.hasLegalEnPassant() {
+		return NoSquare
+	}
+	return pos.enPassantSquare
 }
@@ -1,6 +1,10 @@
 package chess
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -23,3 +27,446 @@ func TestPositionBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestPositionBinaryVariants(t *testing.T) {
+	tables := []struct {
+		v   Variant
+		fen string
+	}{
+		{Crazyhouse{}, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQ~KB1R[QPnn] w KQkq - 0 1"},
+		{ThreeCheck{}, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 2+1 0 2"},
+		{Chess960{}, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1"},
+		{Atomic{}, startFEN},
+	}
+	// the decoded positions reuse one whose moves and hash were cached
+	cp := unsafeFEN("4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1")
+	cp.ValidMoves()
+	cp.Hash()
+	for _, table := range tables {
+		pos, err := FromVariantFEN(table.v, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := pos.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cp.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if cp.String() != pos.String() || cp.Variant().Name() != table.v.Name() || cp.ShredderFEN() != pos.ShredderFEN() {
+			t.Fatalf("expected %s in %s but got %s in %s", pos, table.v.Name(), cp, cp.Variant().Name())
+		}
+		if len(cp.ValidMoves()) != len(pos.ValidMoves()) || cp.Hash() != pos.Hash() {
+			t.Fatalf("expected %s in %s to decode with its moves and hash", pos, table.v.Name())
+		}
+		// a standard position decoded afterwards drops the variant
+		std := unsafeFEN(startFEN)
+		b, _ = std.MarshalBinary()
+		if err := cp.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if cp.Variant().Name() != "Standard" || cp.HasPockets() || len(cp.ValidMoves()) != 20 {
+			t.Fatalf("expected the standard position after %s but got %s in %s", table.v.Name(), cp, cp.Variant().Name())
+		}
+	}
+	pos, _ := FromVariantFEN(Crazyhouse{}, crazyhouseStartFEN)
+	b, _ := pos.MarshalBinary()
+	for _, data := range [][]byte{b[:len(b)-1], append(b, 0), append(b[:101:101], 3, 'F', 'o', 'o', 0)} {
+		if err := (&Position{}).UnmarshalBinary(data); err == nil {
+			t.Fatalf("expected an error decoding %v", data)
+		}
+	}
+}
+
+func unsafeFEN(s string) *Position {
+	pos, err := decodeFEN(s)
+	if err != nil {
+		panic(err)
+	}
+	pos.inCheck = isInCheck(pos)
+	return pos
+}
+
+func TestPositionMirror(t *testing.T) {
+	tables := []struct {
+		fen    string
+		mirror string
+	}{
+		{
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1",
+		},
+		{
+			"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 3",
+			"rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w Qk e6 0 3",
+		},
+		{
+			"8/8/8/4k3/8/8/8/R3K2R w K - 5 40",
+			"r3k2r/8/8/8/4K3/8/8/8 b k - 5 40",
+		},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		mirror := pos.Mirror()
+		if mirror.String() != table.mirror {
+			t.Fatalf("expected mirror of %s to be %s but got %s", table.fen, table.mirror, mirror.String())
+		}
+		if back := mirror.Mirror().String(); back != table.fen {
+			t.Fatalf("expected double mirror of %s to be the original but got %s", table.fen, back)
+		}
+	}
+}
+
+func TestPositionNullMove(t *testing.T) {
+	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	null := pos.NullMove()
+	expected := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1 2"
+	if null.String() != expected {
+		t.Fatalf("expected null move to result in %s but got %s", expected, null.String())
+	}
+	// white can still play any of its 30 moves after passing
+	if n := len(null.ValidMoves()); n != 30 {
+		t.Fatalf("expected 30 valid moves after null move but got %d", n)
+	}
+	if pos.String() != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Fatal("expected null move not to modify the original position")
+	}
+	// a nil move and the null move pass the turn the same way
+	for _, m := range []*Move{nil, NewNullMove()} {
+		if next := pos.Update(m); next.String() != expected || next.Hash() != null.Hash() {
+			t.Fatalf("expected updating with %v to result in %s but got %s", m, expected, next)
+		}
+	}
+	if unsafeFEN("4k3/8/8/8/8/8/4r3/4K3 w - - 0 1").NullMove() != nil {
+		t.Fatal("expected null move to be refused in check")
+	}
+}
+
+func TestPositionJSON(t *testing.T) {
+	type record struct {
+		Position *Position `json:"position"`
+		Move     *Move     `json:"move"`
+	}
+	pos := unsafeFEN("r3k3/1P6/8/8/8/8/8/4K3 w q - 0 1")
+	m := &Move{S1: B7, S2: A8, promo: Queen}
+	b, err := json.Marshal(record{Position: pos, Move: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"position":"r3k3/1P6/8/8/8/8/8/4K3 w q - 0 1","move":"b7a8q"}`
+	if string(b) != expected {
+		t.Fatalf("expected json %s but got %s", expected, b)
+	}
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Position.Equal(pos) || r.Move.String() != m.String() {
+		t.Fatalf("expected %s and %s to round trip but got %s and %s", pos, m, r.Position, r.Move)
+	}
+	for _, data := range []string{`{"position":"8/8 w - - 0 1"}`, `{"move":"e2"}`, `{"move":1}`} {
+		if err := json.Unmarshal([]byte(data), &r); err == nil {
+			t.Fatalf("expected an error decoding %s", data)
+		}
+	}
+}
+
+func TestPositionLegalMoves(t *testing.T) {
+	tables := []struct {
+		fen   string
+		count int
+	}{
+		{startFEN, 20},
+		// stalemate
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 0},
+		// checkmate
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", 0},
+		{"k7/8/8/8/8/8/8/7K w - - 0 1", 3},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if has := pos.HasLegalMoves(); has != (table.count > 0) {
+			t.Fatalf("%s expected has legal moves to be %t but got %t", table.fen, table.count > 0, has)
+		}
+		if n := unsafeFEN(table.fen).LegalMoveCount(); n != table.count {
+			t.Fatalf("%s expected %d legal moves but got %d", table.fen, table.count, n)
+		}
+	}
+}
+
+func TestPositionDragConstraints(t *testing.T) {
+	// the e2 bishop is pinned by the e8 rook and the b1 knight is free
+	pos := unsafeFEN("k3r3/8/8/8/8/8/3PB3/1N2K3 w - - 0 1")
+	drag := pos.DragConstraints()
+	if sqs, ok := drag[E2]; ok {
+		t.Fatalf("expected pinned bishop to have no legal squares but got %v", sqs)
+	}
+	if sqs := drag[B1]; !reflect.DeepEqual(sqs, []Square{A3, C3}) {
+		t.Fatalf("expected knight to reach a3 and c3 but got %v", sqs)
+	}
+	// the pinned rook may only move along the pin
+	pos = unsafeFEN("k3r3/8/8/8/8/4R3/8/4K3 w - - 0 1")
+	expected := []Square{E2, E4, E5, E6, E7, E8}
+	if sqs := pos.DragConstraints()[E3]; !reflect.DeepEqual(sqs, expected) {
+		t.Fatalf("expected pinned rook to reach %v but got %v", expected, sqs)
+	}
+	// promotions to different pieces share a destination
+	pos = unsafeFEN("8/4P3/8/8/8/8/8/k3K3 w - - 0 1")
+	if sqs := pos.DragConstraints()[E7]; !reflect.DeepEqual(sqs, []Square{E8}) {
+		t.Fatalf("expected promoting pawn to reach e8 but got %v", sqs)
+	}
+}
+
+func TestPositionEnPassantSquare(t *testing.T) {
+	tables := []struct {
+		fen      string
+		move     string
+		expected string
+	}{
+		// no black pawn can capture after the opening double push
+		{startFEN, "e2e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"},
+		// the d4 pawn can capture en passant
+		{"4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1", "e2e4", "4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1"},
+		// the e4 pawn isn't beside the pushed pawn
+		{"4k3/4p3/8/8/4P3/8/8/4K3 b - - 0 1", "e7e5", "4k3/8/8/4p3/4P3/8/8/4K3 w - - 0 2"},
+		// the e5 pawn can capture en passant
+		{"4k3/3p4/8/4P3/8/8/8/7K b - - 0 1", "d7d5", "4k3/8/8/3pP3/8/8/8/7K w - d6 0 2"},
+		// the white pawn is pinned to its king by the bishop
+		{"4k2b/3p4/8/4P3/8/8/8/K7 b - - 0 1", "d7d5", "4k2b/8/8/3pP3/8/8/8/K7 w - - 0 2"},
+		// capturing would expose the king along the rank
+		{"8/8/8/8/1k3p1R/8/4P3/4K3 w - - 0 1", "e2e4", "8/8/8/8/1k2Pp1R/8/8/4K3 b - - 0 1"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := UCINotation{}.Decode(pos, table.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := pos.Update(m).String(); s != table.expected {
+			t.Fatalf("expected %s after %s but got %s", table.expected, table.move, s)
+		}
+	}
+}
+
+func TestPositionMovesFromTo(t *testing.T) {
+	// the e2 knight is pinned by the e8 rook and white may castle king side
+	pos := unsafeFEN("k3r3/8/8/8/8/2B5/4N3/4K2R w K - 0 1")
+	tables := []struct {
+		moves    []*Move
+		expected []string
+	}{
+		{pos.MovesFrom(E2), []string{}},
+		{pos.MovesFrom(E1), []string{"e1d1", "e1f1", "e1d2", "e1f2", "e1g1"}},
+		{pos.MovesFrom(A8), []string{}},
+		{pos.MovesFrom(D4), []string{}},
+		// the pinned knight can't reach d4
+		{pos.MovesTo(D4), []string{"c3d4"}},
+		{pos.MovesTo(G1), []string{"h1g1", "e1g1"}},
+	}
+	for i, table := range tables {
+		moves := []string{}
+		for _, m := range table.moves {
+			moves = append(moves, m.String())
+		}
+		if !reflect.DeepEqual(moves, table.expected) {
+			t.Fatalf("%d expected moves %v but got %v", i, table.expected, moves)
+		}
+	}
+}
+
+func TestPositionKingDestinations(t *testing.T) {
+	tables := []struct {
+		fen      string
+		expected []Square
+	}{
+		// the b2 rook guards the second rank so the king can step to d1 or f1 and castle
+		{"4k3/8/8/8/8/8/1r6/R3K2R w KQ - 0 1", []Square{D1, F1, G1, C1}},
+		// the king can't step back along the ray of the checking rook
+		{"4k3/8/8/8/8/8/8/r3K3 w - - 0 1", []Square{D2, E2, F2}},
+		// castling through check isn't allowed
+		{"4k3/8/8/8/8/5r2/8/4K2R w K - 0 1", []Square{D1, D2, E2}},
+	}
+	for _, table := range tables {
+		if sqs := unsafeFEN(table.fen).KingDestinations(); !reflect.DeepEqual(sqs, table.expected) {
+			t.Fatalf("%s expected king destinations %v but got %v", table.fen, table.expected, sqs)
+		}
+	}
+}
+
+func TestPositionMoveCounters(t *testing.T) {
+	// Fischer vs Spassky, 1972 game 6, with the half move clock and full
+	// move number of the FEN after each move
+	plies := []struct {
+		move     string
+		halfMove int
+		fullMove int
+	}{
+		{"c4", 0, 1}, {"e6", 0, 2}, {"Nf3", 1, 2}, {"d5", 0, 3},
+		{"d4", 0, 3}, {"Nf6", 1, 4}, {"Nc3", 2, 4}, {"Be7", 3, 5},
+		{"Bg5", 4, 5}, {"O-O", 5, 6}, {"e3", 0, 6}, {"h6", 0, 7},
+		{"Bh4", 1, 7}, {"b6", 0, 8}, {"cxd5", 0, 8}, {"Nxd5", 0, 9},
+		{"Bxe7", 0, 9}, {"Qxe7", 0, 10}, {"Nxd5", 0, 10}, {"exd5", 0, 11},
+		{"Rc1", 1, 11}, {"Be6", 2, 12}, {"Qa4", 3, 12}, {"c5", 0, 13},
+		{"Qa3", 1, 13}, {"Rc8", 2, 14}, {"Bb5", 3, 14}, {"a6", 0, 15},
+		{"dxc5", 0, 15}, {"bxc5", 0, 16}, {"O-O", 1, 16}, {"Ra7", 2, 17},
+		{"Be2", 3, 17}, {"Nd7", 4, 18}, {"Nd4", 5, 18}, {"Qf8", 6, 19},
+		{"Nxe6", 0, 19}, {"fxe6", 0, 20}, {"e4", 0, 20}, {"d4", 0, 21},
+		{"f4", 0, 21}, {"Qe7", 1, 22}, {"e5", 0, 22}, {"Rb8", 1, 23},
+		{"Bc4", 2, 23}, {"Kh8", 3, 24}, {"Qh3", 4, 24}, {"Nf8", 5, 25},
+		{"b3", 0, 25}, {"a5", 0, 26}, {"f5", 0, 26}, {"exf5", 0, 27},
+		{"Rxf5", 0, 27}, {"Nh7", 1, 28}, {"Rcf1", 2, 28}, {"Qd8", 3, 29},
+		{"Qg3", 4, 29}, {"Re7", 5, 30}, {"h4", 0, 30}, {"Rbb7", 1, 31},
+	}
+	g := NewGame()
+	for _, ply := range plies {
+		if err := g.MoveStr(ply.move); err != nil {
+			t.Fatal(err)
+		}
+		pos := g.Position()
+		if pos.HalfMoveClock() != ply.halfMove || pos.FullMoveNumber() != ply.fullMove {
+			t.Fatalf("expected counters %d %d after %s but got %d %d", ply.halfMove, ply.fullMove, ply.move, pos.HalfMoveClock(), pos.FullMoveNumber())
+		}
+		if suffix := fmt.Sprintf(" %d %d", ply.halfMove, ply.fullMove); !strings.HasSuffix(pos.String(), suffix) {
+			t.Fatalf("expected fen %s to end with%s", pos, suffix)
+		}
+	}
+	expected := "3q3k/1r2r1pn/7p/p1p1PR2/2Bp3P/1P4Q1/P5P1/5RK1 w - - 1 31"
+	if g.FEN() != expected {
+		t.Fatalf("expected %s after %d moves but got %s", expected, len(plies), g.FEN())
+	}
+}
+
+func TestPositionUpdateUntaggedMoves(t *testing.T) {
+	tables := []struct {
+		fen      string
+		move     *Move
+		expected string
+		tags     MoveTag
+	}{
+		// en passant removes the captured pawn
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", &Move{S1: E5, S2: D6}, "4k3/8/3P4/8/8/8/8/4K3 b - - 0 1", EnPassant},
+		// castling moves the rook
+		{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", &Move{S1: E1, S2: G1}, "5k2/8/8/8/8/8/8/5RK1 b - - 1 1", KingSideCastle | Check},
+		{"r3k3/8/8/8/8/8/8/4K3 b q - 0 1", &Move{S1: E8, S2: C8}, "2kr4/8/8/8/8/8/8/4K3 w - - 1 2", QueenSideCastle},
+		// captures reset the half move clock
+		{"4k3/8/8/3p4/8/8/8/3RK3 w - - 7 1", &Move{S1: D1, S2: D5}, "4k3/8/8/3R4/8/8/8/4K3 b - - 0 1", Capture},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if s := pos.Update(table.move).String(); s != table.expected {
+			t.Fatalf("expected %s after %s but got %s", table.expected, table.move, s)
+		}
+		if table.move.tags != 0 {
+			t.Fatalf("expected update not to modify the move %s", table.move)
+		}
+		pos.NormalizeTags(table.move)
+		if table.move.tags != table.tags {
+			t.Fatalf("expected %s to be tagged %d but got %d", table.move, table.tags, table.move.tags)
+		}
+	}
+	// a hand built mating move results in checkmate
+	pos := unsafeFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	if status := pos.Update(&Move{S1: A1, S2: A8}).Status(); status != Checkmate {
+		t.Fatalf("expected checkmate but got %s", status)
+	}
+}
+
+func TestPositionCastlingRights(t *testing.T) {
+	tables := []struct {
+		fen    string
+		moves  []string
+		rights CastleRights
+	}{
+		// capturing a rook on its home square revokes that side
+		{"r3k2r/8/8/8/8/8/6b1/R3K2R b KQkq - 0 1", []string{"g2h1"}, "Qkq"},
+		{"r3k2r/6B1/8/8/8/8/8/R3K2R w KQkq - 0 1", []string{"g7h8"}, "KQq"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", []string{"a1a8"}, "Kk"},
+		// the king moving and returning revokes both sides
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", []string{"e1e2", "e8e7", "e2e1", "e7e8"}, "-"},
+		// a rook moving and returning revokes its side
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", []string{"h1h2", "a8a7", "h2h1", "a7a8"}, "Qk"},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		for _, s := range table.moves {
+			m, err := UCINotation{}.Decode(pos, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pos = pos.Update(m)
+		}
+		if pos.CastleRights() != table.rights {
+			t.Fatalf("%s expected castling rights %s after %v but got %s", table.fen, table.rights, table.moves, pos.CastleRights())
+		}
+	}
+	// stale rights without the rook don't allow castling, such FENs
+	// are only accepted unchecked
+	pos, err := FromFENUnchecked("4k3/8/8/8/8/8/8/4K3 w KQ - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range pos.ValidMoves() {
+		if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+			t.Fatalf("expected no castling without rooks but got %s", m)
+		}
+	}
+}
+
+func TestPositionEquality(t *testing.T) {
+	tables := []struct {
+		a, b  string
+		equal bool
+		same  bool
+	}{
+		{startFEN, startFEN, true, true},
+		// only the move counters differ
+		{startFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 4 3", false, true},
+		// an en passant square without a legal capture
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", false, true},
+		// an en passant square with a legal capture
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1", false, false},
+		{startFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", false, false},
+		{startFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w Kkq - 0 1", false, false},
+		{startFEN, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1", false, false},
+	}
+	for _, table := range tables {
+		a, b := unsafeFEN(table.a), unsafeFEN(table.b)
+		if a.Equal(b) != table.equal || b.Equal(a) != table.equal {
+			t.Fatalf("expected %s and %s equality to be %t", table.a, table.b, table.equal)
+		}
+		if a.SamePosition(b) != table.same || b.SamePosition(a) != table.same {
+			t.Fatalf("expected %s and %s same position to be %t", table.a, table.b, table.same)
+		}
+	}
+}
+
+func TestPositionInsufficientMaterial(t *testing.T) {
+	tables := []struct {
+		fen          string
+		insufficient bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/1N2K3 w - - 0 1", true},
+		// bishops on the same colored squares
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"2b1k3/8/8/8/8/8/8/2B1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"4kn2/8/8/8/8/8/8/2B1K3 w - - 0 1", false},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if pos.InsufficientMaterial() != table.insufficient {
+			t.Fatalf("%s expected insufficient material to be %t", table.fen, table.insufficient)
+		}
+		if status := pos.Status(); (status == InsufficientMaterial) != table.insufficient {
+			t.Fatalf("%s expected status %s to match insufficient material %t", table.fen, status, table.insufficient)
+		}
+	}
+	// checkmate and stalemate come first
+	if status := unsafeFEN("k7/2K5/8/1N6/8/8/8/8 b - - 0 1").Status(); status != Stalemate {
+		t.Fatalf("expected stalemate but got %s", status)
+	}
+}
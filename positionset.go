@@ -0,0 +1,72 @@
+package chess
+
+// PositionSet counts positions by the properties that make positions
+// repetitions of each other: the piece placement, the side to move, the
+// castling rights and the en passant square if a legal en passant
+// capture exists.  Move counters are ignored so positions reached by
+// different move orders, or in different games, are counted together.
+type PositionSet struct {
+	counts map[uint64]int
+}
+
+// NewPositionSet returns an empty position set.
+func NewPositionSet() *PositionSet {
+	return &PositionSet{counts: map[uint64]int{}}
+}
+
+// Add adds the position to the set.
+func (s *PositionSet) Add(pos *Position) {
+	if s.counts == nil {
+		s.counts = map[uint64]int{}
+	}
+	s.counts[pos.repetitionHash()]++
+}
+
+// Contains returns true if an equivalent position was added to the set.
+func (s *PositionSet) Contains(pos *Position) bool {
+	return s.Count(pos) > 0
+}
+
+// Count returns the number of times an equivalent position was added
+// to the set.
+func (s *PositionSet) Count(pos *Position) int {
+	return s.counts[pos.repetitionHash()]
+}
+
+// Len returns the number of distinct positions in the set.
+func (s *PositionSet) Len() int {
+	return len(s.counts)
+}
+
+// repetitionHash returns the zobrist hash of the position ignoring an
+// en passant square that can't be captured on.
+func (pos *Position) repetitionHash() uint64 {
+	if pos.legalEnPassantSquare() == pos.enPassantSquare {
+		return pos.Hash()
+	}
+	cp := pos.copy()
+	cp.enPassantSquare = NoSquare
+	return cp.Hash()
+}
+
+// hasLegalEnPassant returns true if the side to move can legally
+// capture en passant.
+func (pos *Position) hasLegalEnPassant() bool {
+	pawn := getPiece(Pawn, pos.turn)
+	dr := -1
+	if pos.turn == Black {
+		dr = 1
+	}
+	for _, df := range []int{-1, 1} {
+		s1, ok := pos.enPassantSquare.Offset(df, dr)
+		if !ok || pos.board.Piece(s1) != pawn {
+			continue
+		}
+		m := &Move{S1: s1, S2: pos.enPassantSquare}
+		addTags(m, pos)
+		if !m.HasTag(inCheck) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package chess
+
+import "testing"
+
+func TestPositionSet(t *testing.T) {
+	// the same position reached by two move orders
+	orders := [][]string{
+		{"e4", "e5", "Nf3", "Nc6"},
+		{"Nf3", "Nc6", "e4", "e5"},
+	}
+	set := NewPositionSet()
+	for _, order := range orders {
+		g := NewGame()
+		for _, move := range order {
+			if err := g.MoveStr(move); err != nil {
+				t.Fatal(err)
+			}
+		}
+		set.Add(g.Position())
+	}
+	pos := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if !set.Contains(pos) || set.Count(pos) != 2 || set.Len() != 1 {
+		t.Fatalf("expected transposition to be counted twice but got %d of %d positions", set.Count(pos), set.Len())
+	}
+	// move counters don't matter
+	if set.Count(unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 9")) != 2 {
+		t.Fatal("expected move counters to be ignored")
+	}
+	// side to move and castling rights do
+	if set.Contains(unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 2 3")) {
+		t.Fatal("expected side to move to distinguish positions")
+	}
+	if set.Contains(unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w Kkq - 2 3")) {
+		t.Fatal("expected castling rights to distinguish positions")
+	}
+	// an en passant square without a capture doesn't
+	set = NewPositionSet()
+	set.Add(unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"))
+	if !set.Contains(unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")) {
+		t.Fatal("expected en passant square without a legal capture to be ignored")
+	}
+	set.Add(unsafeFEN("4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1"))
+	if set.Contains(unsafeFEN("4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1")) {
+		t.Fatal("expected capturable en passant square to distinguish positions")
+	}
+}
@@ -0,0 +1,130 @@
+package chess
+
+// seeValues are the piece values in centipawns used by static exchange evaluation.
+var seeValues = [...]int{
+	NoPieceType: 0,
+	King:        20000,
+	Queen:       900,
+	Rook:        500,
+	Bishop:      330,
+	Knight:      320,
+	Pawn:        100,
+}
+
+// seeOrder lists piece types from least to most valuable.
+var seeOrder = []PieceType{Pawn, Knight, Bishop, Rook, Queen, King}
+
+// SEE returns the static exchange evaluation of the move in centipawns
+// from the moving side's perspective, which is useful for ordering
+// captures and for telling whether a capture is safe.  The exchange on the destination
+// square is played out with the least valuable attacker recapturing
+// each time, including attackers revealed behind other pieces, and
+// either side may stop capturing when continuing would lose material.
+// Non capturing moves evaluate whether the moved piece can be won.  The
+// move isn't validated and pins aren't considered.
+func (pos *Position) SEE(m *Move) int {
+	return pos.seeExcluding(m, 0)
+}
+
+// seeExcluding returns the static exchange evaluation of the move
+// without the given pieces taking part in the recaptures.
+func (pos *Position) seeExcluding(m *Move, excluded bitboard) int {
+	b := pos.board
+	attacker := b.Piece(m.S1)
+	if attacker == NoPiece {
+		return 0
+	}
+	occ := ^b.emptySqs
+	var gain [32]int
+	gain[0] = seeValues[b.Piece(m.S2).Type()]
+	if attacker.Type() == Pawn && m.S2 == pos.enPassantSquare && !b.isOccupied(m.S2) {
+		captured, _ := m.S2.Offset(0, int(m.S1.Rank())-int(m.S2.Rank()))
+		occ &= ^bbForSquare(captured)
+		gain[0] = seeValues[Pawn]
+	}
+	onSquare := seeValues[attacker.Type()]
+	if m.promo != NoPieceType {
+		gain[0] += seeValues[m.promo] - seeValues[Pawn]
+		onSquare = seeValues[m.promo]
+	}
+	from := bbForSquare(m.S1)
+	side := attacker.Color()
+	d := 0
+	for from != 0 && d < len(gain)-1 {
+		d++
+		side = side.Other()
+		// speculative gain if the piece on the square is recaptured
+		gain[d] = onSquare - gain[d-1]
+		occ &= ^from
+		from = 0
+		attackers := b.attackersOf(m.S2, side, occ) & occ & ^excluded
+		for _, pt := range seeOrder {
+			bb := attackers & b.bbForPiece(getPiece(pt, side))
+			if bb == 0 {
+				continue
+			}
+			// the king can't recapture onto a square that is still attacked
+			if pt == King && b.attackersOf(m.S2, side.Other(), occ)&occ != 0 {
+				break
+			}
+			from = bbForSquare(bb.squares()[0])
+			onSquare = seeValues[pt]
+			break
+		}
+	}
+	for d--; d > 0; d-- {
+		gain[d-1] = -maxInt(-gain[d-1], gain[d])
+	}
+	return gain[0]
+}
+
+// HangingPieces returns the squares of the pieces of the given color
+// that the opponent can win by capturing them.  A piece is hanging if a
+// legal capture of it by a piece not violating a pin wins material by
+// static exchange evaluation, with pinned pieces of either color left
+// out of the recaptures.  The king is never considered hanging.
+func (pos *Position) HangingPieces(c Color) []Square {
+	hanging := []Square{}
+	pieces := pos.board.whiteSqs
+	if c == Black {
+		pieces = pos.board.blackSqs
+	}
+	for _, sq := range pieces.squares() {
+		if pos.board.Piece(sq).Type() == King {
+			continue
+		}
+		attackers := pos.Attackers(sq, c.Other())
+		if len(attackers) == 0 {
+			continue
+		}
+		// pieces that can't recapture on the square without leaving a pin
+		var pinned bitboard
+		for _, s1 := range (^pos.board.emptySqs).squares() {
+			if pos.ViolatesPin(&Move{S1: s1, S2: sq}) {
+				pinned |= bbForSquare(s1)
+			}
+		}
+		for _, s1 := range attackers {
+			m := &Move{S1: s1, S2: sq}
+			if pinned&bbForSquare(s1) != 0 {
+				continue
+			}
+			// the king can only capture an undefended piece
+			if pos.board.Piece(s1).Type() == King && len(pos.Attackers(sq, c)) > 0 {
+				continue
+			}
+			if pos.seeExcluding(m, pinned) > 0 {
+				hanging = append(hanging, sq)
+				break
+			}
+		}
+	}
+	return hanging
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
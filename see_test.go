@@ -0,0 +1,85 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSEE(t *testing.T) {
+	tables := []struct {
+		fen      string
+		move     string
+		expected int
+	}{
+		// undefended knight
+		{"4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1", "d1d5", 320},
+		// rook takes a pawn defended by a pawn
+		{"4k3/8/2p5/3p4/8/8/8/3RK3 w - - 0 1", "d1d5", -400},
+		// pawn takes a defended knight
+		{"4k3/8/2p5/3n4/4P3/8/8/4K3 w - - 0 1", "e4d5", 220},
+		// rook x-ray behind a rook wins the defended pawn
+		{"3rk3/8/8/3p4/8/8/3R4/3RK3 w - - 0 1", "d2d5", 100},
+		// queen trade on a square defended once more than attacked
+		{"3qk3/8/8/3q4/8/8/8/3QK3 w - - 0 1", "d1d5", 0},
+		// en passant capture of an undefended pawn
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		// quiet rook move to a square attacked by a pawn
+		{"4k3/8/2p5/8/8/8/8/3RK3 w - - 0 1", "d1d5", -500},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		m, err := UCINotation{}.Decode(pos, table.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := pos.SEE(m); actual != table.expected {
+			t.Fatalf("%s expected see of %s to be %d but got %d", table.fen, table.move, table.expected, actual)
+		}
+	}
+}
+
+func TestHasWinningCapture(t *testing.T) {
+	tables := []struct {
+		fen     string
+		winning bool
+	}{
+		{"4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1", true},
+		{"4k3/8/2p5/3p4/8/8/8/3RK3 w - - 0 1", false},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", false},
+	}
+	for _, table := range tables {
+		if unsafeFEN(table.fen).HasWinningCapture() != table.winning {
+			t.Fatalf("%s expected winning capture to be %t", table.fen, table.winning)
+		}
+	}
+}
+
+func TestHangingPieces(t *testing.T) {
+	tables := []struct {
+		fen   string
+		white []Square
+		black []Square
+	}{
+		// undefended knight attacked by a rook
+		{"4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1", []Square{}, []Square{D5}},
+		// pawn defended by a pawn
+		{"4k3/8/2p5/3p4/8/8/8/3RK3 w - - 0 1", []Square{}, []Square{}},
+		// defended knight attacked by a knight and an undefended one
+		{"4k3/4p3/3n4/8/4N3/8/8/4K3 w - - 0 1", []Square{E4}, []Square{}},
+		// the e6 pawn pinned to its king doesn't defend the knight
+		{"4k3/5p2/4p3/3n4/8/8/8/3RR1K1 w - - 0 1", []Square{}, []Square{D5}},
+		// the c3 knight pinned to its king can't capture the rook
+		{"4k3/8/8/b2r4/8/2N5/8/4K3 w - - 0 1", []Square{C3}, []Square{}},
+		// the king can't capture a defended pawn
+		{"8/8/8/8/8/2k5/3p4/3K4 w - - 0 1", []Square{}, []Square{}},
+	}
+	for _, table := range tables {
+		pos := unsafeFEN(table.fen)
+		if actual := pos.HangingPieces(White); !reflect.DeepEqual(actual, table.white) {
+			t.Fatalf("%s expected white hanging pieces %v but got %v", table.fen, table.white, actual)
+		}
+		if actual := pos.HangingPieces(Black); !reflect.DeepEqual(actual, table.black) {
+			t.Fatalf("%s expected black hanging pieces %v but got %v", table.fen, table.black, actual)
+		}
+	}
+}
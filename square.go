@@ -29,6 +29,89 @@ func (sq Square) color() Color {
 	return White
 }
 
+// Offset returns the square shifted by the given number of files and
+// ranks and true if the result is on the board.  Offsets never wrap
+// around the edges of the board, moving right from h4 is off the board.
+func (sq Square) Offset(fileDelta, rankDelta int) (Square, bool) {
+	if sq < A1 || sq > H8 {
+		return NoSquare, false
+	}
+	f := int(sq.File()) + fileDelta
+	r := int(sq.Rank()) + rankDelta
+	if !onBoard(f, r) {
+		return NoSquare, false
+	}
+	return getSquare(File(f), Rank(r)), true
+}
+
+// Up returns the square one rank higher and true if it is on the board.
+func (sq Square) Up() (Square, bool) {
+	return sq.Offset(0, 1)
+}
+
+// Down returns the square one rank lower and true if it is on the board.
+func (sq Square) Down() (Square, bool) {
+	return sq.Offset(0, -1)
+}
+
+// Left returns the square one file towards the A file and true if it is on the board.
+func (sq Square) Left() (Square, bool) {
+	return sq.Offset(-1, 0)
+}
+
+// Right returns the square one file towards the H file and true if it is on the board.
+func (sq Square) Right() (Square, bool) {
+	return sq.Offset(1, 0)
+}
+
+// UpLeft returns the diagonally adjacent square up and to the left and true if it is on the board.
+func (sq Square) UpLeft() (Square, bool) {
+	return sq.Offset(-1, 1)
+}
+
+// UpRight returns the diagonally adjacent square up and to the right and true if it is on the board.
+func (sq Square) UpRight() (Square, bool) {
+	return sq.Offset(1, 1)
+}
+
+// DownLeft returns the diagonally adjacent square down and to the left and true if it is on the board.
+func (sq Square) DownLeft() (Square, bool) {
+	return sq.Offset(-1, -1)
+}
+
+// DownRight returns the diagonally adjacent square down and to the right and true if it is on the board.
+func (sq Square) DownRight() (Square, bool) {
+	return sq.Offset(1, -1)
+}
+
+// SquareFromFileRank returns the square with the given file and rank.
+func SquareFromFileRank(f File, r Rank) Square {
+	return getSquare(f, r)
+}
+
+// SquaresBetween returns the squares strictly between a and b ordered
+// from a towards b if the squares share a rank, file or diagonal.  An
+// empty slice is returned otherwise and for adjacent squares.
+func SquaresBetween(a, b Square) []Square {
+	sqs := []Square{}
+	if a < A1 || a > H8 || b < A1 || b > H8 {
+		return sqs
+	}
+	df, dr, ok := rayDirection(a, b)
+	if !ok {
+		return sqs
+	}
+	for sq, _ := a.Offset(df, dr); sq != b; sq, _ = sq.Offset(df, dr) {
+		sqs = append(sqs, sq)
+	}
+	return sqs
+}
+
+// mirror returns the square reflected across the board's horizontal center line.
+func (sq Square) mirror() Square {
+	return getSquare(sq.File(), Rank8-sq.Rank())
+}
+
 func getSquare(f File, r Rank) Square {
 	return Square((int(r) * 8) + int(f))
 }
@@ -0,0 +1,85 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSquareOffset(t *testing.T) {
+	tables := []struct {
+		sq        Square
+		fileDelta int
+		rankDelta int
+		expected  Square
+		ok        bool
+	}{
+		{E4, 0, 1, E5, true},
+		{E4, 1, -1, F3, true},
+		{A1, 7, 7, H8, true},
+		{H4, 1, 0, NoSquare, false},
+		{A5, -1, 0, NoSquare, false},
+		{E8, 0, 1, NoSquare, false},
+		{E1, 0, -1, NoSquare, false},
+		{G1, 2, 1, NoSquare, false},
+		{NoSquare, 0, 0, NoSquare, false},
+	}
+	for _, table := range tables {
+		sq, ok := table.sq.Offset(table.fileDelta, table.rankDelta)
+		if sq != table.expected || ok != table.ok {
+			t.Fatalf("expected offset (%d, %d) of %d to be (%d, %t) but got (%d, %t)",
+				table.fileDelta, table.rankDelta, table.sq, table.expected, table.ok, sq, ok)
+		}
+	}
+}
+
+func TestSquareDirections(t *testing.T) {
+	dirs := []func(Square) (Square, bool){
+		Square.Up, Square.Down, Square.Left, Square.Right,
+		Square.UpLeft, Square.UpRight, Square.DownLeft, Square.DownRight,
+	}
+	expected := []Square{D5, D3, C4, E4, C5, E5, C3, E3}
+	for i, dir := range dirs {
+		if sq, ok := dir(D4); !ok || sq != expected[i] {
+			t.Fatalf("expected direction %d from d4 to be %s but got %d", i, expected[i], sq)
+		}
+	}
+	if _, ok := H4.Right(); ok {
+		t.Fatal("expected right of h4 to be off the board")
+	}
+	if _, ok := A8.UpLeft(); ok {
+		t.Fatal("expected up left of a8 to be off the board")
+	}
+	if sq := SquareFromFileRank(FileC, Rank6); sq != C6 {
+		t.Fatalf("expected c6 but got %s", sq)
+	}
+}
+
+func TestSquaresBetween(t *testing.T) {
+	tables := []struct {
+		a, b     Square
+		expected []Square
+	}{
+		// all eight directions from d4
+		{D4, D8, []Square{D5, D6, D7}},
+		{D4, D1, []Square{D3, D2}},
+		{D4, H4, []Square{E4, F4, G4}},
+		{D4, A4, []Square{C4, B4}},
+		{D4, H8, []Square{E5, F6, G7}},
+		{D4, A7, []Square{C5, B6}},
+		{D4, G1, []Square{E3, F2}},
+		{D4, A1, []Square{C3, B2}},
+		// adjacent squares
+		{D4, D5, []Square{}},
+		{D4, E5, []Square{}},
+		// not collinear
+		{D4, E6, []Square{}},
+		{A1, B8, []Square{}},
+		{D4, D4, []Square{}},
+		{D4, NoSquare, []Square{}},
+	}
+	for _, table := range tables {
+		if sqs := SquaresBetween(table.a, table.b); !reflect.DeepEqual(sqs, table.expected) {
+			t.Fatalf("expected squares between %s and %s to be %v but got %v", table.a, table.b, table.expected, sqs)
+		}
+	}
+}
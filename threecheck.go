@@ -0,0 +1,90 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThreeCheck is standard chess that is also won by giving check three
+// times.  The checks each side has left are written in FEN after the en
+// passant square such as 3+3, or 2+3 after white's first check.
+type ThreeCheck struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (ThreeCheck) Name() string {
+	return "Three-check"
+}
+
+// StartingFEN implements the Variant interface.
+func (ThreeCheck) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+// Status implements the Variant interface.  The third check wins and
+// material is only insufficient with bare kings since any other piece
+// can still give check.
+func (ThreeCheck) Status(pos *Position) (Outcome, Method) {
+	mover := pos.turn.Other()
+	if pos.Checks(mover) >= 3 {
+		return winner(mover), VariantEnd
+	}
+	outcome, method := Standard{}.Status(pos)
+	b := pos.board
+	if method == InsufficientMaterial && b.whiteSqs|b.blackSqs != b.bbWhiteKing|b.bbBlackKing {
+		return NoOutcome, NoMethod
+	}
+	return outcome, method
+}
+
+// Checks returns the number of checks the color has given.  It is zero
+// for positions that don't count checks as in Three-check.
+func (pos *Position) Checks(c Color) int {
+	if pos.checks == nil || c == NoColor {
+		return 0
+	}
+	return pos.checks[c]
+}
+
+// updateChecks returns the checks given after the move of the side to
+// move.  The checks of a position are never modified so they can be
+// shared.
+func (pos *Position) updateChecks(check bool) *[3]int {
+	if pos.checks == nil || !check {
+		return pos.checks
+	}
+	next := *pos.checks
+	next[pos.turn]++
+	return &next
+}
+
+// parseFENChecks parses the remaining checks of white and black such as
+// 3+3 into the checks each has given.
+func parseFENChecks(s string) (*[3]int, error) {
+	parts := strings.Split(s, "+")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("chess: fen invalid remaining checks %s", s)
+	}
+	checks := &[3]int{}
+	for i, c := range []Color{White, Black} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || n > 3 {
+			return nil, fmt.Errorf("chess: fen invalid remaining checks %s", s)
+		}
+		checks[c] = 3 - n
+	}
+	return checks, nil
+}
+
+// checksFEN returns the remaining checks of white and black.
+func (pos *Position) checksFEN() string {
+	remaining := func(c Color) int {
+		if n := 3 - pos.Checks(c); n > 0 {
+			return n
+		}
+		return 0
+	}
+	return fmt.Sprintf("%d+%d", remaining(White), remaining(Black))
+}
@@ -0,0 +1,95 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThreeCheckFEN(t *testing.T) {
+	tables := []struct {
+		fen      string
+		expected string
+	}{
+		{ThreeCheck{}.StartingFEN(), ThreeCheck{}.StartingFEN()},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 1+3", "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1"},
+	}
+	for _, table := range tables {
+		pos, err := FromFEN(table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := pos.String(); s != table.expected {
+			t.Fatalf("expected fen %s but got %s", table.expected, s)
+		}
+		if pos.Variant() != (ThreeCheck{}) {
+			t.Fatalf("expected %s to be a three-check position", table.fen)
+		}
+	}
+	pos, _ := FromVariantFEN(ThreeCheck{}, startFEN)
+	if s := pos.String(); s != (ThreeCheck{}).StartingFEN() {
+		t.Fatalf("expected the three-check variant to add the remaining checks but got %s", s)
+	}
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/R3K3 w - - 4+3 0 1",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 3-3 0 1",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 3+ 0 1",
+	} {
+		if _, err := FromFEN(fen); err == nil {
+			t.Fatalf("expected an error decoding fen %s", fen)
+		}
+	}
+}
+
+func TestThreeCheckCounting(t *testing.T) {
+	tables := []struct {
+		fen     string
+		moves   []string
+		checks  int
+		outcome Outcome
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1", []string{"Ra8+"}, 1, NoOutcome},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", []string{"Ra8+"}, 3, WhiteWon},
+		// material isn't insufficient while a piece can give check
+		{"4k3/8/8/8/8/8/8/N3K3 w - - 3+3 0 1", []string{"Nb3"}, 0, NoOutcome},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 3+3 0 1", []string{}, 0, Draw},
+	}
+	for _, table := range tables {
+		fen, err := FEN(table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(fen)
+		for _, s := range table.moves {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pos := g.Position()
+		if n := pos.Checks(White); n != table.checks {
+			t.Fatalf("expected white to have given %d checks after %v but got %d", table.checks, table.moves, n)
+		}
+		if g.Outcome() != table.outcome {
+			t.Fatalf("expected outcome %s after %v but got %s", table.outcome, table.moves, g.Outcome())
+		}
+		if pos.Hash() != generateZobristHash(pos) {
+			t.Fatalf("expected the updated hash to match the hash of %s", pos)
+		}
+	}
+	if a, b := unsafeFEN("4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1"), unsafeFEN("4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1"); a.SamePosition(b) || a.Hash() == b.Hash() {
+		t.Fatal("expected positions with different checks given to differ")
+	}
+}
+
+func TestThreeCheckPGN(t *testing.T) {
+	pgn := `[Variant "Three-check"]
+
+1. e4 e5 2. Bc4 Nc6 3. Bxf7+ Kxf7 4. Qh5+ Ke7 5. Qxe5+ 1-0`
+	read, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(read)
+	if g.Outcome() != WhiteWon || g.Method() != VariantEnd {
+		t.Fatalf("expected white to win by the third check but got %s by %s", g.Outcome(), g.Method())
+	}
+}
@@ -0,0 +1,212 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// Cmd is a UCI command sent to the engine.  ProcessResponse reads
+// the engine's response to the command, if it has one.
+type Cmd interface {
+	fmt.Stringer
+	ProcessResponse(e *Engine) error
+}
+
+var (
+	// CmdUCI tells the engine to use UCI and reads its id and options.
+	CmdUCI = cmdUCI{}
+	// CmdIsReady waits for the engine to be ready for more commands.
+	CmdIsReady = cmdIsReady{}
+	// CmdUCINewGame tells the engine the next search is from a new game.
+	CmdUCINewGame = cmdNoResponse("ucinewgame")
+	// CmdPonderHit tells the engine the expected move was played.
+	CmdPonderHit = cmdNoResponse("ponderhit")
+	// CmdStop stops a search started with CmdGo and reads its results.
+	CmdStop = cmdStop{}
+	// CmdQuit tells the engine to exit.
+	CmdQuit = cmdNoResponse("quit")
+)
+
+type cmdNoResponse string
+
+func (c cmdNoResponse) String() string {
+	return string(c)
+}
+
+func (cmdNoResponse) ProcessResponse(e *Engine) error {
+	return nil
+}
+
+type cmdUCI struct{}
+
+func (cmdUCI) String() string {
+	return "uci"
+}
+
+func (cmdUCI) ProcessResponse(e *Engine) error {
+	return e.readUntil("uciok", func(line string) bool {
+		switch fields := strings.Fields(line); {
+		case len(fields) == 0:
+		case fields[0] == "uciok":
+			return true
+		case fields[0] == "id" && len(fields) > 2:
+			e.id[fields[1]] = strings.Join(fields[2:], " ")
+		case fields[0] == "option":
+			if o, ok := parseOption(fields[1:]); ok {
+				e.options[o.Name] = o
+			}
+		}
+		return false
+	})
+}
+
+type cmdIsReady struct{}
+
+func (cmdIsReady) String() string {
+	return "isready"
+}
+
+func (cmdIsReady) ProcessResponse(e *Engine) error {
+	return e.readUntil("readyok", func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+type cmdStop struct{}
+
+func (cmdStop) String() string {
+	return "stop"
+}
+
+func (cmdStop) ProcessResponse(e *Engine) error {
+	return readSearchResults(e)
+}
+
+// CmdSetOption sets the engine option with the given name to the
+// value.  Button options are pressed by leaving the value empty.
+type CmdSetOption struct {
+	Name  string
+	Value string
+}
+
+func (cmd CmdSetOption) String() string {
+	if cmd.Value == "" {
+		return "setoption name " + cmd.Name
+	}
+	return "setoption name " + cmd.Name + " value " + cmd.Value
+}
+
+func (CmdSetOption) ProcessResponse(e *Engine) error {
+	return nil
+}
+
+// CmdPosition sets the position the engine searches to the result of
+// playing the moves from the position.  The standard starting position
+// is used if the position is nil.
+type CmdPosition struct {
+	Position *chess.Position
+	Moves    []*chess.Move
+}
+
+func (cmd CmdPosition) String() string {
+	s := "position startpos"
+	if cmd.Position != nil && !cmd.Position.IsStandardStart() {
+		s = "position fen " + cmd.Position.String()
+	}
+	if len(cmd.Moves) == 0 {
+		return s
+	}
+	strs := []string{}
+	for _, m := range cmd.Moves {
+		strs = append(strs, chess.UCINotation{}.Encode(nil, m))
+	}
+	return s + " moves " + strings.Join(strs, " ")
+}
+
+func (cmd CmdPosition) ProcessResponse(e *Engine) error {
+	pos := cmd.Position
+	if pos == nil {
+		pos = chess.StartingPosition()
+	}
+	for _, m := range cmd.Moves {
+		pos = pos.Update(m)
+	}
+	e.position = pos
+	return nil
+}
+
+// CmdGo starts a search of the position set by CmdPosition.  Zero
+// values are left out of the command.  Unless the search is infinite
+// or pondering, the results are read once the engine reports its best
+// move and are available from the engine's SearchResults method.
+// Otherwise CmdStop ends the search and reads the results.
+type CmdGo struct {
+	SearchMoves    []*chess.Move
+	Ponder         bool
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+	MovesToGo      int
+	Depth          int
+	Nodes          int
+	Mate           int
+	MoveTime       time.Duration
+	Infinite       bool
+}
+
+func (cmd CmdGo) String() string {
+	a := []string{"go"}
+	if len(cmd.SearchMoves) > 0 {
+		a = append(a, "searchmoves")
+		for _, m := range cmd.SearchMoves {
+			a = append(a, chess.UCINotation{}.Encode(nil, m))
+		}
+	}
+	if cmd.Ponder {
+		a = append(a, "ponder")
+	}
+	for _, d := range []struct {
+		name string
+		v    time.Duration
+	}{
+		{"wtime", cmd.WhiteTime},
+		{"btime", cmd.BlackTime},
+		{"winc", cmd.WhiteIncrement},
+		{"binc", cmd.BlackIncrement},
+	} {
+		if d.v > 0 {
+			a = append(a, d.name, fmt.Sprint(d.v.Milliseconds()))
+		}
+	}
+	for _, n := range []struct {
+		name string
+		v    int
+	}{
+		{"movestogo", cmd.MovesToGo},
+		{"depth", cmd.Depth},
+		{"nodes", cmd.Nodes},
+		{"mate", cmd.Mate},
+	} {
+		if n.v > 0 {
+			a = append(a, n.name, fmt.Sprint(n.v))
+		}
+	}
+	if cmd.MoveTime > 0 {
+		a = append(a, "movetime", fmt.Sprint(cmd.MoveTime.Milliseconds()))
+	}
+	if cmd.Infinite {
+		a = append(a, "infinite")
+	}
+	return strings.Join(a, " ")
+}
+
+func (cmd CmdGo) ProcessResponse(e *Engine) error {
+	if cmd.Infinite || cmd.Ponder {
+		return nil
+	}
+	return readSearchResults(e)
+}
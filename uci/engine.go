@@ -0,0 +1,148 @@
+// Package uci is a client for chess engines speaking the Universal
+// Chess Interface protocol such as Stockfish.
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// Engine represents a running chess engine process.  The engine
+// isn't safe for concurrent use.
+type Engine struct {
+	cmd      *exec.Cmd
+	in       io.WriteCloser
+	out      *bufio.Scanner
+	logger   *log.Logger
+	id       map[string]string
+	options  map[string]Option
+	results  SearchResults
+	position *chess.Position
+}
+
+// Debug is an option for the New function that logs the
+// communication with the engine to standard output.
+func Debug(e *Engine) {
+	e.logger = log.New(os.Stdout, "uci ", log.LstdFlags)
+}
+
+// Logger is an option for the New function that logs the
+// communication with the engine to the given logger.
+func Logger(logger *log.Logger) func(e *Engine) {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// New starts the engine executable at the given path.  Options can be
+// given to configure the engine.  An error is returned if the engine
+// can't be started.
+func New(path string, opts ...func(e *Engine)) (*Engine, error) {
+	e := &Engine{
+		cmd:      exec.Command(path),
+		id:       map[string]string{},
+		options:  map[string]Option{},
+		position: chess.StartingPosition(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	in, err := e.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := e.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.cmd.Start(); err != nil {
+		return nil, err
+	}
+	e.in = in
+	e.out = bufio.NewScanner(out)
+	return e, nil
+}
+
+// ID returns the id values reported in response to CmdUCI such as
+// the name and author of the engine.
+func (e *Engine) ID() map[string]string {
+	cp := map[string]string{}
+	for k, v := range e.id {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Options returns the options reported in response to CmdUCI keyed by
+// their names.
+func (e *Engine) Options() map[string]Option {
+	cp := map[string]Option{}
+	for k, v := range e.options {
+		cp[k] = v
+	}
+	return cp
+}
+
+// SearchResults returns the results of the most recent search.
+func (e *Engine) SearchResults() SearchResults {
+	return e.results
+}
+
+// Run sends the commands to the engine in order and processes
+// their responses.  An error is returned if writing to the engine
+// fails or a response can't be read.
+func (e *Engine) Run(cmds ...Cmd) error {
+	for _, cmd := range cmds {
+		if err := e.send(cmd.String()); err != nil {
+			return err
+		}
+		if err := cmd.ProcessResponse(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends CmdQuit to the engine and waits for it to exit.
+func (e *Engine) Close() error {
+	if err := e.Run(CmdQuit); err != nil {
+		return err
+	}
+	if err := e.in.Close(); err != nil {
+		return err
+	}
+	return e.cmd.Wait()
+}
+
+func (e *Engine) send(s string) error {
+	if e.logger != nil {
+		e.logger.Println(">", s)
+	}
+	_, err := io.WriteString(e.in, s+"\n")
+	return err
+}
+
+// readUntil reads lines from the engine, passing each to f, until f
+// returns true.  An error is returned if the engine output ends first.
+func (e *Engine) readUntil(response string, f func(line string) bool) error {
+	for e.out.Scan() {
+		line := strings.TrimSpace(e.out.Text())
+		if e.logger != nil {
+			e.logger.Println("<", line)
+		}
+		if f(line) {
+			return nil
+		}
+	}
+	if err := e.out.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("uci: engine output ended before %s", response)
+}
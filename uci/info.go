@@ -0,0 +1,212 @@
+package uci
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// OptionType is the type of an engine option.
+type OptionType string
+
+const (
+	// OptionCheck is a boolean option with the values true and false.
+	OptionCheck OptionType = "check"
+	// OptionSpin is an integer option between a minimum and maximum.
+	OptionSpin OptionType = "spin"
+	// OptionCombo is an option with one of a list of predefined values.
+	OptionCombo OptionType = "combo"
+	// OptionButton is an option without a value that triggers an action.
+	OptionButton OptionType = "button"
+	// OptionString is a text option.
+	OptionString OptionType = "string"
+)
+
+// Option is an option supported by the engine that can be set with
+// CmdSetOption.
+type Option struct {
+	Name    string
+	Type    OptionType
+	Default string
+	Min     string
+	Max     string
+	Vars    []string
+}
+
+// parseOption parses the fields following "option" in the engine's
+// response to CmdUCI.  Names and values may contain spaces.
+func parseOption(fields []string) (Option, bool) {
+	o := Option{}
+	key := ""
+	values := map[string][]string{}
+	for _, f := range fields {
+		switch f {
+		case "name", "type", "default", "min", "max", "var":
+			key = f
+			if f == "var" {
+				o.Vars = append(o.Vars, "")
+			}
+			continue
+		}
+		if key == "var" {
+			o.Vars[len(o.Vars)-1] = strings.TrimSpace(o.Vars[len(o.Vars)-1] + " " + f)
+			continue
+		}
+		values[key] = append(values[key], f)
+	}
+	o.Name = strings.Join(values["name"], " ")
+	o.Type = OptionType(strings.Join(values["type"], " "))
+	o.Default = strings.Join(values["default"], " ")
+	o.Min = strings.Join(values["min"], " ")
+	o.Max = strings.Join(values["max"], " ")
+	return o, o.Name != ""
+}
+
+// SearchResults are the results of a search.  BestMove is nil if the
+// engine had no move to play.
+type SearchResults struct {
+	BestMove *chess.Move
+	Ponder   *chess.Move
+	Info     Info
+}
+
+// Info is the search information reported by the engine during a
+// search.  Only the last report with a principal variation of the
+// first line is kept.
+type Info struct {
+	Depth             int
+	Seldepth          int
+	MultiPV           int
+	Score             Score
+	Nodes             int
+	NPS               int
+	Time              time.Duration
+	HashFull          int
+	TBHits            int
+	CurrentMove       *chess.Move
+	CurrentMoveNumber int
+	PV                []*chess.Move
+}
+
+// Score is the evaluation of a search from the perspective of the
+// side to move.  Mate is the number of moves until mate, negative if
+// the side to move is getting mated, and zero if no mate was found in
+// which case CP is the evaluation in centipawns.  UpperBound and
+// LowerBound are set if the score is only a bound.
+type Score struct {
+	CP         int
+	Mate       int
+	UpperBound bool
+	LowerBound bool
+}
+
+// readSearchResults reads the engine's search information until it
+// reports its best move.
+func readSearchResults(e *Engine) error {
+	results := SearchResults{}
+	err := e.readUntil("bestmove", func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return false
+		}
+		switch fields[0] {
+		case "info":
+			info := parseInfo(e.position, fields[1:])
+			if len(info.PV) > 0 && info.MultiPV <= 1 {
+				results.Info = info
+			}
+		case "bestmove":
+			if len(fields) > 1 {
+				results.BestMove, _ = e.position.DecodeUCI(fields[1])
+			}
+			if len(fields) > 3 && fields[2] == "ponder" && results.BestMove != nil {
+				results.Ponder, _ = e.position.Update(results.BestMove).DecodeUCI(fields[3])
+			}
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	e.results = results
+	return nil
+}
+
+// parseInfo parses the fields following "info" with the moves decoded
+// from the position.  Unknown and malformed fields are skipped.
+func parseInfo(pos *chess.Position, fields []string) Info {
+	info := Info{}
+	for i := 0; i < len(fields); i++ {
+		next := func() int {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(fields[i])
+			return n
+		}
+		switch fields[i] {
+		case "depth":
+			info.Depth = next()
+		case "seldepth":
+			info.Seldepth = next()
+		case "multipv":
+			info.MultiPV = next()
+		case "nodes":
+			info.Nodes = next()
+		case "nps":
+			info.NPS = next()
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "hashfull":
+			info.HashFull = next()
+		case "tbhits":
+			info.TBHits = next()
+		case "currmovenumber":
+			info.CurrentMoveNumber = next()
+		case "currmove":
+			if i+1 < len(fields) {
+				i++
+				info.CurrentMove, _ = pos.DecodeUCI(fields[i])
+			}
+		case "score":
+		score:
+			for i+1 < len(fields) {
+				switch fields[i+1] {
+				case "cp":
+					i++
+					info.Score.CP = next()
+				case "mate":
+					i++
+					info.Score.Mate = next()
+				case "lowerbound":
+					i++
+					info.Score.LowerBound = true
+				case "upperbound":
+					i++
+					info.Score.UpperBound = true
+				default:
+					break score
+				}
+			}
+		case "pv":
+			// the principal variation runs to the end of the line
+			p := pos
+			for _, s := range fields[i+1:] {
+				m, err := p.DecodeUCI(s)
+				if err != nil {
+					break
+				}
+				info.PV = append(info.PV, m)
+				p = p.Update(m)
+			}
+			i = len(fields)
+		case "string":
+			i = len(fields)
+		}
+	}
+	return info
+}
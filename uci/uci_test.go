@@ -0,0 +1,133 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// TestHelperProcess isn't a real test, it's run as a fake engine by
+// newTestEngine.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		switch fields := strings.Fields(scanner.Text()); fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine 1.0")
+			fmt.Println("id author Tester")
+			fmt.Println("option name Hash type spin default 16 min 1 max 1024")
+			fmt.Println("option name Skill Level type combo default Normal var Weak var Normal")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "go":
+			if fields[len(fields)-1] == "infinite" {
+				continue
+			}
+			fmt.Println("info string searching")
+			fmt.Println("info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 2000 time 10 pv e2e4")
+			fmt.Println("info depth 2 multipv 2 score cp 10 pv d2d4 d7d5")
+			fmt.Println("info depth 2 seldepth 3 multipv 1 score cp 35 lowerbound nodes 400 nps 20000 time 20 pv e2e4 e7e5")
+			fmt.Println("bestmove e2e4 ponder e7e5")
+		case "stop":
+			fmt.Println("info depth 3 score mate -2 pv f7f6")
+			fmt.Println("bestmove f7f6")
+		case "quit":
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	eng, err := New(os.Args[0], func(e *Engine) {
+		e.cmd.Args = append(e.cmd.Args, "-test.run=TestHelperProcess")
+		e.cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eng
+}
+
+func TestEngine(t *testing.T) {
+	eng := newTestEngine(t)
+	if err := eng.Run(CmdUCI, CmdIsReady, CmdUCINewGame); err != nil {
+		t.Fatal(err)
+	}
+	if name := eng.ID()["name"]; name != "Fake Engine 1.0" {
+		t.Fatalf("expected engine name Fake Engine 1.0 but got %s", name)
+	}
+	expected := Option{Name: "Skill Level", Type: OptionCombo, Default: "Normal", Vars: []string{"Weak", "Normal"}}
+	if o := eng.Options()["Skill Level"]; !reflect.DeepEqual(o, expected) {
+		t.Fatalf("expected option %v but got %v", expected, o)
+	}
+	if o := eng.Options()["Hash"]; o.Type != OptionSpin || o.Min != "1" || o.Max != "1024" {
+		t.Fatalf("expected spin option Hash from 1 to 1024 but got %v", o)
+	}
+	if err := eng.Run(CmdPosition{Position: chess.StartingPosition()}, CmdGo{MoveTime: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	results := eng.SearchResults()
+	if results.BestMove.String() != "e2e4" || results.Ponder.String() != "e7e5" {
+		t.Fatalf("expected best move e2e4 and ponder e7e5 but got %s and %s", results.BestMove, results.Ponder)
+	}
+	info := results.Info
+	if info.Depth != 2 || info.Seldepth != 3 || info.Nodes != 400 || info.NPS != 20000 || info.Time != 20*time.Millisecond {
+		t.Fatalf("expected the last info of the first line but got %+v", info)
+	}
+	if info.Score != (Score{CP: 35, LowerBound: true}) || len(info.PV) != 2 || info.PV[1].String() != "e7e5" {
+		t.Fatalf("expected score 35 lower bound and pv e2e4 e7e5 but got %+v and %v", info.Score, info.PV)
+	}
+	// an infinite search reads the results on stop
+	game := chess.NewGame()
+	if err := game.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Run(CmdPosition{Moves: game.Moves()}, CmdGo{Infinite: true}, CmdStop); err != nil {
+		t.Fatal(err)
+	}
+	results = eng.SearchResults()
+	if results.BestMove.String() != "f7f6" || results.Ponder != nil || results.Info.Score.Mate != -2 {
+		t.Fatalf("expected best move f7f6 and mate in -2 but got %s and %+v", results.BestMove, results.Info.Score)
+	}
+	if err := eng.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCmdString(t *testing.T) {
+	pos := chess.StartingPosition()
+	e4 := &chess.Move{S1: chess.E2, S2: chess.E4}
+	fen, _ := chess.FromFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	tables := []struct {
+		cmd Cmd
+		s   string
+	}{
+		{CmdUCI, "uci"},
+		{CmdUCINewGame, "ucinewgame"},
+		{CmdSetOption{Name: "Hash", Value: "128"}, "setoption name Hash value 128"},
+		{CmdSetOption{Name: "Clear Hash"}, "setoption name Clear Hash"},
+		{CmdPosition{Position: pos}, "position startpos"},
+		{CmdPosition{Position: pos, Moves: []*chess.Move{e4}}, "position startpos moves e2e4"},
+		{CmdPosition{Position: fen}, "position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+		{CmdGo{MoveTime: time.Second / 10}, "go movetime 100"},
+		{CmdGo{WhiteTime: time.Minute, BlackTime: time.Minute, WhiteIncrement: time.Second, MovesToGo: 20}, "go wtime 60000 btime 60000 winc 1000 movestogo 20"},
+		{CmdGo{SearchMoves: []*chess.Move{e4}, Depth: 10}, "go searchmoves e2e4 depth 10"},
+		{CmdGo{Infinite: true}, "go infinite"},
+	}
+	for _, table := range tables {
+		if s := table.cmd.String(); s != table.s {
+			t.Fatalf("expected command %s but got %s", table.s, s)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package uciserver exposes a chess engine written in Go over the
+// Universal Chess Interface protocol so that it can be used by chess
+// GUIs and tournament managers.
+package uciserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// Engine is implemented by engines served over UCI.  Search returns the
+// best move for the position within the limits.  The context is
+// canceled when the GUI stops the search and the engine should then
+// return the best move found so far.  A nil move is sent as 0000.
+type Engine interface {
+	Search(ctx context.Context, pos *chess.Position, limits Limits) *chess.Move
+}
+
+// Limits are the search limits given by the go command.  Zero values
+// weren't given.  Infinite searches run until the GUI stops them.
+// Ponder searches run until the GUI stops them or sends ponderhit, after
+// which the search is canceled once the side to move's time budget is
+// used.
+type Limits struct {
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+	MovesToGo      int
+	Depth          int
+	Nodes          int
+	Mate           int
+	MoveTime       time.Duration
+	Infinite       bool
+	Ponder         bool
+}
+
+// Budget returns the time the color should spend on its move.  The move
+// time is used if given.  Otherwise the remaining time is split over
+// the moves to go, thirty if not given, plus half of the increment.
+// Zero is returned if the search isn't limited by time.
+func (l Limits) Budget(c chess.Color) time.Duration {
+	if l.MoveTime > 0 {
+		return l.MoveTime
+	}
+	remaining, inc := l.WhiteTime, l.WhiteIncrement
+	if c == chess.Black {
+		remaining, inc = l.BlackTime, l.BlackIncrement
+	}
+	if remaining <= 0 {
+		return 0
+	}
+	movesToGo := l.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	budget := remaining/time.Duration(movesToGo) + inc/2
+	// leave a margin so the clock never runs out
+	if max := remaining - remaining/10; budget > max {
+		budget = max
+	}
+	return budget
+}
+
+// Server speaks UCI for an engine.
+type Server struct {
+	name   string
+	author string
+	engine Engine
+
+	mu     sync.Mutex
+	w      io.Writer
+	pos    *chess.Position
+	cancel context.CancelFunc
+	done   chan struct{}
+	hit    chan struct{}
+	limits Limits
+	turn   chess.Color
+	timer  *time.Timer
+}
+
+// New returns a server for the engine reporting the name and author
+// in response to the uci command.
+func New(name, author string, e Engine) *Server {
+	return &Server{name: name, author: author, engine: e, pos: chess.StartingPosition()}
+}
+
+// Serve reads commands from r and writes responses to w until the quit
+// command is received or r ends.  Unknown commands are ignored.  An
+// error is returned if reading from r fails.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.w = w
+	scanner := bufio.NewScanner(r)
+	defer s.stop()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			s.send("id name " + s.name)
+			s.send("id author " + s.author)
+			s.send("uciok")
+		case "isready":
+			s.send("readyok")
+		case "ucinewgame":
+			s.stop()
+			s.pos = chess.StartingPosition()
+		case "position":
+			s.stop()
+			if err := s.position(fields[1:]); err != nil {
+				s.send("info string " + err.Error())
+			}
+		case "go":
+			s.stop()
+			s.search(parseLimits(fields[1:]))
+		case "stop":
+			s.stop()
+		case "ponderhit":
+			s.ponderHit()
+		case "quit":
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) send(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintln(s.w, line)
+}
+
+// position sets the position from the fields following the position
+// command.
+func (s *Server) position(fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("uciserver: position requires startpos or fen")
+	}
+	pos := chess.StartingPosition()
+	i := 1
+	if fields[0] == "fen" {
+		for i < len(fields) && fields[i] != "moves" {
+			i++
+		}
+		var err error
+		if pos, err = chess.FromFEN(strings.Join(fields[1:i], " ")); err != nil {
+			return err
+		}
+	} else if fields[0] != "startpos" {
+		return fmt.Errorf("uciserver: position requires startpos or fen")
+	}
+	if i < len(fields) && fields[i] == "moves" {
+		for _, s := range fields[i+1:] {
+			m, err := pos.DecodeUCI(s)
+			if err != nil {
+				return err
+			}
+			pos = pos.Update(m)
+		}
+	}
+	s.pos = pos
+	return nil
+}
+
+// search starts the engine's search in the background.  The best move
+// of an infinite or ponder search is only sent once it is stopped.
+func (s *Server) search(limits Limits) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done, hit := make(chan struct{}), make(chan struct{})
+	s.cancel, s.done, s.hit = cancel, done, hit
+	pos := s.pos
+	s.limits, s.turn = limits, pos.Turn()
+	go func() {
+		defer close(done)
+		m := s.engine.Search(ctx, pos, limits)
+		switch {
+		case limits.Infinite:
+			<-ctx.Done()
+		case limits.Ponder:
+			select {
+			case <-ctx.Done():
+			case <-hit:
+			}
+		}
+		move := "0000"
+		if m != nil {
+			move = chess.UCINotation{}.Encode(pos, m)
+		}
+		s.send("bestmove " + move)
+	}()
+}
+
+// stop cancels the running search and waits for its best move to be sent.
+func (s *Server) stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.cancel, s.done, s.hit, s.timer = nil, nil, nil, nil
+}
+
+// ponderHit switches a ponder search to the real time limits as the
+// expected move was played.  The search is canceled once the time
+// budget is used, or right away if the go command gave no time.  Its
+// best move is sent when it ends.
+func (s *Server) ponderHit() {
+	if s.hit == nil || !s.limits.Ponder {
+		return
+	}
+	select {
+	case <-s.hit:
+		return
+	default:
+		close(s.hit)
+	}
+	if budget := s.limits.Budget(s.turn); budget > 0 {
+		s.timer = time.AfterFunc(budget, s.cancel)
+	} else {
+		s.cancel()
+	}
+}
+
+// parseLimits parses the fields following the go command.  Unknown and
+// malformed fields are skipped.
+func parseLimits(fields []string) Limits {
+	l := Limits{}
+	for i := 0; i < len(fields); i++ {
+		next := func() int {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(fields[i])
+			return n
+		}
+		ms := func() time.Duration {
+			return time.Duration(next()) * time.Millisecond
+		}
+		switch fields[i] {
+		case "wtime":
+			l.WhiteTime = ms()
+		case "btime":
+			l.BlackTime = ms()
+		case "winc":
+			l.WhiteIncrement = ms()
+		case "binc":
+			l.BlackIncrement = ms()
+		case "movestogo":
+			l.MovesToGo = next()
+		case "depth":
+			l.Depth = next()
+		case "nodes":
+			l.Nodes = next()
+		case "mate":
+			l.Mate = next()
+		case "movetime":
+			l.MoveTime = ms()
+		case "infinite":
+			l.Infinite = true
+		case "ponder":
+			l.Ponder = true
+		}
+	}
+	return l
+}
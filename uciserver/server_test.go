@@ -0,0 +1,142 @@
+package uciserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	chess "github.com/Yoshi-Exeler/chesslib"
+)
+
+// testEngine plays the first valid move in UCI order and waits for
+// infinite searches to be stopped.
+type testEngine struct {
+	positions []*chess.Position
+	limits    []Limits
+}
+
+func (e *testEngine) Search(ctx context.Context, pos *chess.Position, limits Limits) *chess.Move {
+	e.positions = append(e.positions, pos)
+	e.limits = append(e.limits, limits)
+	if limits.Infinite {
+		<-ctx.Done()
+	}
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].String() < moves[j].String() })
+	return moves[0]
+}
+
+func serve(t *testing.T, e Engine, commands ...string) []string {
+	out := &bytes.Buffer{}
+	in := strings.NewReader(strings.Join(commands, "\n") + "\n")
+	if err := New("Test Engine", "Tester", e).Serve(in, out); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestServe(t *testing.T) {
+	e := &testEngine{}
+	lines := serve(t, e, "uci", "isready", "position startpos moves e2e4 e7e5", "go wtime 60000 btime 30000 winc 1000 movestogo 20", "quit")
+	expected := []string{"id name Test Engine", "id author Tester", "uciok", "readyok", "bestmove a2a3"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected responses %v but got %v", expected, lines)
+	}
+	pos := chess.StartingPosition()
+	for _, s := range []string{"e2e4", "e7e5"} {
+		m, _ := chess.UCINotation{}.Decode(pos, s)
+		pos = pos.Update(m)
+	}
+	if len(e.positions) != 1 || !e.positions[0].Equal(pos) {
+		t.Fatalf("expected the search of the position after e4 e5 but got %v", e.positions)
+	}
+	limits := Limits{WhiteTime: time.Minute, BlackTime: 30 * time.Second, WhiteIncrement: time.Second, MovesToGo: 20}
+	if e.limits[0] != limits {
+		t.Fatalf("expected limits %+v but got %+v", limits, e.limits[0])
+	}
+}
+
+func TestServeStop(t *testing.T) {
+	e := &testEngine{}
+	fen := "7k/8/8/8/8/8/8/R5K1 b - - 0 1"
+	lines := serve(t, e, "position fen "+fen, "go infinite", "stop", "position fen "+fen+" moves h8g7", "go movetime 10", "stop", "quit")
+	expected := []string{"bestmove h8g7", "bestmove a1a2"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected responses %v but got %v", expected, lines)
+	}
+	lines = serve(t, e, "position startpos moves e2e5", "position fen 8/8/8 w - - 0 1", "position")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "info string") {
+		t.Fatalf("expected invalid positions to be reported but got %v", lines)
+	}
+}
+
+// ponderEngine searches until its context is canceled.
+type ponderEngine struct{}
+
+func (ponderEngine) Search(ctx context.Context, pos *chess.Position, limits Limits) *chess.Move {
+	<-ctx.Done()
+	return pos.ValidMoves()[0]
+}
+
+func TestServePonderHit(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go New("Test Engine", "Tester", ponderEngine{}).Serve(inR, outW)
+	defer inW.Close()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	fmt.Fprintln(inW, "position startpos moves e2e4")
+	fmt.Fprintln(inW, "go ponder wtime 200 btime 200 movestogo 1")
+	select {
+	case line := <-lines:
+		t.Fatalf("expected the ponder search to run until ponderhit but got %s", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+	start := time.Now()
+	fmt.Fprintln(inW, "ponderhit")
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "bestmove") {
+			t.Fatalf("expected the best move but got %s", line)
+		}
+		// black's budget is 180ms after ponderhit
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Fatalf("expected the search to use its time budget after ponderhit but it ended after %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the search to end once its time budget was used after ponderhit")
+	}
+}
+
+func TestLimitsBudget(t *testing.T) {
+	tables := []struct {
+		limits Limits
+		c      chess.Color
+		budget time.Duration
+	}{
+		{Limits{MoveTime: time.Second, WhiteTime: time.Minute}, chess.White, time.Second},
+		{Limits{WhiteTime: time.Minute, BlackTime: 30 * time.Second}, chess.Black, time.Second},
+		{Limits{WhiteTime: time.Minute, WhiteIncrement: 2 * time.Second, MovesToGo: 10}, chess.White, 7 * time.Second},
+		{Limits{BlackTime: time.Second, MovesToGo: 1}, chess.Black, 900 * time.Millisecond},
+		{Limits{Infinite: true}, chess.White, 0},
+	}
+	for _, table := range tables {
+		if budget := table.limits.Budget(table.c); budget != table.budget {
+			t.Fatalf("expected %+v to have budget %s but got %s", table.limits, table.budget, budget)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package chess
+
+// Undo holds the state a position had before a move played with Do so
+// that Position's Undo method can take the move back.
+type Undo struct {
+	pos   Position
+	board Board
+}
+
+// Do plays the move on the position in place and returns the Undo that
+// takes it back.  Unlike Update no new position or board is allocated
+// in standard chess, which suits searches that walk a single position
+// through the tree.  Moves must be undone in the reverse order they
+// were done.  The move isn't validated and is played by the rules of
+// the position's variant, which falls back to Update.  Positions held
+// by a Game must not be changed so search on a copy made by Clone.
+func (pos *Position) Do(m *Move) Undo {
+	u := Undo{pos: *pos, board: *pos.board}
+	if m == nil || m.IsNull() || pos.variant != nil {
+		*pos = *pos.Update(m)
+		return u
+	}
+	*pos = pos.play(m, pos.board)
+	return u
+}
+
+// Undo restores the position to its state before the move that returned
+// the Undo was played with Do.
+func (pos *Position) Undo(u Undo) {
+	*u.pos.board = u.board
+	*pos = u.pos
+}
+
+// Clone returns a copy of the position that can be changed with Do
+// without changing the original.
+func (pos *Position) Clone() *Position {
+	cp := *pos
+	cp.board = pos.board.copy()
+	return &cp
+}
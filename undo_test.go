@@ -0,0 +1,51 @@
+package chess
+
+import "testing"
+
+func TestDoUndo(t *testing.T) {
+	fens := append([]string{
+		// crazyhouse and three-check positions fall back to Update
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R[Pp] w KQkq - 2 3",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1",
+	}, moveGenFENs...)
+	for _, fen := range fens {
+		pos := unsafeFEN(fen)
+		before, hash := pos.String(), pos.Hash()
+		for _, m := range pos.ValidMoves() {
+			expected := pos.Update(m)
+			u := pos.Do(m)
+			if pos.String() != expected.String() || pos.Hash() != expected.Hash() || pos.inCheck != expected.inCheck {
+				t.Fatalf("%s expected %s to play %s but got %s", fen, m, expected, pos)
+			}
+			pos.Undo(u)
+			if pos.String() != before || pos.Hash() != hash {
+				t.Fatalf("%s expected undoing %s to restore the position but got %s", fen, m, pos)
+			}
+		}
+	}
+}
+
+func TestDoOnClone(t *testing.T) {
+	pos := StartingPosition()
+	cp := pos.Clone()
+	m, _ := UCINotation{}.Decode(cp, "e2e4")
+	cp.Do(m)
+	if pos.String() != startFEN {
+		t.Fatalf("expected the original position to be unchanged but got %s", pos)
+	}
+	if cp.Board().Piece(E4) != WhitePawn {
+		t.Fatalf("expected the clone to have played e2e4 but got %s", cp)
+	}
+}
+
+func BenchmarkDoUndo(b *testing.B) {
+	pos := unsafeFEN("r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP2BPPP/R2QKB1R w KQ - 0 8")
+	pos.hash = generateZobristHash(pos)
+	moves := pos.ValidMoves()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		u := pos.Do(moves[n%len(moves)])
+		pos.Undo(u)
+	}
+}
@@ -0,0 +1,421 @@
+package chess
+
+import (
+	"strings"
+)
+
+// A Variant is a set of rules that changes which moves are legal, how a
+// move changes the position and how the game ends.  Positions without a
+// variant are played by the rules of Standard chess.  Positions of a
+// variant are created with FromVariantFEN and the moves played from
+// them keep the variant.  Custom variants can embed Standard and
+// override the rules that differ.
+type Variant interface {
+	// Name returns the variant's name as written in the PGN Variant tag.
+	Name() string
+	// StartingFEN returns the FEN of the variant's starting position.
+	StartingFEN() string
+	// ValidMoves returns the legal moves of the position.
+	ValidMoves(pos *Position) []*Move
+	// Update returns the position after the legal move.
+	Update(pos *Position, m *Move) *Position
+	// Status returns the outcome of the position and the method that
+	// ended the game, or NoOutcome and NoMethod if the game goes on.
+	Status(pos *Position) (Outcome, Method)
+}
+
+// variants are the built in variants looked up by VariantByName.
+var variants = []Variant{
+	Standard{}, Crazyhouse{}, Atomic{}, KingOfTheHill{}, Horde{}, RacingKings{}, Antichess{}, ThreeCheck{},
+	Chess960{},
+}
+
+// VariantByName returns the built in variant with the given name
+// matched case insensitively, such as "Atomic" or "King of the Hill".
+// False is returned if there is no such variant.
+func VariantByName(name string) (Variant, bool) {
+	for _, v := range variants {
+		if strings.EqualFold(v.Name(), name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Variant returns the variant the position is played by.
+func (pos *Position) Variant() Variant {
+	if pos.variant == nil {
+		return Standard{}
+	}
+	return pos.variant
+}
+
+// winner returns the outcome of a game won by the color.
+func winner(c Color) Outcome {
+	if c == White {
+		return WhiteWon
+	}
+	return BlackWon
+}
+
+// Standard is standard chess.
+type Standard struct{}
+
+// Name implements the Variant interface.
+func (Standard) Name() string {
+	return "Standard"
+}
+
+// StartingFEN implements the Variant interface.
+func (Standard) StartingFEN() string {
+	return startFEN
+}
+
+// ValidMoves implements the Variant interface.
+func (Standard) ValidMoves(pos *Position) []*Move {
+	return engine{}.CalcMoves(pos, false)
+}
+
+// Update implements the Variant interface.
+func (Standard) Update(pos *Position, m *Move) *Position {
+	return pos.update(m)
+}
+
+// Status implements the Variant interface.  The game ends by checkmate,
+// stalemate or insufficient material.
+func (Standard) Status(pos *Position) (Outcome, Method) {
+	switch method := (engine{}).Status(pos); method {
+	case Checkmate:
+		return winner(pos.turn.Other()), method
+	case Stalemate, InsufficientMaterial:
+		return Draw, method
+	}
+	return NoOutcome, NoMethod
+}
+
+// Crazyhouse is standard chess with pockets in which captured pieces
+// change color and can be dropped back on the board.
+type Crazyhouse struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Crazyhouse) Name() string {
+	return "Crazyhouse"
+}
+
+// StartingFEN implements the Variant interface.
+func (Crazyhouse) StartingFEN() string {
+	return crazyhouseStartFEN
+}
+
+// KingOfTheHill is standard chess that is also won by bringing the king
+// to one of the four center squares.
+type KingOfTheHill struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (KingOfTheHill) Name() string {
+	return "King of the Hill"
+}
+
+// Status implements the Variant interface.  A king reaching the center
+// wins and since any king can walk there material is never
+// insufficient.
+func (KingOfTheHill) Status(pos *Position) (Outcome, Method) {
+	mover := pos.turn.Other()
+	switch pos.board.kingSquare(mover) {
+	case D4, E4, D5, E5:
+		return winner(mover), VariantEnd
+	}
+	if outcome, method := (Standard{}).Status(pos); method != InsufficientMaterial {
+		return outcome, method
+	}
+	return NoOutcome, NoMethod
+}
+
+// RacingKings is a race of the kings to the eighth rank in which giving
+// check is illegal.  If white's king arrives first black has one move
+// to draw by also arriving.
+type RacingKings struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (RacingKings) Name() string {
+	return "Racing Kings"
+}
+
+// StartingFEN implements the Variant interface.
+func (RacingKings) StartingFEN() string {
+	return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+}
+
+// ValidMoves implements the Variant interface.
+func (RacingKings) ValidMoves(pos *Position) []*Move {
+	moves := []*Move{}
+	for _, m := range (engine{}).CalcMoves(pos, false) {
+		if !m.HasTag(Check) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// Status implements the Variant interface.
+func (RacingKings) Status(pos *Position) (Outcome, Method) {
+	arrived := func(c Color) bool {
+		sq := pos.board.kingSquare(c)
+		return sq != NoSquare && sq.Rank() == Rank8
+	}
+	white, black := arrived(White), arrived(Black)
+	switch {
+	case white && black:
+		return Draw, VariantEnd
+	case black:
+		return BlackWon, VariantEnd
+	case white && pos.turn == Black:
+		// black may still draw by bringing the king to the eighth rank
+		for _, m := range pos.MovesFrom(pos.board.blackKingSq) {
+			if m.S2.Rank() == Rank8 {
+				return NoOutcome, NoMethod
+			}
+		}
+		return WhiteWon, VariantEnd
+	case white:
+		return WhiteWon, VariantEnd
+	}
+	if outcome, method := (Standard{}).Status(pos); method == Stalemate {
+		return outcome, method
+	}
+	return NoOutcome, NoMethod
+}
+
+// Horde is played by black's standard army against white's horde of
+// pawns without a king.  White's pawns on the first rank can advance two
+// squares and black wins by capturing all of white's pieces.
+type Horde struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Horde) Name() string {
+	return "Horde"
+}
+
+// StartingFEN implements the Variant interface.
+func (Horde) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+// ValidMoves implements the Variant interface.
+func (Horde) ValidMoves(pos *Position) []*Move {
+	moves := engine{}.CalcMoves(pos, false)
+	if pos.turn != White {
+		return moves
+	}
+	for sq := A1; sq <= H1; sq++ {
+		if !pos.board.bbWhitePawn.Occupied(sq) || pos.board.isOccupied(sq+8) || pos.board.isOccupied(sq+16) {
+			continue
+		}
+		m := &Move{S1: sq, S2: sq + 16}
+		addTags(m, pos)
+		if !m.HasTag(inCheck) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// Status implements the Variant interface.
+func (Horde) Status(pos *Position) (Outcome, Method) {
+	if pos.board.whiteSqs == 0 {
+		return BlackWon, VariantEnd
+	}
+	return Standard{}.Status(pos)
+}
+
+func (Horde) validateFEN(pos *Position) error {
+	// only white's pawns may stand on the first rank
+	cp := pos.copy()
+	cp.board.bbWhitePawn &^= bbRank1
+	cp.board.calcConvienceBBs(nil)
+	return validateFENPosition(cp)
+}
+
+// Antichess is won by losing all pieces or being stalemated.  Capturing
+// is compulsory, the king is an ordinary piece that can be captured and
+// promoted to, and there is no check or castling.
+type Antichess struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Antichess) Name() string {
+	return "Antichess"
+}
+
+// StartingFEN implements the Variant interface.
+func (Antichess) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+}
+
+// ValidMoves implements the Variant interface.
+func (Antichess) ValidMoves(pos *Position) []*Move {
+	moves, captures := []*Move{}, []*Move{}
+	for _, m := range (engine{}).CalcMoves(pos, false) {
+		if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+			continue
+		}
+		ms := []*Move{m}
+		// promotions to a king follow the other promotions
+		if m.promo == Knight {
+			k := &Move{S1: m.S1, S2: m.S2, promo: King}
+			addTags(k, pos)
+			ms = append(ms, k)
+		}
+		for _, mv := range ms {
+			moves = append(moves, mv)
+			if mv.HasTag(Capture) || mv.HasTag(EnPassant) {
+				captures = append(captures, mv)
+			}
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+// Status implements the Variant interface.
+func (Antichess) Status(pos *Position) (Outcome, Method) {
+	if !pos.HasLegalMoves() {
+		return winner(pos.turn), VariantEnd
+	}
+	return NoOutcome, NoMethod
+}
+
+func (Antichess) isInCheck(pos *Position) bool {
+	return false
+}
+
+func (Antichess) validateFEN(pos *Position) error {
+	// any number of kings may be on the board and castling is ignored
+	cp := pos.copy()
+	cp.board.bbWhiteKing, cp.board.bbBlackKing = 0, 0
+	cp.board.calcConvienceBBs(nil)
+	cp.castleRights = "-"
+	return validateFENPosition(cp)
+}
+
+// Atomic is standard chess in which a capture explodes the capturing
+// piece and all pieces other than pawns next to the capture square.
+// Exploding the opponent's king wins, kings can't capture and a king
+// next to the opponent's king can't be in check.
+type Atomic struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Atomic) Name() string {
+	return "Atomic"
+}
+
+// ValidMoves implements the Variant interface.  A move is legal if it
+// keeps the own king and either explodes the opponent's king or doesn't
+// leave the own king in check.
+func (a Atomic) ValidMoves(pos *Position) []*Move {
+	moves := []*Move{}
+	for _, m := range pseudoLegalMoves(pos) {
+		if pos.board.Piece(m.S1).Type() == King && (m.HasTag(Capture) || m.HasTag(EnPassant)) {
+			continue
+		}
+		next := a.Update(pos, m)
+		if next.board.kingSquare(pos.turn) == NoSquare {
+			continue
+		}
+		if next.board.kingSquare(next.turn) != NoSquare {
+			cp := next.copy()
+			cp.turn = pos.turn
+			if a.isInCheck(cp) {
+				continue
+			}
+		}
+		if next.inCheck {
+			m.addTag(Check)
+		}
+		moves = append(moves, m)
+	}
+	return moves
+}
+
+// Update implements the Variant interface.
+func (Atomic) Update(pos *Position, m *Move) *Position {
+	tagged := *m
+	if tagged.tags == 0 {
+		pos.addMoveTags(&tagged)
+	}
+	next := pos.update(m)
+	if tagged.HasTag(Capture) || tagged.HasTag(EnPassant) {
+		explode(next.board, m.S2)
+		next.castleRights = next.castleRightsOnBoard()
+		next.hash = 0
+	}
+	next.inCheck = isInCheck(next)
+	return next
+}
+
+// Status implements the Variant interface.
+func (Atomic) Status(pos *Position) (Outcome, Method) {
+	if pos.board.kingSquare(pos.turn) == NoSquare {
+		return winner(pos.turn.Other()), VariantEnd
+	}
+	return Standard{}.Status(pos)
+}
+
+func (Atomic) isInCheck(pos *Position) bool {
+	// connected kings can't give check since a capture would explode both
+	kingSq, otherSq := pos.board.kingSquare(pos.turn), pos.board.kingSquare(pos.turn.Other())
+	if kingSq != NoSquare && otherSq != NoSquare && bbKingMoves[kingSq].Occupied(otherSq) {
+		return false
+	}
+	return isKingAttacked(pos)
+}
+
+// pseudoLegalMoves returns the moves of the position including those
+// that leave the king in check.
+func pseudoLegalMoves(pos *Position) []*Move {
+	cp := pos.copy()
+	cp.variant = Antichess{}
+	moves := standardMoves(cp, false, allTargets)
+	return append(moves, castleMoves(cp)...)
+}
+
+// explode removes the piece on the square and the pieces other than
+// pawns around it.
+func explode(b *Board, sq Square) {
+	bb := bbForSquare(sq) | bbKingMoves[sq]&^(b.bbWhitePawn|b.bbBlackPawn)
+	for _, p := range allPieces {
+		b.setBBForPiece(p, b.bbForPiece(p)&^bb)
+	}
+	b.calcConvienceBBs(nil)
+}
+
+// castleRightsOnBoard returns the castling rights whose king and rook
+// are still on their home squares.
+func (pos *Position) castleRightsOnBoard() CastleRights {
+	s := ""
+	for _, c := range []Color{White, Black} {
+		for _, side := range castleSides {
+			king, rook := pos.castleSquares(c, side)
+			if pos.castleRights.CanCastle(c, side) && pos.board.Piece(king) == getPiece(King, c) && pos.board.Piece(rook) == getPiece(Rook, c) {
+				s += castleChar(c, side)
+			}
+		}
+	}
+	if s == "" {
+		s = "-"
+	}
+	return CastleRights(s)
+}
@@ -0,0 +1,153 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariantPerft(t *testing.T) {
+	tables := []struct {
+		variant Variant
+		nodes   int
+	}{
+		{Standard{}, 400},
+		{Atomic{}, 400},
+		{Horde{}, 128},
+		{RacingKings{}, 421},
+		{Antichess{}, 400},
+	}
+	for _, table := range tables {
+		pos, err := FromVariantFEN(table.variant, table.variant.StartingFEN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes := 0
+		for _, m := range pos.ValidMoves() {
+			nodes += len(pos.Update(m).ValidMoves())
+		}
+		if nodes != table.nodes {
+			t.Fatalf("expected %s to have %d nodes at depth 2 but got %d", table.variant.Name(), table.nodes, nodes)
+		}
+	}
+}
+
+func TestVariantOutcomes(t *testing.T) {
+	tables := []struct {
+		variant Variant
+		fen     string
+		moves   []string
+		outcome Outcome
+		method  Method
+	}{
+		{KingOfTheHill{}, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", []string{}, NoOutcome, NoMethod},
+		{KingOfTheHill{}, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", []string{"Ke4"}, WhiteWon, VariantEnd},
+		{RacingKings{}, "8/k4K2/8/8/8/8/8/8 w - - 0 1", []string{"Kf8"}, NoOutcome, NoMethod},
+		{RacingKings{}, "8/k4K2/8/8/8/8/8/8 w - - 0 1", []string{"Kf8", "Ka8"}, Draw, VariantEnd},
+		{RacingKings{}, "8/5K2/8/8/8/8/8/k7 w - - 0 1", []string{"Kf8"}, WhiteWon, VariantEnd},
+		{Horde{}, "4k3/8/8/8/8/8/1q6/P7 b - - 0 1", []string{"Qxa1"}, BlackWon, VariantEnd},
+		{Antichess{}, "8/8/8/8/8/8/1p6/R7 b - - 0 1", []string{"bxa1=K"}, WhiteWon, VariantEnd},
+		{Atomic{}, "3k4/3p4/8/8/8/8/8/3QK3 w - - 0 1", []string{"Qxd7"}, WhiteWon, VariantEnd},
+		{Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3 w - - 0 1", []string{}, NoOutcome, NoMethod},
+	}
+	for _, table := range tables {
+		fen, err := VariantFEN(table.variant, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(fen)
+		for _, s := range table.moves {
+			if err := g.MoveStr(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g.Outcome() != table.outcome || g.Method() != table.method {
+			t.Fatalf("expected %s %s after %v to end %s by %s but got %s by %s", table.variant.Name(), table.fen,
+				table.moves, table.outcome, table.method, g.Outcome(), g.Method())
+		}
+	}
+}
+
+func TestVariantIllegalMoves(t *testing.T) {
+	tables := []struct {
+		variant Variant
+		fen     string
+		move    string
+	}{
+		// giving check is illegal in racing kings
+		{RacingKings{}, "8/8/8/8/8/8/1R6/k6K w - - 0 1", "Ra2"},
+		// capturing is compulsory in antichess
+		{Antichess{}, "rnbqkbnr/p1pppppp/8/1p6/8/4P3/PPPP1PPP/RNBQKBNR w - - 0 2", "e4"},
+		// kings can't capture and pieces can't explode their own king
+		{Atomic{}, "4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", "Kxe2"},
+		{Atomic{}, "4k3/8/8/8/8/8/R2pK3/8 w - - 0 1", "Rxd2"},
+	}
+	for _, table := range tables {
+		pos, err := FromVariantFEN(table.variant, table.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (AlgebraicNotation{}).Decode(pos, table.move); err == nil {
+			t.Fatalf("expected %s to be illegal in %s %s", table.move, table.variant.Name(), table.fen)
+		}
+	}
+}
+
+func TestVariantFEN(t *testing.T) {
+	if _, err := FromFEN(Horde{}.StartingFEN()); err == nil {
+		t.Fatal("expected the horde position to be illegal in standard chess")
+	}
+	for _, table := range []struct {
+		variant Variant
+		fen     string
+	}{
+		{Antichess{}, "kk6/8/8/8/8/8/8/KK6 w - - 0 1"},
+		// connected kings aren't in check in atomic
+		{Atomic{}, "8/8/8/8/8/8/8/Kk6 w - - 0 1"},
+	} {
+		if _, err := FromVariantFEN(table.variant, table.fen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := FromVariantFEN(Horde{}, "4k3/8/8/8/8/8/8/p7 w - - 0 1"); err == nil {
+		t.Fatal("expected black pawns on the first rank to be illegal in horde")
+	}
+	pos, _ := FromVariantFEN(Horde{}, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if moves := pos.MovesFrom(A1); len(moves) != 2 {
+		t.Fatalf("expected the pawn on the first rank to have two moves but got %v", moves)
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	pos, err := FromVariantFEN(Atomic{}, "r3k3/8/8/8/8/8/1n6/R3K3 w Qq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := AlgebraicNotation{}.Decode(pos, "Rxa8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := pos.Update(m)
+	if next.Board().Piece(A8) != NoPiece || next.Board().Piece(A1) != NoPiece {
+		t.Fatalf("expected the capture to explode both rooks but got %s", next)
+	}
+	if next.CastleRights() != "-" || next.Hash() != generateZobristHash(next) {
+		t.Fatalf("expected no castling rights and a matching hash but got %s", next)
+	}
+}
+
+func TestVariantPGN(t *testing.T) {
+	pgn := `[Variant "King of the Hill"]
+
+1. e4 d5 2. Ke2 dxe4 3. Ke3 Kd7 4. Kxe4 1-0`
+	read, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(read)
+	if g.Method() != VariantEnd || g.Position().Variant().Name() != "King of the Hill" {
+		t.Fatalf("expected the king of the hill game to end by its variant but got %s", g.Method())
+	}
+	if v, ok := VariantByName("racing kings"); !ok || v != (RacingKings{}) {
+		t.Fatalf("expected to find racing kings but got %v", v)
+	}
+}
@@ -9,18 +9,44 @@ var castleRightsZC [4]uint64
 var enPassantZC [16]uint64
 var whiteTurnZC uint64
 
+// pocketsZC holds a key for each count of a piece type in a pocket.
+// Counts beyond the table are left out of the hash.
+var pocketsZC [2][5][16]uint64
+
+// checksZC holds a key for each check given by a color in Three-check.
+var checksZC [2][3]uint64
+
+// zobristSeed seeds the zobrist keys so that hashes are stable across
+// runs of a program and can be stored.
+const zobristSeed = 0x5eed
+
 func initZobrist() {
-	whiteTurnZC = rand.Uint64()
+	rng := rand.New(rand.NewSource(zobristSeed))
+	whiteTurnZC = rng.Uint64()
 	for i := 0; i < 12; i++ {
 		for j := 0; j < 64; j++ {
-			piecesZC[i][j] = rand.Uint64()
+			piecesZC[i][j] = rng.Uint64()
 		}
 	}
 	for i := 0; i < 4; i++ {
-		castleRightsZC[i] = rand.Uint64()
+		castleRightsZC[i] = rng.Uint64()
 	}
 	for i := 0; i < 16; i++ {
-		enPassantZC[i] = rand.Uint64()
+		enPassantZC[i] = rng.Uint64()
+	}
+	// the pocket keys come last to keep the hashes of positions
+	// without pockets unchanged
+	for c := 0; c < 2; c++ {
+		for pt := 0; pt < 5; pt++ {
+			for n := 0; n < 16; n++ {
+				pocketsZC[c][pt][n] = rng.Uint64()
+			}
+		}
+	}
+	for c := 0; c < 2; c++ {
+		for n := 0; n < 3; n++ {
+			checksZC[c][n] = rng.Uint64()
+		}
 	}
 }
 
@@ -49,45 +75,63 @@ func generateZobristHash(pos *Position) uint64 {
 	}
 
 	/* En passant */
-	enPassant := pos.enPassantSquare
-	if enPassant != NoSquare {
-		if turn == Black {
-			/* Next mov Black -> Current pos White -> White en passant square */
-			hash ^= enPassantZC[enPassant-16]
-		} else {
-			/* Next mov White -> Current pos Black -> Black en passant square */
-			hash ^= enPassantZC[enPassant-40+8]
-		}
-	}
+	hash ^= enPassantKey(pos.enPassantSquare)
 
 	/* Board */
 	piece := pos.board.Piece
 	for sq := 0; sq < 64; sq++ {
 		p := piece(Square(sq))
-		if p != NoPiece {
-			hash ^= piecesZC[int8(p)-1][sq]
+		hash ^= pieceKey(p, Square(sq))
+	}
+
+	/* Pockets */
+	for _, c := range []Color{White, Black} {
+		for _, pt := range dropPieceTypes {
+			for n := 1; n <= pos.pockets.count(c, pt); n++ {
+				hash ^= pocketKey(c, pt, n)
+			}
+		}
+	}
+
+	/* Checks */
+	for _, c := range []Color{White, Black} {
+		for n := 1; n <= pos.Checks(c); n++ {
+			hash ^= checkKey(c, n)
 		}
 	}
 
 	return hash
 }
 
+// UpdateZobristHash returns the zobrist hash of the position resulting
+// from the move by updating the position's hash instead of hashing the
+// resulting position from scratch.
 func UpdateZobristHash(pos *Position, mov *Move) uint64 {
-	hash := pos.hash
+	return updateZobristHash(pos.Hash(), pos, mov, pos.updateCastleRights(mov), pos.updateEnPassantSquare(mov))
+}
+
+// updateZobristHash updates the hash of the position for the move given
+// the castle rights and en passant square after the move.
+func updateZobristHash(hash uint64, pos *Position, mov *Move, newCR CastleRights, movEnPassantSquare Square) uint64 {
 	turn := pos.turn
 	srcSq := mov.S1
 	dstSq := mov.S2
 	piece := pos.board.Piece
 	hasTag := mov.HasTag
 	posEnPassantSquare := pos.enPassantSquare
-	movEnPassantSquare := pos.updateEnPassantSquare(mov)
 
 	/* Switch turn */
 	hash ^= whiteTurnZC
 
 	/* Remove our piece in S1 */
 	ourP := piece(srcSq)
-	hash ^= piecesZC[int8(ourP)-1][srcSq]
+	if mov.drop != NoPieceType {
+		/* Take the dropped piece from our pocket */
+		ourP = getPiece(mov.drop, turn)
+		hash ^= pocketKey(turn, mov.drop, pos.pockets.count(turn, mov.drop))
+	} else {
+		hash ^= pieceKey(ourP, srcSq)
+	}
 
 	/* Add our promoted piece in S2 */
 	var ourPromoP Piece
@@ -97,15 +141,20 @@ func UpdateZobristHash(pos *Position, mov *Move) uint64 {
 	} else {
 		ourPromoP = ourP
 	}
-	hash ^= piecesZC[int8(ourPromoP)-1][dstSq]
+	hash ^= pieceKey(ourPromoP, dstSq)
 
 	/* Capture */
 	if hasTag(Capture) {
 		/* Remove captured piece */
-		hash ^= piecesZC[int8(piece(dstSq))-1][dstSq]
+		hash ^= pieceKey(piece(dstSq), dstSq)
+		/* Add it to our pocket */
+		if pos.pockets != nil {
+			pt := pos.pockets.capturedType(pos.board, dstSq)
+			hash ^= pocketKey(turn, pt, pos.pockets.count(turn, pt)+1)
+		}
 	}
 
-	if oldCR, newCR := pos.castleRights, pos.updateCastleRights(mov); newCR != oldCR {
+	if oldCR := pos.castleRights; newCR != oldCR {
 		/* Remove old castle rights */
 		oldCanCastle := oldCR.CanCastle
 		if oldCanCastle(White, KingSide) {
@@ -137,57 +186,20 @@ func UpdateZobristHash(pos *Position, mov *Move) uint64 {
 	}
 
 	/* Castle */
-	hasKingSideCastle := hasTag(KingSideCastle)
-	hasQueenSideCastle := hasTag(QueenSideCastle)
-	if turn == White {
-		rookBoard := piecesZC[int8(WhiteRook)-1]
-		if hasKingSideCastle {
-			/* Remove rook in H1 */
-			hash ^= rookBoard[H1]
-			/* Add rook in F1 */
-			hash ^= rookBoard[F1]
-		} else if hasQueenSideCastle {
-			/* Remove rook in A1 */
-			hash ^= rookBoard[A1]
-			/* Add rook in D1 */
-			hash ^= rookBoard[D1]
-		}
-	} else {
-		rookBoard := piecesZC[int8(BlackRook)-1]
-		if hasKingSideCastle {
-			/* Remove rook in H8 */
-			hash ^= rookBoard[H8]
-			/* Add rook in F8 */
-			hash ^= rookBoard[F8]
-		} else if hasQueenSideCastle {
-			/* Remove rook in A8 */
-			hash ^= rookBoard[A8]
-			/* Add rook in D8 */
-			hash ^= rookBoard[D8]
-		}
+	if side := mov.castleSide(); side != 0 {
+		/* Move the rook from its home square to its destination */
+		_, rookSq := pos.castleSquares(turn, side)
+		_, rookTo := castleDestinations(turn, side)
+		rook := getPiece(Rook, turn)
+		hash ^= pieceKey(rook, rookSq)
+		hash ^= pieceKey(rook, rookTo)
 	}
 
 	/* Remove old en passant square*/
-	if posEnPassantSquare != NoSquare {
-		if turn == White {
-			/* White mov -> Black old en passant square */
-			hash ^= enPassantZC[posEnPassantSquare-40+8]
-		} else {
-			/* Black mov -> White old en passant square */
-			hash ^= enPassantZC[posEnPassantSquare-16]
-		}
-	}
+	hash ^= enPassantKey(posEnPassantSquare)
 
 	/* Add new en passant square */
-	if movEnPassantSquare != NoSquare {
-		if turn == Black {
-			/* Black mov -> new Black en passant square */
-			hash ^= enPassantZC[movEnPassantSquare-40+8]
-		} else {
-			/* White mov -> new White en passant square */
-			hash ^= enPassantZC[movEnPassantSquare-16]
-		}
-	}
+	hash ^= enPassantKey(movEnPassantSquare)
 
 	/* En passant */
 	if hasTag(EnPassant) {
@@ -200,11 +212,60 @@ func UpdateZobristHash(pos *Position, mov *Move) uint64 {
 			Remove white pawn in same file as en passant square but next rank */
 			hash ^= piecesZC[int8(WhitePawn)-1][posEnPassantSquare+8]
 		}
+		if pos.pockets != nil {
+			hash ^= pocketKey(turn, Pawn, pos.pockets.count(turn, Pawn)+1)
+		}
+	}
+
+	/* Count the check given by the move */
+	if pos.checks != nil && pos.GivesCheck(mov) {
+		hash ^= checkKey(turn, pos.Checks(turn)+1)
 	}
 
 	return hash
 }
 
+// pieceKey returns the key of the piece on the square or zero for an
+// empty square.
+func pieceKey(p Piece, sq Square) uint64 {
+	if p == NoPiece {
+		return 0
+	}
+	return piecesZC[int8(p)-1][sq]
+}
+
+// pocketKey returns the key of the nth piece of the type in the color's
+// pocket.
+func pocketKey(c Color, pt PieceType, n int) uint64 {
+	if n < 1 || n > 16 {
+		return 0
+	}
+	return pocketsZC[c-1][pt-Queen][n-1]
+}
+
+// enPassantKey returns the key of the en passant square or zero if
+// the square isn't on the third or sixth rank.
+func enPassantKey(sq Square) uint64 {
+	switch {
+	case sq == NoSquare:
+		return 0
+	case sq.Rank() == Rank3:
+		return enPassantZC[sq-16]
+	case sq.Rank() == Rank6:
+		return enPassantZC[sq-40+8]
+	}
+	return 0
+}
+
 func init() {
 	initZobrist()
 }
+
+// checkKey returns the key of the nth check given by the color.  Checks
+// beyond the third are left out of the hash.
+func checkKey(c Color, n int) uint64 {
+	if n < 1 || n > 3 {
+		return 0
+	}
+	return checksZC[c-1][n-1]
+}
@@ -0,0 +1,53 @@
+package chess
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestZobristHashIsIncremental(t *testing.T) {
+	fens := []string{
+		startFEN,
+		// castling, en passant and promotions
+		"r3k2r/1P4pp/8/3pP3/8/8/6PP/R3K2R w KQkq d6 0 1",
+		"r3k2r/pp4pp/8/8/3pP3/8/1p4PP/R3K2R b KQkq e3 0 1",
+	}
+	rng := rand.New(rand.NewSource(42))
+	for _, fen := range fens {
+		for i := 0; i < 20; i++ {
+			pos := unsafeFEN(fen)
+			for ply := 0; ply < 60; ply++ {
+				m, ok := pos.RandomMove(rng)
+				if !ok {
+					break
+				}
+				pos = pos.Update(m)
+				if pos.hash != generateZobristHash(pos) {
+					t.Fatalf("expected incremental hash of %s after %s to match the full hash", pos, m)
+				}
+				if pos.Hash() != unsafeFEN(pos.String()).Hash() {
+					t.Fatalf("expected hash of %s to match the decoded fen", pos)
+				}
+			}
+		}
+	}
+}
+
+func TestZobristHashDistinguishesState(t *testing.T) {
+	tables := []struct {
+		a, b string
+	}{
+		{startFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1"},
+		{startFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w Kkq - 0 1"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1"},
+	}
+	for _, table := range tables {
+		if unsafeFEN(table.a).Hash() == unsafeFEN(table.b).Hash() {
+			t.Fatalf("expected %s and %s to hash differently", table.a, table.b)
+		}
+	}
+	// the move counters don't change the hash
+	if unsafeFEN(startFEN).Hash() != unsafeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 5 9").Hash() {
+		t.Fatal("expected the move counters to be ignored by the hash")
+	}
+}